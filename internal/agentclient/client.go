@@ -0,0 +1,149 @@
+package agentclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go-api/internal/db"
+	"go-api/internal/outbound"
+)
+
+// Most of a non-200 response body kept in the error, which ends up in the
+// error_message of the failed snapshot. Proxies answer with whole HTML pages.
+const maxErrorBody = 512
+
+// Client calls agent webhooks. Requests to agents with a signing secret are
+// signed and their responses verified; agents with mTLS material get an HTTP
+// client presenting that certificate. Every destination, including
+// redirects, must pass the outbound policy.
+type Client struct {
+	timeout time.Duration
+	policy  *outbound.Policy
+
+	mu      sync.Mutex
+	clients map[int64]cachedClient
+}
+
+// cachedClient is reused until the agent row changes
+type cachedClient struct {
+	version time.Time
+	client  *http.Client
+}
+
+func New(policy *outbound.Policy) *Client {
+	return &Client{
+		timeout: 30 * time.Second,
+		policy:  policy,
+		clients: make(map[int64]cachedClient),
+	}
+}
+
+// Post sends body as JSON to path on the agent and returns the response body
+func (c *Client) Post(ctx context.Context, agent db.Agent, path string, body any) ([]byte, error) {
+	var payload []byte
+	if body != nil {
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		payload = jsonBody
+	}
+
+	if err := c.policy.CheckURL(agent.BaseUrl + path); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, agent.BaseUrl+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	var nonce string
+	if agent.SigningSecret.Valid {
+		nonce, err = signRequest(req, []byte(agent.SigningSecret.String), path, payload, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := c.httpClient(agent)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if len(respBody) > maxErrorBody {
+			respBody = append(bytes.ToValidUTF8(respBody[:maxErrorBody], nil), "…"...)
+		}
+		return nil, fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	if agent.SigningSecret.Valid {
+		if err := verifyResponse(resp.Header, []byte(agent.SigningSecret.String), nonce, respBody, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	return respBody, nil
+}
+
+func (c *Client) httpClient(agent db.Agent) (*http.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.clients[agent.ID]; ok && cached.version.Equal(agent.UpdatedAt.Time) {
+		return cached.client, nil
+	}
+
+	tlsConfig, err := TLSConfig(agent.ClientCertPem.String, agent.ClientKeyPem.String, agent.CaCertPem.String)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration for agent %d: %w", agent.ID, err)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   c.policy.Control,
+	}
+
+	// No proxy: the policy must see the real destination address
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	transport.TLSClientConfig = tlsConfig
+
+	client := &http.Client{
+		Timeout:   c.timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("stopped after 5 redirects")
+			}
+			return c.policy.CheckURL(req.URL.String())
+		},
+	}
+
+	c.clients[agent.ID] = cachedClient{version: agent.UpdatedAt.Time, client: client}
+	return client, nil
+}
@@ -0,0 +1,93 @@
+package agentclient
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signature headers, used on both requests and responses
+const (
+	HeaderTimestamp = "X-Agent-Timestamp"
+	HeaderNonce     = "X-Agent-Nonce"
+	HeaderSignature = "X-Agent-Signature"
+)
+
+// MaxClockSkew is how far a response timestamp may be from our clock
+const MaxClockSkew = 5 * time.Minute
+
+// MinSecretLength is the shortest accepted signing secret
+const MinSecretLength = 32
+
+var ErrInvalidSignature = errors.New("agent response signature is invalid")
+
+// signRequest adds the timestamp, nonce and signature headers to req and
+// returns the nonce, which the agent must echo in its response signature.
+//
+// The request signature is hex(HMAC-SHA256(secret, canonical)) where
+// canonical is the newline-joined method, path, timestamp, nonce and
+// hex(SHA-256(body)).
+func signRequest(req *http.Request, secret []byte, path string, body []byte, now time.Time) (string, error) {
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, nonce)
+	req.Header.Set(HeaderSignature, sign(secret, req.Method, path, timestamp, nonce, body))
+	return nonce, nil
+}
+
+// verifyResponse checks the agent's response signature. Responses are signed
+// like requests with the method "RESPONSE" and the request nonce, which
+// binds each response to the request it answers.
+func verifyResponse(header http.Header, secret []byte, requestNonce string, body []byte, now time.Time) error {
+	timestamp := header.Get(HeaderTimestamp)
+	signature := header.Get(HeaderSignature)
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("%w: missing signature headers", ErrInvalidSignature)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", ErrInvalidSignature)
+	}
+	skew := now.Sub(time.Unix(seconds, 0))
+	if skew > MaxClockSkew || skew < -MaxClockSkew {
+		return fmt.Errorf("%w: timestamp outside the allowed clock skew", ErrInvalidSignature)
+	}
+
+	expected := sign(secret, "RESPONSE", "", timestamp, requestNonce, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func sign(secret []byte, method, path, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	canonical := strings.Join([]string{method, path, timestamp, nonce, hex.EncodeToString(bodyHash[:])}, "\n")
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(canonical))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GenerateSecret returns a random signing secret
+func GenerateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,40 @@
+package agentclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// TLSConfig builds the TLS configuration for an agent from its PEM material.
+// It returns nil when the agent has neither a client certificate nor a CA.
+func TLSConfig(certPEM, keyPEM, caPEM string) (*tls.Config, error) {
+	if certPEM == "" && keyPEM == "" && caPEM == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if (certPEM == "") != (keyPEM == "") {
+		return nil, errors.New("client_cert_pem and client_key_pem must be provided together")
+	}
+
+	if certPEM != "" {
+		certificate, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate or key: %w", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	if caPEM != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(caPEM)) {
+			return nil, errors.New("ca_cert_pem contains no valid certificates")
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
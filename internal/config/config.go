@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -13,10 +17,68 @@ type Config struct {
 	DBName     string
 	DBSSLMode  string
 	Port       string
+
+	// APP_ENV; anything other than "development" is treated as production
+	AppEnv string
+
+	// Access tokens are signed with the first PEM key in JWT_KEY_FILES
+	// (RS256 or EdDSA) or, when none is given, with JWT_SECRET (HS256)
+	JWTSecret       string
+	JWTKeyFiles     []string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// OIDC single sign-on, enabled when OIDC_ISSUER_URL is set.
+	// OIDC_ROLE_MAPPING maps IdP groups to roles ("group=role,..."); users in
+	// no mapped group get OIDC_DEFAULT_ROLE, or are rejected when it is empty.
+	OIDCIssuerURL    string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       []string
+	OIDCGroupsClaim  string
+	OIDCRoleMapping  map[string]string
+	OIDCDefaultRole  string
+
+	// How often the capture scheduler looks for due schedules
+	SchedulerTick time.Duration
+
+	// Number of workers running asynchronous capture jobs
+	JobWorkers int
+
+	// Length of one unit of the user/kernel time reported by agents, used to
+	// derive CPU usage (KPROCESS times are in clock ticks of 15.625ms)
+	CPUTimeUnit time.Duration
+
+	// SMTP server of email notification channels, which are unavailable when
+	// SMTP_HOST is unset
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// Number of workers delivering notifications, and how many times a
+	// webhook delivery is retried
+	NotifyWorkers int
+	NotifyRetries int
+
+	// Outbound policy for calls to agents (comma-separated lists)
+	OutboundAllowedSchemes []string
+	OutboundAllowedHosts   []string
+	OutboundAllowedCIDRs   []string
+	OutboundBlockedCIDRs   []string
+	OutboundBlockPrivate   bool
 }
 
+// Secret used when JWT_SECRET is unset in development
+const devJWTSecret = "your-secret-key"
+
+// Shortest JWT_SECRET accepted outside development
+const minJWTSecretLength = 32
+
 func Load() *Config {
-	return &Config{
+	cfg := &Config{
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		DBUser:     getEnv("DB_USER", "postgres"),
@@ -24,7 +86,78 @@ func Load() *Config {
 		DBName:     getEnv("DB_NAME", "api_db"),
 		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
 		Port:       getEnv("PORT", "3000"),
+
+		AppEnv:          getEnv("APP_ENV", "production"),
+		JWTSecret:       os.Getenv("JWT_SECRET"),
+		JWTKeyFiles:     getEnvList("JWT_KEY_FILES"),
+		AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		OIDCIssuerURL:    os.Getenv("OIDC_ISSUER_URL"),
+		OIDCClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		OIDCScopes:       getEnvList("OIDC_SCOPES"),
+		OIDCGroupsClaim:  getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCRoleMapping:  getEnvMap("OIDC_ROLE_MAPPING"),
+		OIDCDefaultRole:  os.Getenv("OIDC_DEFAULT_ROLE"),
+
+		SchedulerTick: getEnvDuration("SCHEDULER_TICK", 10*time.Second),
+		JobWorkers:    getEnvInt("JOB_WORKERS", 4),
+		CPUTimeUnit:   getEnvDuration("CPU_TIME_UNIT", 15625*time.Microsecond),
+
+		SMTPHost:      os.Getenv("SMTP_HOST"),
+		SMTPPort:      getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:  os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:  os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:      os.Getenv("SMTP_FROM"),
+		NotifyWorkers: getEnvInt("NOTIFY_WORKERS", 2),
+		NotifyRetries: getEnvInt("NOTIFY_RETRIES", 3),
+
+		OutboundAllowedSchemes: getEnvList("OUTBOUND_ALLOWED_SCHEMES"),
+		OutboundAllowedHosts:   getEnvList("OUTBOUND_ALLOWED_HOSTS"),
+		OutboundAllowedCIDRs:   getEnvList("OUTBOUND_ALLOWED_CIDRS"),
+		OutboundBlockedCIDRs:   getEnvList("OUTBOUND_BLOCKED_CIDRS"),
+		OutboundBlockPrivate:   getEnv("OUTBOUND_BLOCK_PRIVATE", "false") == "true",
+	}
+
+	if len(cfg.OIDCScopes) == 0 {
+		cfg.OIDCScopes = []string{"openid", "profile", "email"}
+	}
+
+	if cfg.JWTSecret == "" && cfg.IsDevelopment() {
+		cfg.JWTSecret = devJWTSecret
 	}
+
+	return cfg
+}
+
+func (c *Config) IsDevelopment() bool {
+	return c.AppEnv == "development"
+}
+
+func (c *Config) OIDCEnabled() bool {
+	return c.OIDCIssuerURL != ""
+}
+
+// Validate rejects settings that are unsafe outside development
+func (c *Config) Validate() error {
+	if c.OIDCEnabled() && (c.OIDCClientID == "" || c.OIDCRedirectURL == "") {
+		return errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL must be set when OIDC_ISSUER_URL is set")
+	}
+	if c.SMTPHost != "" && c.SMTPFrom == "" {
+		return errors.New("SMTP_FROM must be set when SMTP_HOST is set")
+	}
+	if c.IsDevelopment() || len(c.JWTKeyFiles) > 0 {
+		return nil
+	}
+	if c.JWTSecret == "" {
+		return errors.New("JWT_SECRET or JWT_KEY_FILES must be set when APP_ENV is not development")
+	}
+	if c.JWTSecret == devJWTSecret || len(c.JWTSecret) < minJWTSecretLength {
+		return fmt.Errorf("JWT_SECRET must be a random value of at least %d bytes", minJWTSecretLength)
+	}
+	return nil
 }
 
 func (c *Config) DatabaseURL() string {
@@ -37,4 +170,44 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if number, err := strconv.Atoi(value); err == nil && number > 0 {
+			return number
+		}
+	}
+	return defaultValue
+}
+
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvMap parses "key=value,key=value" lists
+func getEnvMap(key string) map[string]string {
+	values := make(map[string]string)
+	for _, pair := range getEnvList(key) {
+		name, value, ok := strings.Cut(pair, "=")
+		if name, value = strings.TrimSpace(name), strings.TrimSpace(value); ok && name != "" {
+			values[name] = value
+		}
+	}
+	return values
+}
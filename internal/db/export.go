@@ -0,0 +1,111 @@
+package db
+
+// Hand-written export query. Exports can cover millions of rows, so rows are
+// handed to the caller one at a time as pgx reads them off the connection
+// instead of being collected into a slice.
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const exportProcessInfos = `SELECT p.id, p.snapshot_id, p.user_id, p.process_id, p.parent_process_id, p.process_name, p.thread_count, p.handle_count, p.base_priority, p.create_time, p.user_time, p.kernel_time, p.working_set_size, p.peak_working_set_size, p.virtual_size, p.peak_virtual_size, p.read_operation_count, p.write_operation_count, p.other_operation_count, p.read_transfer_count, p.write_transfer_count, p.other_transfer_count, p.page_fault_count, p.current_process_address, p.next_process_eprocess_address, p.next_process_name, p.next_process_id, p.next_id, p.previous_process_eprocess_address, p.previous_process_name, p.previous_process_id, p.previous_id, p.created_at, p.updated_at, s.created_at, s.agent_id, s.snapshot_type
+FROM process_info p
+JOIN process_snapshots s ON s.id = p.snapshot_id`
+
+// ExportProcessInfoRow is a process with the snapshot it was captured in
+type ExportProcessInfoRow struct {
+	ProcessInfo
+	CapturedAt   pgtype.Timestamp
+	AgentID      pgtype.Int8
+	SnapshotType string
+}
+
+type ExportProcessInfosParams struct {
+	SnapshotID pgtype.Int8 // one snapshot; the filters below are ignored
+	// Every snapshot of the user (and unowned ones when IncludeUnowned)
+	// created in [CreatedFrom, CreatedTo)
+	UserID         pgtype.Int8
+	IncludeUnowned bool
+	AgentID        pgtype.Int8
+	SnapshotType   pgtype.Text
+	CreatedFrom    pgtype.Timestamp
+	CreatedTo      pgtype.Timestamp
+}
+
+// ExportProcessInfos calls fn for each matching process, ordered by
+// snapshot and then insertion. An error from fn stops the export and is
+// returned.
+func (q *Queries) ExportProcessInfos(ctx context.Context, arg ExportProcessInfosParams, fn func(ExportProcessInfoRow) error) error {
+	l := &listQuery{}
+	if arg.SnapshotID.Valid {
+		l.where = append(l.where, "p.snapshot_id = "+l.arg(arg.SnapshotID))
+	} else {
+		l.where = append(l.where, "(s.user_id = "+l.arg(arg.UserID)+" OR (s.user_id IS NULL AND "+l.arg(arg.IncludeUnowned)+"::BOOLEAN))")
+		if arg.AgentID.Valid {
+			l.where = append(l.where, "s.agent_id = "+l.arg(arg.AgentID))
+		}
+		if arg.SnapshotType.Valid {
+			l.where = append(l.where, "s.snapshot_type = "+l.arg(arg.SnapshotType))
+		}
+		if arg.CreatedFrom.Valid {
+			l.where = append(l.where, "s.created_at >= "+l.arg(arg.CreatedFrom))
+		}
+		if arg.CreatedTo.Valid {
+			l.where = append(l.where, "s.created_at < "+l.arg(arg.CreatedTo))
+		}
+	}
+
+	sql := exportProcessInfos + " WHERE " + strings.Join(l.where, " AND ") + " ORDER BY p.snapshot_id, p.id"
+	rows, err := q.db.Query(ctx, sql, l.args...)
+	if err != nil {
+		return err
+	}
+
+	var i ExportProcessInfoRow
+	_, err = pgx.ForEachRow(rows, []any{
+		&i.ID,
+		&i.SnapshotID,
+		&i.UserID,
+		&i.ProcessID,
+		&i.ParentProcessID,
+		&i.ProcessName,
+		&i.ThreadCount,
+		&i.HandleCount,
+		&i.BasePriority,
+		&i.CreateTime,
+		&i.UserTime,
+		&i.KernelTime,
+		&i.WorkingSetSize,
+		&i.PeakWorkingSetSize,
+		&i.VirtualSize,
+		&i.PeakVirtualSize,
+		&i.ReadOperationCount,
+		&i.WriteOperationCount,
+		&i.OtherOperationCount,
+		&i.ReadTransferCount,
+		&i.WriteTransferCount,
+		&i.OtherTransferCount,
+		&i.PageFaultCount,
+		&i.CurrentProcessAddress,
+		&i.NextProcessEprocessAddress,
+		&i.NextProcessName,
+		&i.NextProcessID,
+		&i.NextID,
+		&i.PreviousProcessEprocessAddress,
+		&i.PreviousProcessName,
+		&i.PreviousProcessID,
+		&i.PreviousID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CapturedAt,
+		&i.AgentID,
+		&i.SnapshotType,
+	}, func() error {
+		return fn(i)
+	})
+	return err
+}
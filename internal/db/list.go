@@ -0,0 +1,400 @@
+package db
+
+// Hand-written list queries. Their filters and sort order are chosen per
+// request, which sqlc cannot generate, so the SQL is assembled here from
+// fixed fragments; every value is passed as a query parameter.
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort key")
+)
+
+// ListPage selects one page of a list. Pages are keyset based: the cursor
+// holds the sort value and id of the last row of the previous page, so rows
+// inserted meanwhile never shift or repeat results.
+type ListPage struct {
+	SortBy string // one of the list's sort keys, created_at when empty
+	Desc   bool
+	Cursor string // next cursor of the previous page, empty for the first page
+	Limit  int32
+}
+
+// Page is one page of rows. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Rows       []T
+	NextCursor string
+}
+
+type cursor struct {
+	SortBy string `json:"s"`
+	Desc   bool   `json:"d"`
+	Value  string `json:"v"`
+	ID     int64  `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// sortKey is a column a list can be sorted by. Its values are kept in
+// cursors as text.
+type sortKey[T any] struct {
+	column string
+	parse  func(string) (any, error)
+	value  func(T) string
+}
+
+func timeKey[T any](column string, get func(T) pgtype.Timestamp) sortKey[T] {
+	return sortKey[T]{
+		column: column,
+		parse: func(s string) (any, error) {
+			return time.Parse(time.RFC3339Nano, s)
+		},
+		value: func(row T) string {
+			return get(row).Time.Format(time.RFC3339Nano)
+		},
+	}
+}
+
+func intKey[T any](column string, get func(T) int64) sortKey[T] {
+	return sortKey[T]{
+		column: column,
+		parse: func(s string) (any, error) {
+			return strconv.ParseInt(s, 10, 64)
+		},
+		value: func(row T) string {
+			return strconv.FormatInt(get(row), 10)
+		},
+	}
+}
+
+func textKey[T any](column string, get func(T) string) sortKey[T] {
+	return sortKey[T]{
+		column: column,
+		parse:  func(s string) (any, error) { return s, nil },
+		value:  get,
+	}
+}
+
+// listQuery collects the WHERE conditions and parameters of a list query
+type listQuery struct {
+	where []string
+	args  []any
+}
+
+// arg adds a parameter and returns its placeholder
+func (l *listQuery) arg(value any) string {
+	l.args = append(l.args, value)
+	return "$" + strconv.Itoa(len(l.args))
+}
+
+func (l *listQuery) owner(userID pgtype.Int8, includeUnowned bool) {
+	l.where = append(l.where, fmt.Sprintf("(user_id = %s OR (user_id IS NULL AND %s::BOOLEAN))", l.arg(userID), l.arg(includeUnowned)))
+}
+
+func (l *listQuery) createdBetween(from, to pgtype.Timestamp) {
+	if from.Valid {
+		l.where = append(l.where, "created_at >= "+l.arg(from))
+	}
+	if to.Valid {
+		l.where = append(l.where, "created_at < "+l.arg(to))
+	}
+}
+
+// GlobToLike converts a glob (* and ?) to a LIKE pattern, escaping the LIKE
+// wildcards the glob contains
+func GlobToLike(glob string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `*`, `%`, `?`, `_`)
+	return replacer.Replace(glob)
+}
+
+func runList[T any](ctx context.Context, db DBTX, from string, keys map[string]sortKey[T], rowID func(T) int64, q *listQuery, page ListPage) (Page[T], error) {
+	sortBy := page.SortBy
+	if sortBy == "" {
+		sortBy = "created_at"
+	}
+
+	key, ok := keys[sortBy]
+	if !ok {
+		names := slices.Sorted(maps.Keys(keys))
+		return Page[T]{}, fmt.Errorf("%w %q, use one of %s", ErrInvalidSort, sortBy, strings.Join(names, ", "))
+	}
+
+	direction, op := "ASC", ">"
+	if page.Desc {
+		direction, op = "DESC", "<"
+	}
+
+	if page.Cursor != "" {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil || c.SortBy != sortBy || c.Desc != page.Desc {
+			return Page[T]{}, ErrInvalidCursor
+		}
+		value, err := key.parse(c.Value)
+		if err != nil {
+			return Page[T]{}, ErrInvalidCursor
+		}
+		q.where = append(q.where, fmt.Sprintf("(%s, id) %s (%s, %s)", key.column, op, q.arg(value), q.arg(c.ID)))
+	}
+
+	sql := from
+	if len(q.where) > 0 {
+		sql += " WHERE " + strings.Join(q.where, " AND ")
+	}
+	// One extra row tells whether there is a next page
+	sql += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %s", key.column, direction, direction, q.arg(page.Limit+1))
+
+	rows, err := db.Query(ctx, sql, q.args...)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByPos[T])
+	if err != nil {
+		return Page[T]{}, err
+	}
+
+	result := Page[T]{Rows: items}
+	if len(items) > int(page.Limit) {
+		result.Rows = items[:page.Limit]
+		last := result.Rows[len(result.Rows)-1]
+		result.NextCursor = encodeCursor(cursor{
+			SortBy: sortBy,
+			Desc:   page.Desc,
+			Value:  key.value(last),
+			ID:     rowID(last),
+		})
+	}
+	return result, nil
+}
+
+const listProcessSnapshots = `SELECT id, user_id, webhook_url, snapshot_type, process_count, success, error_message, created_at, updated_at, agent_id, source, source_host, imported_at FROM process_snapshots`
+
+var processSnapshotSortKeys = map[string]sortKey[ProcessSnapshot]{
+	"created_at":    timeKey("created_at", func(r ProcessSnapshot) pgtype.Timestamp { return r.CreatedAt }),
+	"process_count": intKey("process_count", func(r ProcessSnapshot) int64 { return int64(r.ProcessCount) }),
+}
+
+type ListProcessSnapshotsParams struct {
+	UserID         pgtype.Int8
+	IncludeUnowned bool
+	SnapshotType   pgtype.Text
+	Success        pgtype.Bool
+	AgentID        pgtype.Int8
+	CreatedFrom    pgtype.Timestamp
+	CreatedTo      pgtype.Timestamp
+	Page           ListPage
+}
+
+func (q *Queries) ListProcessSnapshots(ctx context.Context, arg ListProcessSnapshotsParams) (Page[ProcessSnapshot], error) {
+	l := &listQuery{}
+	l.owner(arg.UserID, arg.IncludeUnowned)
+	if arg.SnapshotType.Valid {
+		l.where = append(l.where, "snapshot_type = "+l.arg(arg.SnapshotType))
+	}
+	if arg.Success.Valid {
+		l.where = append(l.where, "success = "+l.arg(arg.Success))
+	}
+	if arg.AgentID.Valid {
+		l.where = append(l.where, "agent_id = "+l.arg(arg.AgentID))
+	}
+	l.createdBetween(arg.CreatedFrom, arg.CreatedTo)
+
+	return runList(ctx, q.db, listProcessSnapshots, processSnapshotSortKeys, func(r ProcessSnapshot) int64 { return r.ID }, l, arg.Page)
+}
+
+const listProcessInfos = `SELECT id, snapshot_id, user_id, process_id, parent_process_id, process_name, thread_count, handle_count, base_priority, create_time, user_time, kernel_time, working_set_size, peak_working_set_size, virtual_size, peak_virtual_size, read_operation_count, write_operation_count, other_operation_count, read_transfer_count, write_transfer_count, other_transfer_count, page_fault_count, current_process_address, next_process_eprocess_address, next_process_name, next_process_id, next_id, previous_process_eprocess_address, previous_process_name, previous_process_id, previous_id, created_at, updated_at FROM process_info`
+
+var processInfoSortKeys = map[string]sortKey[ProcessInfo]{
+	"created_at":       timeKey("created_at", func(r ProcessInfo) pgtype.Timestamp { return r.CreatedAt }),
+	"process_id":       intKey("process_id", func(r ProcessInfo) int64 { return r.ProcessID }),
+	"process_name":     textKey("process_name", func(r ProcessInfo) string { return r.ProcessName }),
+	"working_set_size": intKey("working_set_size", func(r ProcessInfo) int64 { return r.WorkingSetSize }),
+}
+
+type ListProcessInfosParams struct {
+	UserID         pgtype.Int8
+	IncludeUnowned bool
+	NameGlob       string // case-insensitive, * and ? wildcards
+	ProcessID      pgtype.Int8
+	AgentID        pgtype.Int8
+	CreatedFrom    pgtype.Timestamp
+	CreatedTo      pgtype.Timestamp
+	// Extra condition built by the caller, such as a compiled search query.
+	// arg binds a parameter and returns its placeholder.
+	Where func(arg func(value any) string) string
+	Page  ListPage
+}
+
+func (q *Queries) ListProcessInfos(ctx context.Context, arg ListProcessInfosParams) (Page[ProcessInfo], error) {
+	l := &listQuery{}
+	l.owner(arg.UserID, arg.IncludeUnowned)
+	if arg.NameGlob != "" {
+		l.where = append(l.where, "process_name ILIKE "+l.arg(GlobToLike(arg.NameGlob)))
+	}
+	if arg.ProcessID.Valid {
+		l.where = append(l.where, "process_id = "+l.arg(arg.ProcessID))
+	}
+	if arg.AgentID.Valid {
+		l.where = append(l.where, "snapshot_id IN (SELECT id FROM process_snapshots WHERE agent_id = "+l.arg(arg.AgentID)+")")
+	}
+	l.createdBetween(arg.CreatedFrom, arg.CreatedTo)
+	if arg.Where != nil {
+		l.where = append(l.where, arg.Where(l.arg))
+	}
+
+	return runList(ctx, q.db, listProcessInfos, processInfoSortKeys, func(r ProcessInfo) int64 { return r.ID }, l, arg.Page)
+}
+
+type MatchProcessInfosParams struct {
+	SnapshotID    int64
+	ProcessInfoID pgtype.Int8 // only this process when set
+	// Condition built by the caller; arg binds a parameter and returns its
+	// placeholder
+	Where func(arg func(value any) string) string
+	Limit int32
+}
+
+// MatchProcessInfos returns the processes of a snapshot that meet a caller
+// built condition, such as an alert rule, in insertion order
+func (q *Queries) MatchProcessInfos(ctx context.Context, arg MatchProcessInfosParams) ([]ProcessInfo, error) {
+	l := &listQuery{}
+	l.where = append(l.where, "snapshot_id = "+l.arg(arg.SnapshotID))
+	if arg.ProcessInfoID.Valid {
+		l.where = append(l.where, "id = "+l.arg(arg.ProcessInfoID))
+	}
+	l.where = append(l.where, arg.Where(l.arg))
+
+	sql := listProcessInfos + " WHERE " + strings.Join(l.where, " AND ") + " ORDER BY id LIMIT " + l.arg(arg.Limit)
+	rows, err := q.db.Query(ctx, sql, l.args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[ProcessInfo])
+}
+
+const listProcessQueries = `SELECT id, snapshot_id, user_id, webhook_url, requested_pid, process_info_id, success, error_message, created_at, agent_id FROM process_queries`
+
+var processQuerySortKeys = map[string]sortKey[ProcessQuery]{
+	"created_at":    timeKey("created_at", func(r ProcessQuery) pgtype.Timestamp { return r.CreatedAt }),
+	"requested_pid": intKey("requested_pid", func(r ProcessQuery) int64 { return int64(r.RequestedPid) }),
+}
+
+type ListProcessQueriesParams struct {
+	UserID         pgtype.Int8
+	IncludeUnowned bool
+	RequestedPid   pgtype.Int4
+	Success        pgtype.Bool
+	AgentID        pgtype.Int8
+	CreatedFrom    pgtype.Timestamp
+	CreatedTo      pgtype.Timestamp
+	Page           ListPage
+}
+
+func (q *Queries) ListProcessQueries(ctx context.Context, arg ListProcessQueriesParams) (Page[ProcessQuery], error) {
+	l := &listQuery{}
+	l.owner(arg.UserID, arg.IncludeUnowned)
+	if arg.RequestedPid.Valid {
+		l.where = append(l.where, "requested_pid = "+l.arg(arg.RequestedPid))
+	}
+	if arg.Success.Valid {
+		l.where = append(l.where, "success = "+l.arg(arg.Success))
+	}
+	if arg.AgentID.Valid {
+		l.where = append(l.where, "agent_id = "+l.arg(arg.AgentID))
+	}
+	l.createdBetween(arg.CreatedFrom, arg.CreatedTo)
+
+	return runList(ctx, q.db, listProcessQueries, processQuerySortKeys, func(r ProcessQuery) int64 { return r.ID }, l, arg.Page)
+}
+
+const listUsers = `SELECT id, name, password, created_at, updated_at, role, oidc_issuer, oidc_subject FROM users`
+
+var userSortKeys = map[string]sortKey[User]{
+	"created_at": timeKey("created_at", func(r User) pgtype.Timestamp { return r.CreatedAt }),
+	"name":       textKey("name", func(r User) string { return r.Name }),
+}
+
+type ListUsersParams struct {
+	NameGlob string // case-insensitive, * and ? wildcards
+	Role     pgtype.Text
+	Page     ListPage
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) (Page[User], error) {
+	l := &listQuery{}
+	if arg.NameGlob != "" {
+		l.where = append(l.where, "name ILIKE "+l.arg(GlobToLike(arg.NameGlob)))
+	}
+	if arg.Role.Valid {
+		l.where = append(l.where, "role = "+l.arg(arg.Role))
+	}
+
+	return runList(ctx, q.db, listUsers, userSortKeys, func(r User) int64 { return r.ID }, l, arg.Page)
+}
+
+const listAlerts = `SELECT id, rule_id, user_id, snapshot_id, process_info_id, rule_name, severity, message, process_id, process_name, acknowledged_at, acknowledged_by, created_at FROM alerts`
+
+var alertSortKeys = map[string]sortKey[Alert]{
+	"created_at": timeKey("created_at", func(r Alert) pgtype.Timestamp { return r.CreatedAt }),
+}
+
+type ListAlertsParams struct {
+	UserID         pgtype.Int8
+	IncludeUnowned bool
+	Severity       pgtype.Text
+	Acknowledged   pgtype.Bool
+	RuleID         pgtype.Int8
+	SnapshotID     pgtype.Int8
+	CreatedFrom    pgtype.Timestamp
+	CreatedTo      pgtype.Timestamp
+	Page           ListPage
+}
+
+func (q *Queries) ListAlerts(ctx context.Context, arg ListAlertsParams) (Page[Alert], error) {
+	l := &listQuery{}
+	l.owner(arg.UserID, arg.IncludeUnowned)
+	if arg.Severity.Valid {
+		l.where = append(l.where, "severity = "+l.arg(arg.Severity))
+	}
+	if arg.Acknowledged.Valid {
+		l.where = append(l.where, "(acknowledged_at IS NOT NULL) = "+l.arg(arg.Acknowledged))
+	}
+	if arg.RuleID.Valid {
+		l.where = append(l.where, "rule_id = "+l.arg(arg.RuleID))
+	}
+	if arg.SnapshotID.Valid {
+		l.where = append(l.where, "snapshot_id = "+l.arg(arg.SnapshotID))
+	}
+	l.createdBetween(arg.CreatedFrom, arg.CreatedTo)
+
+	return runList(ctx, q.db, listAlerts, alertSortKeys, func(r Alert) int64 { return r.ID }, l, arg.Page)
+}
@@ -8,6 +8,109 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type Agent struct {
+	ID            int64            `json:"id"`
+	OwnerID       pgtype.Int8      `json:"owner_id"`
+	Name          string           `json:"name"`
+	BaseUrl       string           `json:"base_url"`
+	Tags          []string         `json:"tags"`
+	OsBuild       pgtype.Text      `json:"os_build"`
+	LastSeenAt    pgtype.Timestamp `json:"last_seen_at"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
+	SigningSecret pgtype.Text      `json:"signing_secret"`
+	ClientCertPem pgtype.Text      `json:"client_cert_pem"`
+	ClientKeyPem  pgtype.Text      `json:"client_key_pem"`
+	CaCertPem     pgtype.Text      `json:"ca_cert_pem"`
+	OfflineSince  pgtype.Timestamp `json:"offline_since"`
+}
+
+type Alert struct {
+	ID             int64            `json:"id"`
+	RuleID         pgtype.Int8      `json:"rule_id"`
+	UserID         pgtype.Int8      `json:"user_id"`
+	SnapshotID     int64            `json:"snapshot_id"`
+	ProcessInfoID  pgtype.Int8      `json:"process_info_id"`
+	RuleName       string           `json:"rule_name"`
+	Severity       string           `json:"severity"`
+	Message        string           `json:"message"`
+	ProcessID      pgtype.Int8      `json:"process_id"`
+	ProcessName    pgtype.Text      `json:"process_name"`
+	AcknowledgedAt pgtype.Timestamp `json:"acknowledged_at"`
+	AcknowledgedBy pgtype.Int8      `json:"acknowledged_by"`
+	CreatedAt      pgtype.Timestamp `json:"created_at"`
+}
+
+type AlertRule struct {
+	ID          int64            `json:"id"`
+	UserID      int64            `json:"user_id"`
+	Name        string           `json:"name"`
+	Description pgtype.Text      `json:"description"`
+	Kind        string           `json:"kind"`
+	Query       pgtype.Text      `json:"query"`
+	ParentQuery pgtype.Text      `json:"parent_query"`
+	IssueType   pgtype.Text      `json:"issue_type"`
+	Severity    string           `json:"severity"`
+	Enabled     bool             `json:"enabled"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
+}
+
+type ApiKey struct {
+	ID         int64            `json:"id"`
+	UserID     int64            `json:"user_id"`
+	Name       string           `json:"name"`
+	Prefix     string           `json:"prefix"`
+	KeyHash    string           `json:"key_hash"`
+	Scopes     []string         `json:"scopes"`
+	ExpiresAt  pgtype.Timestamp `json:"expires_at"`
+	LastUsedAt pgtype.Timestamp `json:"last_used_at"`
+	CreatedAt  pgtype.Timestamp `json:"created_at"`
+}
+
+type CaptureJob struct {
+	ID           int64            `json:"id"`
+	UserID       int64            `json:"user_id"`
+	AgentID      int64            `json:"agent_id"`
+	Status       string           `json:"status"`
+	SnapshotID   pgtype.Int8      `json:"snapshot_id"`
+	ErrorMessage pgtype.Text      `json:"error_message"`
+	CreatedAt    pgtype.Timestamp `json:"created_at"`
+	StartedAt    pgtype.Timestamp `json:"started_at"`
+	FinishedAt   pgtype.Timestamp `json:"finished_at"`
+	UpdatedAt    pgtype.Timestamp `json:"updated_at"`
+}
+
+type CaptureSchedule struct {
+	ID              int64            `json:"id"`
+	AgentID         int64            `json:"agent_id"`
+	UserID          int64            `json:"user_id"`
+	CronExpression  pgtype.Text      `json:"cron_expression"`
+	IntervalSeconds pgtype.Int4      `json:"interval_seconds"`
+	Paused          bool             `json:"paused"`
+	NextRunAt       pgtype.Timestamp `json:"next_run_at"`
+	LastRunAt       pgtype.Timestamp `json:"last_run_at"`
+	LastSnapshotID  pgtype.Int8      `json:"last_snapshot_id"`
+	LastError       pgtype.Text      `json:"last_error"`
+	CreatedAt       pgtype.Timestamp `json:"created_at"`
+	UpdatedAt       pgtype.Timestamp `json:"updated_at"`
+}
+
+type NotificationChannel struct {
+	ID            int64            `json:"id"`
+	UserID        int64            `json:"user_id"`
+	Name          string           `json:"name"`
+	Kind          string           `json:"kind"`
+	Config        []byte           `json:"config"`
+	Events        []string         `json:"events"`
+	MinSeverity   string           `json:"min_severity"`
+	Enabled       bool             `json:"enabled"`
+	LastAttemptAt pgtype.Timestamp `json:"last_attempt_at"`
+	LastError     pgtype.Text      `json:"last_error"`
+	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	UpdatedAt     pgtype.Timestamp `json:"updated_at"`
+}
+
 type ProcessInfo struct {
 	ID                             int64            `json:"id"`
 	SnapshotID                     int64            `json:"snapshot_id"`
@@ -55,24 +158,66 @@ type ProcessQuery struct {
 	Success       bool             `json:"success"`
 	ErrorMessage  pgtype.Text      `json:"error_message"`
 	CreatedAt     pgtype.Timestamp `json:"created_at"`
+	AgentID       pgtype.Int8      `json:"agent_id"`
+}
+
+type ProcessRate struct {
+	ProcessInfoID         int64            `json:"process_info_id"`
+	PreviousProcessInfoID pgtype.Int8      `json:"previous_process_info_id"`
+	SnapshotID            int64            `json:"snapshot_id"`
+	PreviousSnapshotID    pgtype.Int8      `json:"previous_snapshot_id"`
+	IntervalSeconds       float64          `json:"interval_seconds"`
+	CpuPercent            pgtype.Float8    `json:"cpu_percent"`
+	ReadBytesPerSec       pgtype.Float8    `json:"read_bytes_per_sec"`
+	WriteBytesPerSec      pgtype.Float8    `json:"write_bytes_per_sec"`
+	OtherBytesPerSec      pgtype.Float8    `json:"other_bytes_per_sec"`
+	ReadOpsPerSec         pgtype.Float8    `json:"read_ops_per_sec"`
+	WriteOpsPerSec        pgtype.Float8    `json:"write_ops_per_sec"`
+	PageFaultsPerSec      pgtype.Float8    `json:"page_faults_per_sec"`
+	HandleGrowthPerSec    float64          `json:"handle_growth_per_sec"`
+	CreatedAt             pgtype.Timestamp `json:"created_at"`
 }
 
 type ProcessSnapshot struct {
 	ID           int64            `json:"id"`
 	UserID       pgtype.Int8      `json:"user_id"`
-	WebhookUrl   string           `json:"webhook_url"`
+	WebhookUrl   pgtype.Text      `json:"webhook_url"`
 	SnapshotType string           `json:"snapshot_type"`
 	ProcessCount int32            `json:"process_count"`
 	Success      bool             `json:"success"`
 	ErrorMessage pgtype.Text      `json:"error_message"`
 	CreatedAt    pgtype.Timestamp `json:"created_at"`
 	UpdatedAt    pgtype.Timestamp `json:"updated_at"`
+	AgentID      pgtype.Int8      `json:"agent_id"`
+	Source       string           `json:"source"`
+	SourceHost   pgtype.Text      `json:"source_host"`
+	ImportedAt   pgtype.Timestamp `json:"imported_at"`
 }
 
-type User struct {
+type RefreshToken struct {
 	ID        int64            `json:"id"`
-	Name      string           `json:"name"`
-	Password  string           `json:"password"`
+	UserID    int64            `json:"user_id"`
+	FamilyID  string           `json:"family_id"`
+	TokenHash string           `json:"token_hash"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	RevokedAt pgtype.Timestamp `json:"revoked_at"`
+	CreatedAt pgtype.Timestamp `json:"created_at"`
+}
+
+type RevokedToken struct {
+	Jti       string           `json:"jti"`
+	UserID    int64            `json:"user_id"`
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
 	CreatedAt pgtype.Timestamp `json:"created_at"`
-	UpdatedAt pgtype.Timestamp `json:"updated_at"`
+}
+
+type User struct {
+	ID          int64            `json:"id"`
+	Name        string           `json:"name"`
+	Password    string           `json:"password"`
+	CreatedAt   pgtype.Timestamp `json:"created_at"`
+	UpdatedAt   pgtype.Timestamp `json:"updated_at"`
+	Role        string           `json:"role"`
+	OidcIssuer  pgtype.Text      `json:"oidc_issuer"`
+	OidcSubject pgtype.Text      `json:"oidc_subject"`
 }
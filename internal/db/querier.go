@@ -11,49 +11,143 @@ import (
 )
 
 type Querier interface {
+	AcknowledgeAlert(ctx context.Context, arg AcknowledgeAlertParams) (Alert, error)
+	ClaimNextCaptureJob(ctx context.Context) (CaptureJob, error)
+	ClaimSchedule(ctx context.Context, arg ClaimScheduleParams) (int64, error)
+	ConsumeRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error)
+	CountAdmins(ctx context.Context) (int64, error)
 	// ============================================
 	// Statistics and Analytics
 	// ============================================
 	CountUserProcesses(ctx context.Context, userID pgtype.Int8) (int64, error)
 	CountUserQueries(ctx context.Context, userID pgtype.Int8) (int64, error)
 	CountUserSnapshots(ctx context.Context, userID pgtype.Int8) (int64, error)
+	CreateAgent(ctx context.Context, arg CreateAgentParams) (Agent, error)
+	CreateAlert(ctx context.Context, arg CreateAlertParams) (Alert, error)
+	CreateAlertRule(ctx context.Context, arg CreateAlertRuleParams) (AlertRule, error)
+	CreateApiKey(ctx context.Context, arg CreateApiKeyParams) (ApiKey, error)
+	CreateCaptureJob(ctx context.Context, arg CreateCaptureJobParams) (CaptureJob, error)
+	CreateFirstUser(ctx context.Context, arg CreateFirstUserParams) (User, error)
+	// Creates the iteration snapshot of an offline capture. created_at is the
+	// time the capture was taken, imported_at the time it was uploaded.
+	CreateImportedSnapshot(ctx context.Context, arg CreateImportedSnapshotParams) (ProcessSnapshot, error)
+	CreateNotificationChannel(ctx context.Context, arg CreateNotificationChannelParams) (NotificationChannel, error)
+	CreateOIDCUser(ctx context.Context, arg CreateOIDCUserParams) (User, error)
 	// ============================================
 	// Process Info Queries
 	// ============================================
 	CreateProcessInfo(ctx context.Context, arg CreateProcessInfoParams) (ProcessInfo, error)
+	CreateProcessInfos(ctx context.Context, arg []CreateProcessInfosParams) (int64, error)
 	// ============================================
 	// Process Queries (Query by PID history)
 	// ============================================
 	CreateProcessQuery(ctx context.Context, arg CreateProcessQueryParams) (ProcessQuery, error)
+	// Derives per-second rates for the processes of an iteration snapshot that
+	// were also in the previous iteration snapshot of the same agent and user.
+	// cpu_time_unit_seconds is the length of one user/kernel time unit.
+	// Imported offline captures are never used as the previous snapshot.
+	CreateProcessRates(ctx context.Context, arg CreateProcessRatesParams) (int64, error)
 	// ============================================
 	// Process Snapshots Queries
 	// ============================================
 	CreateProcessSnapshot(ctx context.Context, arg CreateProcessSnapshotParams) (ProcessSnapshot, error)
+	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error)
+	CreateSchedule(ctx context.Context, arg CreateScheduleParams) (CaptureSchedule, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	DeleteAgent(ctx context.Context, id int64) error
+	DeleteAlertRule(ctx context.Context, id int64) error
+	DeleteApiKey(ctx context.Context, id int64) error
+	DeleteExpiredRefreshTokens(ctx context.Context) (int64, error)
+	DeleteExpiredRevokedTokens(ctx context.Context) (int64, error)
+	DeleteNotificationChannel(ctx context.Context, id int64) error
 	DeleteProcessInfo(ctx context.Context, id int64) error
 	DeleteProcessSnapshot(ctx context.Context, id int64) error
+	DeleteSchedule(ctx context.Context, id int64) error
 	DeleteUser(ctx context.Context, id int64) error
+	// ExportProcessInfos calls fn for each matching process, ordered by
+	// snapshot and then insertion. An error from fn stops the export and is
+	// returned.
+	ExportProcessInfos(ctx context.Context, arg ExportProcessInfosParams, fn func(ExportProcessInfoRow) error) error
+	FailStaleCaptureJobs(ctx context.Context, maxRuntimeSeconds int32) ([]CaptureJob, error)
+	FinishCaptureJob(ctx context.Context, arg FinishCaptureJobParams) (CaptureJob, error)
+	GetActiveApiKey(ctx context.Context, id int64) (ApiKey, error)
+	GetActiveApiKeyByPrefix(ctx context.Context, prefix string) (GetActiveApiKeyByPrefixRow, error)
+	GetAgent(ctx context.Context, id int64) (Agent, error)
+	GetAgentByBaseURL(ctx context.Context, baseUrl string) (Agent, error)
+	GetAgents(ctx context.Context) ([]Agent, error)
+	GetAlert(ctx context.Context, id int64) (Alert, error)
+	GetAlertRule(ctx context.Context, id int64) (AlertRule, error)
+	GetAlertRulesByUser(ctx context.Context, userID int64) ([]AlertRule, error)
+	GetAlertsBySnapshot(ctx context.Context, snapshotID int64) ([]Alert, error)
+	GetApiKey(ctx context.Context, id int64) (ApiKey, error)
+	GetApiKeysByUser(ctx context.Context, userID int64) ([]ApiKey, error)
+	GetCaptureJob(ctx context.Context, id int64) (CaptureJob, error)
+	GetDueSchedules(ctx context.Context, arg GetDueSchedulesParams) ([]CaptureSchedule, error)
+	GetEnabledAlertRulesByUser(ctx context.Context, userID int64) ([]AlertRule, error)
 	GetMostQueriedProcesses(ctx context.Context, arg GetMostQueriedProcessesParams) ([]GetMostQueriedProcessesRow, error)
+	GetNotificationChannel(ctx context.Context, id int64) (NotificationChannel, error)
+	GetNotificationChannelsByUser(ctx context.Context, userID int64) ([]NotificationChannel, error)
 	GetProcessInfo(ctx context.Context, id int64) (ProcessInfo, error)
 	GetProcessInfoBySnapshotAndPID(ctx context.Context, arg GetProcessInfoBySnapshotAndPIDParams) (ProcessInfo, error)
 	GetProcessInfosByProcessID(ctx context.Context, arg GetProcessInfosByProcessIDParams) ([]ProcessInfo, error)
 	GetProcessInfosBySnapshot(ctx context.Context, snapshotID int64) ([]ProcessInfo, error)
-	GetProcessInfosByUser(ctx context.Context, userID pgtype.Int8) ([]ProcessInfo, error)
+	GetProcessInfosByUser(ctx context.Context, arg GetProcessInfosByUserParams) ([]ProcessInfo, error)
 	GetProcessQueriesByPID(ctx context.Context, arg GetProcessQueriesByPIDParams) ([]ProcessQuery, error)
 	GetProcessQueriesBySnapshot(ctx context.Context, snapshotID int64) ([]ProcessQuery, error)
-	GetProcessQueriesByUser(ctx context.Context, userID pgtype.Int8) ([]ProcessQuery, error)
+	GetProcessQueriesByUser(ctx context.Context, arg GetProcessQueriesByUserParams) ([]ProcessQuery, error)
 	GetProcessQuery(ctx context.Context, id int64) (ProcessQuery, error)
+	GetProcessRatesBySnapshot(ctx context.Context, snapshotID int64) ([]GetProcessRatesBySnapshotRow, error)
 	GetProcessSnapshot(ctx context.Context, id int64) (ProcessSnapshot, error)
+	GetProcessSnapshotsByAgent(ctx context.Context, arg GetProcessSnapshotsByAgentParams) ([]ProcessSnapshot, error)
 	GetProcessSnapshotsByType(ctx context.Context, arg GetProcessSnapshotsByTypeParams) ([]ProcessSnapshot, error)
-	GetProcessSnapshotsByUser(ctx context.Context, userID pgtype.Int8) ([]ProcessSnapshot, error)
-	GetSnapshotStatistics(ctx context.Context, userID pgtype.Int8) (GetSnapshotStatisticsRow, error)
+	GetProcessSnapshotsByUser(ctx context.Context, arg GetProcessSnapshotsByUserParams) ([]ProcessSnapshot, error)
+	// Counter history of a PID across snapshots, oldest first. Rows of
+	// different process instances that reused the PID are told apart by the
+	// caller using create_time, the EPROCESS address and the agent.
+	GetProcessTimeline(ctx context.Context, arg GetProcessTimelineParams) ([]GetProcessTimelineRow, error)
+	GetQueriedProcessesNearSnapshot(ctx context.Context, arg GetQueriedProcessesNearSnapshotParams) ([]GetQueriedProcessesNearSnapshotRow, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error)
+	GetSchedule(ctx context.Context, id int64) (CaptureSchedule, error)
+	GetSchedulesByUser(ctx context.Context, userID int64) ([]CaptureSchedule, error)
+	GetSnapshotStatistics(ctx context.Context, arg GetSnapshotStatisticsParams) (GetSnapshotStatisticsRow, error)
+	GetSubscribedNotificationChannels(ctx context.Context, arg GetSubscribedNotificationChannelsParams) ([]NotificationChannel, error)
 	GetUser(ctx context.Context, id int64) (User, error)
 	GetUserByName(ctx context.Context, name string) (User, error)
+	GetUserByOIDCSubject(ctx context.Context, arg GetUserByOIDCSubjectParams) (User, error)
 	GetUsers(ctx context.Context) ([]User, error)
-	UpdateNextProcess(ctx context.Context, arg UpdateNextProcessParams) (ProcessInfo, error)
-	UpdatePreviousProcess(ctx context.Context, arg UpdatePreviousProcessParams) (ProcessInfo, error)
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	ListAlerts(ctx context.Context, arg ListAlertsParams) (Page[Alert], error)
+	ListProcessInfos(ctx context.Context, arg ListProcessInfosParams) (Page[ProcessInfo], error)
+	ListProcessQueries(ctx context.Context, arg ListProcessQueriesParams) (Page[ProcessQuery], error)
+	ListProcessSnapshots(ctx context.Context, arg ListProcessSnapshotsParams) (Page[ProcessSnapshot], error)
+	ListUsers(ctx context.Context, arg ListUsersParams) (Page[User], error)
+	// Records the start of an outage. It affects no rows while the agent is
+	// already offline, so each outage is reported once.
+	MarkAgentOffline(ctx context.Context, id int64) (int64, error)
+	// MatchProcessInfos returns the processes of a snapshot that meet a caller
+	// built condition, such as an alert rule, in insertion order
+	MatchProcessInfos(ctx context.Context, arg MatchProcessInfosParams) ([]ProcessInfo, error)
+	// Hands an event to every API instance listening on the api_stream
+	// channel. Payloads must stay under 8000 bytes.
+	PublishStreamEvent(ctx context.Context, payload string) error
+	RecordNotificationAttempt(ctx context.Context, arg RecordNotificationAttemptParams) error
+	RecordScheduleRun(ctx context.Context, arg RecordScheduleRunParams) error
+	ReopenAlert(ctx context.Context, id int64) (Alert, error)
+	ReserveProcessInfoIDs(ctx context.Context, count int32) ([]int64, error)
+	RevokeAccessToken(ctx context.Context, arg RevokeAccessTokenParams) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID int64) error
+	SetSchedulePaused(ctx context.Context, arg SetSchedulePausedParams) (CaptureSchedule, error)
+	TouchAgentLastSeen(ctx context.Context, id int64) error
+	TouchApiKeyLastUsed(ctx context.Context, id int64) error
+	UpdateAgent(ctx context.Context, arg UpdateAgentParams) (Agent, error)
+	UpdateAgentCredentials(ctx context.Context, arg UpdateAgentCredentialsParams) (Agent, error)
+	UpdateAlertRule(ctx context.Context, arg UpdateAlertRuleParams) (AlertRule, error)
+	UpdateNotificationChannel(ctx context.Context, arg UpdateNotificationChannelParams) (NotificationChannel, error)
 	UpdateProcessSnapshotCount(ctx context.Context, arg UpdateProcessSnapshotCountParams) error
+	UpdateSchedule(ctx context.Context, arg UpdateScheduleParams) (CaptureSchedule, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -11,6 +11,17 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countAdmins = `-- name: CountAdmins :one
+SELECT COUNT(*) FROM users WHERE role = 'admin'
+`
+
+func (q *Queries) CountAdmins(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countAdmins)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const countUserProcesses = `-- name: CountUserProcesses :one
 
 SELECT COUNT(*) FROM process_info WHERE user_id = $1
@@ -48,6 +59,40 @@ func (q *Queries) CountUserSnapshots(ctx context.Context, userID pgtype.Int8) (i
 	return count, err
 }
 
+const createOIDCUser = `-- name: CreateOIDCUser :one
+INSERT INTO users (name, password, role, oidc_issuer, oidc_subject) VALUES ($1, $2, $3, $4, $5) RETURNING id, name, password, created_at, updated_at, role, oidc_issuer, oidc_subject
+`
+
+type CreateOIDCUserParams struct {
+	Name        string      `json:"name"`
+	Password    string      `json:"password"`
+	Role        string      `json:"role"`
+	OidcIssuer  pgtype.Text `json:"oidc_issuer"`
+	OidcSubject pgtype.Text `json:"oidc_subject"`
+}
+
+func (q *Queries) CreateOIDCUser(ctx context.Context, arg CreateOIDCUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, createOIDCUser,
+		arg.Name,
+		arg.Password,
+		arg.Role,
+		arg.OidcIssuer,
+		arg.OidcSubject,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Password,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Role,
+		&i.OidcIssuer,
+		&i.OidcSubject,
+	)
+	return i, err
+}
+
 const createProcessInfo = `-- name: CreateProcessInfo :one
 
 INSERT INTO process_info (
@@ -200,6 +245,41 @@ func (q *Queries) CreateProcessInfo(ctx context.Context, arg CreateProcessInfoPa
 	return i, err
 }
 
+type CreateProcessInfosParams struct {
+	ID                             int64       `json:"id"`
+	SnapshotID                     int64       `json:"snapshot_id"`
+	UserID                         pgtype.Int8 `json:"user_id"`
+	ProcessID                      int64       `json:"process_id"`
+	ParentProcessID                int64       `json:"parent_process_id"`
+	ProcessName                    string      `json:"process_name"`
+	ThreadCount                    int32       `json:"thread_count"`
+	HandleCount                    int32       `json:"handle_count"`
+	BasePriority                   int32       `json:"base_priority"`
+	CreateTime                     string      `json:"create_time"`
+	UserTime                       int32       `json:"user_time"`
+	KernelTime                     int32       `json:"kernel_time"`
+	WorkingSetSize                 int64       `json:"working_set_size"`
+	PeakWorkingSetSize             int64       `json:"peak_working_set_size"`
+	VirtualSize                    int64       `json:"virtual_size"`
+	PeakVirtualSize                int64       `json:"peak_virtual_size"`
+	ReadOperationCount             int64       `json:"read_operation_count"`
+	WriteOperationCount            int64       `json:"write_operation_count"`
+	OtherOperationCount            int64       `json:"other_operation_count"`
+	ReadTransferCount              int64       `json:"read_transfer_count"`
+	WriteTransferCount             int64       `json:"write_transfer_count"`
+	OtherTransferCount             int64       `json:"other_transfer_count"`
+	PageFaultCount                 int64       `json:"page_fault_count"`
+	CurrentProcessAddress          string      `json:"current_process_address"`
+	NextProcessEprocessAddress     pgtype.Text `json:"next_process_eprocess_address"`
+	NextProcessName                pgtype.Text `json:"next_process_name"`
+	NextProcessID                  pgtype.Int8 `json:"next_process_id"`
+	NextID                         pgtype.Int8 `json:"next_id"`
+	PreviousProcessEprocessAddress pgtype.Text `json:"previous_process_eprocess_address"`
+	PreviousProcessName            pgtype.Text `json:"previous_process_name"`
+	PreviousProcessID              pgtype.Int8 `json:"previous_process_id"`
+	PreviousID                     pgtype.Int8 `json:"previous_id"`
+}
+
 const createProcessQuery = `-- name: CreateProcessQuery :one
 
 INSERT INTO process_queries (
@@ -209,8 +289,9 @@ INSERT INTO process_queries (
     requested_pid,
     process_info_id,
     success,
-    error_message
-) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, snapshot_id, user_id, webhook_url, requested_pid, process_info_id, success, error_message, created_at
+    error_message,
+    agent_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, snapshot_id, user_id, webhook_url, requested_pid, process_info_id, success, error_message, created_at, agent_id
 `
 
 type CreateProcessQueryParams struct {
@@ -221,6 +302,7 @@ type CreateProcessQueryParams struct {
 	ProcessInfoID pgtype.Int8 `json:"process_info_id"`
 	Success       bool        `json:"success"`
 	ErrorMessage  pgtype.Text `json:"error_message"`
+	AgentID       pgtype.Int8 `json:"agent_id"`
 }
 
 // ============================================
@@ -235,6 +317,7 @@ func (q *Queries) CreateProcessQuery(ctx context.Context, arg CreateProcessQuery
 		arg.ProcessInfoID,
 		arg.Success,
 		arg.ErrorMessage,
+		arg.AgentID,
 	)
 	var i ProcessQuery
 	err := row.Scan(
@@ -247,6 +330,7 @@ func (q *Queries) CreateProcessQuery(ctx context.Context, arg CreateProcessQuery
 		&i.Success,
 		&i.ErrorMessage,
 		&i.CreatedAt,
+		&i.AgentID,
 	)
 	return i, err
 }
@@ -259,17 +343,19 @@ INSERT INTO process_snapshots (
     snapshot_type,
     process_count,
     success,
-    error_message
-) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, user_id, webhook_url, snapshot_type, process_count, success, error_message, created_at, updated_at
+    error_message,
+    agent_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, user_id, webhook_url, snapshot_type, process_count, success, error_message, created_at, updated_at, agent_id, source, source_host, imported_at
 `
 
 type CreateProcessSnapshotParams struct {
 	UserID       pgtype.Int8 `json:"user_id"`
-	WebhookUrl   string      `json:"webhook_url"`
+	WebhookUrl   pgtype.Text `json:"webhook_url"`
 	SnapshotType string      `json:"snapshot_type"`
 	ProcessCount int32       `json:"process_count"`
 	Success      bool        `json:"success"`
 	ErrorMessage pgtype.Text `json:"error_message"`
+	AgentID      pgtype.Int8 `json:"agent_id"`
 }
 
 // ============================================
@@ -283,6 +369,7 @@ func (q *Queries) CreateProcessSnapshot(ctx context.Context, arg CreateProcessSn
 		arg.ProcessCount,
 		arg.Success,
 		arg.ErrorMessage,
+		arg.AgentID,
 	)
 	var i ProcessSnapshot
 	err := row.Scan(
@@ -295,21 +382,54 @@ func (q *Queries) CreateProcessSnapshot(ctx context.Context, arg CreateProcessSn
 		&i.ErrorMessage,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.AgentID,
+		&i.Source,
+		&i.SourceHost,
+		&i.ImportedAt,
+	)
+	return i, err
+}
+
+const createFirstUser = `-- name: CreateFirstUser :one
+INSERT INTO users (name, password, role)
+SELECT $1, $2, 'admin'
+WHERE NOT EXISTS (SELECT 1 FROM users)
+RETURNING id, name, password, created_at, updated_at, role, oidc_issuer, oidc_subject
+`
+
+type CreateFirstUserParams struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+func (q *Queries) CreateFirstUser(ctx context.Context, arg CreateFirstUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, createFirstUser, arg.Name, arg.Password)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Password,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Role,
+		&i.OidcIssuer,
+		&i.OidcSubject,
 	)
 	return i, err
 }
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (name, password) VALUES ($1, $2) RETURNING id, name, password, created_at, updated_at
+INSERT INTO users (name, password, role) VALUES ($1, $2, $3) RETURNING id, name, password, created_at, updated_at, role, oidc_issuer, oidc_subject
 `
 
 type CreateUserParams struct {
 	Name     string `json:"name"`
 	Password string `json:"password"`
+	Role     string `json:"role"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRow(ctx, createUser, arg.Name, arg.Password)
+	row := q.db.QueryRow(ctx, createUser, arg.Name, arg.Password, arg.Role)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -317,6 +437,9 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Password,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.OidcIssuer,
+		&i.OidcSubject,
 	)
 	return i, err
 }
@@ -353,15 +476,16 @@ SELECT
     requested_pid,
     COUNT(*) as query_count
 FROM process_queries
-WHERE user_id = $1 OR user_id IS NULL
+WHERE user_id = $1 OR (user_id IS NULL AND $2::BOOLEAN)
 GROUP BY requested_pid
 ORDER BY query_count DESC
-LIMIT $2
+LIMIT $3
 `
 
 type GetMostQueriedProcessesParams struct {
-	UserID pgtype.Int8 `json:"user_id"`
-	Limit  int32       `json:"limit"`
+	UserID         pgtype.Int8 `json:"user_id"`
+	IncludeUnowned bool        `json:"include_unowned"`
+	Limit          int32       `json:"limit"`
 }
 
 type GetMostQueriedProcessesRow struct {
@@ -370,7 +494,7 @@ type GetMostQueriedProcessesRow struct {
 }
 
 func (q *Queries) GetMostQueriedProcesses(ctx context.Context, arg GetMostQueriedProcessesParams) ([]GetMostQueriedProcessesRow, error) {
-	rows, err := q.db.Query(ctx, getMostQueriedProcesses, arg.UserID, arg.Limit)
+	rows, err := q.db.Query(ctx, getMostQueriedProcesses, arg.UserID, arg.IncludeUnowned, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
@@ -490,17 +614,18 @@ func (q *Queries) GetProcessInfoBySnapshotAndPID(ctx context.Context, arg GetPro
 
 const getProcessInfosByProcessID = `-- name: GetProcessInfosByProcessID :many
 SELECT id, snapshot_id, user_id, process_id, parent_process_id, process_name, thread_count, handle_count, base_priority, create_time, user_time, kernel_time, working_set_size, peak_working_set_size, virtual_size, peak_virtual_size, read_operation_count, write_operation_count, other_operation_count, read_transfer_count, write_transfer_count, other_transfer_count, page_fault_count, current_process_address, next_process_eprocess_address, next_process_name, next_process_id, next_id, previous_process_eprocess_address, previous_process_name, previous_process_id, previous_id, created_at, updated_at FROM process_info 
-WHERE (user_id = $1 OR user_id IS NULL) AND process_id = $2
+WHERE (user_id = $1 OR (user_id IS NULL AND $2::BOOLEAN)) AND process_id = $3
 ORDER BY created_at DESC
 `
 
 type GetProcessInfosByProcessIDParams struct {
-	UserID    pgtype.Int8 `json:"user_id"`
-	ProcessID int64       `json:"process_id"`
+	UserID         pgtype.Int8 `json:"user_id"`
+	IncludeUnowned bool        `json:"include_unowned"`
+	ProcessID      int64       `json:"process_id"`
 }
 
 func (q *Queries) GetProcessInfosByProcessID(ctx context.Context, arg GetProcessInfosByProcessIDParams) ([]ProcessInfo, error) {
-	rows, err := q.db.Query(ctx, getProcessInfosByProcessID, arg.UserID, arg.ProcessID)
+	rows, err := q.db.Query(ctx, getProcessInfosByProcessID, arg.UserID, arg.IncludeUnowned, arg.ProcessID)
 	if err != nil {
 		return nil, err
 	}
@@ -617,12 +742,17 @@ func (q *Queries) GetProcessInfosBySnapshot(ctx context.Context, snapshotID int6
 
 const getProcessInfosByUser = `-- name: GetProcessInfosByUser :many
 SELECT id, snapshot_id, user_id, process_id, parent_process_id, process_name, thread_count, handle_count, base_priority, create_time, user_time, kernel_time, working_set_size, peak_working_set_size, virtual_size, peak_virtual_size, read_operation_count, write_operation_count, other_operation_count, read_transfer_count, write_transfer_count, other_transfer_count, page_fault_count, current_process_address, next_process_eprocess_address, next_process_name, next_process_id, next_id, previous_process_eprocess_address, previous_process_name, previous_process_id, previous_id, created_at, updated_at FROM process_info 
-WHERE user_id = $1 OR user_id IS NULL
+WHERE user_id = $1 OR (user_id IS NULL AND $2::BOOLEAN)
 ORDER BY created_at DESC
 `
 
-func (q *Queries) GetProcessInfosByUser(ctx context.Context, userID pgtype.Int8) ([]ProcessInfo, error) {
-	rows, err := q.db.Query(ctx, getProcessInfosByUser, userID)
+type GetProcessInfosByUserParams struct {
+	UserID         pgtype.Int8 `json:"user_id"`
+	IncludeUnowned bool        `json:"include_unowned"`
+}
+
+func (q *Queries) GetProcessInfosByUser(ctx context.Context, arg GetProcessInfosByUserParams) ([]ProcessInfo, error) {
+	rows, err := q.db.Query(ctx, getProcessInfosByUser, arg.UserID, arg.IncludeUnowned)
 	if err != nil {
 		return nil, err
 	}
@@ -677,18 +807,19 @@ func (q *Queries) GetProcessInfosByUser(ctx context.Context, userID pgtype.Int8)
 }
 
 const getProcessQueriesByPID = `-- name: GetProcessQueriesByPID :many
-SELECT id, snapshot_id, user_id, webhook_url, requested_pid, process_info_id, success, error_message, created_at FROM process_queries 
-WHERE (user_id = $1 OR user_id IS NULL) AND requested_pid = $2
+SELECT id, snapshot_id, user_id, webhook_url, requested_pid, process_info_id, success, error_message, created_at, agent_id FROM process_queries 
+WHERE (user_id = $1 OR (user_id IS NULL AND $2::BOOLEAN)) AND requested_pid = $3
 ORDER BY created_at DESC
 `
 
 type GetProcessQueriesByPIDParams struct {
-	UserID       pgtype.Int8 `json:"user_id"`
-	RequestedPid int32       `json:"requested_pid"`
+	UserID         pgtype.Int8 `json:"user_id"`
+	IncludeUnowned bool        `json:"include_unowned"`
+	RequestedPid   int32       `json:"requested_pid"`
 }
 
 func (q *Queries) GetProcessQueriesByPID(ctx context.Context, arg GetProcessQueriesByPIDParams) ([]ProcessQuery, error) {
-	rows, err := q.db.Query(ctx, getProcessQueriesByPID, arg.UserID, arg.RequestedPid)
+	rows, err := q.db.Query(ctx, getProcessQueriesByPID, arg.UserID, arg.IncludeUnowned, arg.RequestedPid)
 	if err != nil {
 		return nil, err
 	}
@@ -706,6 +837,7 @@ func (q *Queries) GetProcessQueriesByPID(ctx context.Context, arg GetProcessQuer
 			&i.Success,
 			&i.ErrorMessage,
 			&i.CreatedAt,
+			&i.AgentID,
 		); err != nil {
 			return nil, err
 		}
@@ -718,7 +850,7 @@ func (q *Queries) GetProcessQueriesByPID(ctx context.Context, arg GetProcessQuer
 }
 
 const getProcessQueriesBySnapshot = `-- name: GetProcessQueriesBySnapshot :many
-SELECT id, snapshot_id, user_id, webhook_url, requested_pid, process_info_id, success, error_message, created_at FROM process_queries 
+SELECT id, snapshot_id, user_id, webhook_url, requested_pid, process_info_id, success, error_message, created_at, agent_id FROM process_queries 
 WHERE snapshot_id = $1
 ORDER BY created_at DESC
 `
@@ -742,6 +874,7 @@ func (q *Queries) GetProcessQueriesBySnapshot(ctx context.Context, snapshotID in
 			&i.Success,
 			&i.ErrorMessage,
 			&i.CreatedAt,
+			&i.AgentID,
 		); err != nil {
 			return nil, err
 		}
@@ -754,13 +887,18 @@ func (q *Queries) GetProcessQueriesBySnapshot(ctx context.Context, snapshotID in
 }
 
 const getProcessQueriesByUser = `-- name: GetProcessQueriesByUser :many
-SELECT id, snapshot_id, user_id, webhook_url, requested_pid, process_info_id, success, error_message, created_at FROM process_queries 
-WHERE user_id = $1 OR user_id IS NULL
+SELECT id, snapshot_id, user_id, webhook_url, requested_pid, process_info_id, success, error_message, created_at, agent_id FROM process_queries 
+WHERE user_id = $1 OR (user_id IS NULL AND $2::BOOLEAN)
 ORDER BY created_at DESC
 `
 
-func (q *Queries) GetProcessQueriesByUser(ctx context.Context, userID pgtype.Int8) ([]ProcessQuery, error) {
-	rows, err := q.db.Query(ctx, getProcessQueriesByUser, userID)
+type GetProcessQueriesByUserParams struct {
+	UserID         pgtype.Int8 `json:"user_id"`
+	IncludeUnowned bool        `json:"include_unowned"`
+}
+
+func (q *Queries) GetProcessQueriesByUser(ctx context.Context, arg GetProcessQueriesByUserParams) ([]ProcessQuery, error) {
+	rows, err := q.db.Query(ctx, getProcessQueriesByUser, arg.UserID, arg.IncludeUnowned)
 	if err != nil {
 		return nil, err
 	}
@@ -778,6 +916,7 @@ func (q *Queries) GetProcessQueriesByUser(ctx context.Context, userID pgtype.Int
 			&i.Success,
 			&i.ErrorMessage,
 			&i.CreatedAt,
+			&i.AgentID,
 		); err != nil {
 			return nil, err
 		}
@@ -790,7 +929,7 @@ func (q *Queries) GetProcessQueriesByUser(ctx context.Context, userID pgtype.Int
 }
 
 const getProcessQuery = `-- name: GetProcessQuery :one
-SELECT id, snapshot_id, user_id, webhook_url, requested_pid, process_info_id, success, error_message, created_at FROM process_queries WHERE id = $1 LIMIT 1
+SELECT id, snapshot_id, user_id, webhook_url, requested_pid, process_info_id, success, error_message, created_at, agent_id FROM process_queries WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetProcessQuery(ctx context.Context, id int64) (ProcessQuery, error) {
@@ -806,12 +945,13 @@ func (q *Queries) GetProcessQuery(ctx context.Context, id int64) (ProcessQuery,
 		&i.Success,
 		&i.ErrorMessage,
 		&i.CreatedAt,
+		&i.AgentID,
 	)
 	return i, err
 }
 
 const getProcessSnapshot = `-- name: GetProcessSnapshot :one
-SELECT id, user_id, webhook_url, snapshot_type, process_count, success, error_message, created_at, updated_at FROM process_snapshots WHERE id = $1 LIMIT 1
+SELECT id, user_id, webhook_url, snapshot_type, process_count, success, error_message, created_at, updated_at, agent_id, source, source_host, imported_at FROM process_snapshots WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetProcessSnapshot(ctx context.Context, id int64) (ProcessSnapshot, error) {
@@ -827,23 +967,74 @@ func (q *Queries) GetProcessSnapshot(ctx context.Context, id int64) (ProcessSnap
 		&i.ErrorMessage,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.AgentID,
+		&i.Source,
+		&i.SourceHost,
+		&i.ImportedAt,
 	)
 	return i, err
 }
 
+const getProcessSnapshotsByAgent = `-- name: GetProcessSnapshotsByAgent :many
+SELECT id, user_id, webhook_url, snapshot_type, process_count, success, error_message, created_at, updated_at, agent_id, source, source_host, imported_at FROM process_snapshots 
+WHERE (user_id = $1 OR (user_id IS NULL AND $2::BOOLEAN)) AND agent_id = $3
+ORDER BY created_at DESC
+`
+
+type GetProcessSnapshotsByAgentParams struct {
+	UserID         pgtype.Int8 `json:"user_id"`
+	IncludeUnowned bool        `json:"include_unowned"`
+	AgentID        pgtype.Int8 `json:"agent_id"`
+}
+
+func (q *Queries) GetProcessSnapshotsByAgent(ctx context.Context, arg GetProcessSnapshotsByAgentParams) ([]ProcessSnapshot, error) {
+	rows, err := q.db.Query(ctx, getProcessSnapshotsByAgent, arg.UserID, arg.IncludeUnowned, arg.AgentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ProcessSnapshot
+	for rows.Next() {
+		var i ProcessSnapshot
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.WebhookUrl,
+			&i.SnapshotType,
+			&i.ProcessCount,
+			&i.Success,
+			&i.ErrorMessage,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.AgentID,
+			&i.Source,
+			&i.SourceHost,
+			&i.ImportedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getProcessSnapshotsByType = `-- name: GetProcessSnapshotsByType :many
-SELECT id, user_id, webhook_url, snapshot_type, process_count, success, error_message, created_at, updated_at FROM process_snapshots 
-WHERE (user_id = $1 OR user_id IS NULL) AND snapshot_type = $2
+SELECT id, user_id, webhook_url, snapshot_type, process_count, success, error_message, created_at, updated_at, agent_id, source, source_host, imported_at FROM process_snapshots 
+WHERE (user_id = $1 OR (user_id IS NULL AND $2::BOOLEAN)) AND snapshot_type = $3
 ORDER BY created_at DESC
 `
 
 type GetProcessSnapshotsByTypeParams struct {
-	UserID       pgtype.Int8 `json:"user_id"`
-	SnapshotType string      `json:"snapshot_type"`
+	UserID         pgtype.Int8 `json:"user_id"`
+	IncludeUnowned bool        `json:"include_unowned"`
+	SnapshotType   string      `json:"snapshot_type"`
 }
 
 func (q *Queries) GetProcessSnapshotsByType(ctx context.Context, arg GetProcessSnapshotsByTypeParams) ([]ProcessSnapshot, error) {
-	rows, err := q.db.Query(ctx, getProcessSnapshotsByType, arg.UserID, arg.SnapshotType)
+	rows, err := q.db.Query(ctx, getProcessSnapshotsByType, arg.UserID, arg.IncludeUnowned, arg.SnapshotType)
 	if err != nil {
 		return nil, err
 	}
@@ -861,6 +1052,10 @@ func (q *Queries) GetProcessSnapshotsByType(ctx context.Context, arg GetProcessS
 			&i.ErrorMessage,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.AgentID,
+			&i.Source,
+			&i.SourceHost,
+			&i.ImportedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -873,13 +1068,18 @@ func (q *Queries) GetProcessSnapshotsByType(ctx context.Context, arg GetProcessS
 }
 
 const getProcessSnapshotsByUser = `-- name: GetProcessSnapshotsByUser :many
-SELECT id, user_id, webhook_url, snapshot_type, process_count, success, error_message, created_at, updated_at FROM process_snapshots 
-WHERE user_id = $1 OR user_id IS NULL
+SELECT id, user_id, webhook_url, snapshot_type, process_count, success, error_message, created_at, updated_at, agent_id, source, source_host, imported_at FROM process_snapshots 
+WHERE user_id = $1 OR (user_id IS NULL AND $2::BOOLEAN)
 ORDER BY created_at DESC
 `
 
-func (q *Queries) GetProcessSnapshotsByUser(ctx context.Context, userID pgtype.Int8) ([]ProcessSnapshot, error) {
-	rows, err := q.db.Query(ctx, getProcessSnapshotsByUser, userID)
+type GetProcessSnapshotsByUserParams struct {
+	UserID         pgtype.Int8 `json:"user_id"`
+	IncludeUnowned bool        `json:"include_unowned"`
+}
+
+func (q *Queries) GetProcessSnapshotsByUser(ctx context.Context, arg GetProcessSnapshotsByUserParams) ([]ProcessSnapshot, error) {
+	rows, err := q.db.Query(ctx, getProcessSnapshotsByUser, arg.UserID, arg.IncludeUnowned)
 	if err != nil {
 		return nil, err
 	}
@@ -897,6 +1097,10 @@ func (q *Queries) GetProcessSnapshotsByUser(ctx context.Context, userID pgtype.I
 			&i.ErrorMessage,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.AgentID,
+			&i.Source,
+			&i.SourceHost,
+			&i.ImportedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -915,7 +1119,7 @@ SELECT
     COALESCE(AVG(process_count), 0) as avg_processes_per_snapshot
 FROM process_info pi
 LEFT JOIN process_snapshots ps ON ps.id = pi.snapshot_id
-WHERE pi.user_id = $1 OR pi.user_id IS NULL
+WHERE pi.user_id = $1 OR (pi.user_id IS NULL AND $2::BOOLEAN)
 `
 
 type GetSnapshotStatisticsRow struct {
@@ -924,15 +1128,20 @@ type GetSnapshotStatisticsRow struct {
 	AvgProcessesPerSnapshot interface{} `json:"avg_processes_per_snapshot"`
 }
 
-func (q *Queries) GetSnapshotStatistics(ctx context.Context, userID pgtype.Int8) (GetSnapshotStatisticsRow, error) {
-	row := q.db.QueryRow(ctx, getSnapshotStatistics, userID)
+type GetSnapshotStatisticsParams struct {
+	UserID         pgtype.Int8 `json:"user_id"`
+	IncludeUnowned bool        `json:"include_unowned"`
+}
+
+func (q *Queries) GetSnapshotStatistics(ctx context.Context, arg GetSnapshotStatisticsParams) (GetSnapshotStatisticsRow, error) {
+	row := q.db.QueryRow(ctx, getSnapshotStatistics, arg.UserID, arg.IncludeUnowned)
 	var i GetSnapshotStatisticsRow
 	err := row.Scan(&i.TotalSnapshots, &i.TotalProcesses, &i.AvgProcessesPerSnapshot)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, name, password, created_at, updated_at FROM users WHERE id = $1 LIMIT 1
+SELECT id, name, password, created_at, updated_at, role, oidc_issuer, oidc_subject FROM users WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetUser(ctx context.Context, id int64) (User, error) {
@@ -944,12 +1153,15 @@ func (q *Queries) GetUser(ctx context.Context, id int64) (User, error) {
 		&i.Password,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.OidcIssuer,
+		&i.OidcSubject,
 	)
 	return i, err
 }
 
 const getUserByName = `-- name: GetUserByName :one
-SELECT id, name, password, created_at, updated_at FROM users WHERE name = $1 LIMIT 1
+SELECT id, name, password, created_at, updated_at, role, oidc_issuer, oidc_subject FROM users WHERE name = $1 LIMIT 1
 `
 
 func (q *Queries) GetUserByName(ctx context.Context, name string) (User, error) {
@@ -961,12 +1173,40 @@ func (q *Queries) GetUserByName(ctx context.Context, name string) (User, error)
 		&i.Password,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.OidcIssuer,
+		&i.OidcSubject,
+	)
+	return i, err
+}
+
+const getUserByOIDCSubject = `-- name: GetUserByOIDCSubject :one
+SELECT id, name, password, created_at, updated_at, role, oidc_issuer, oidc_subject FROM users WHERE oidc_issuer = $1 AND oidc_subject = $2 LIMIT 1
+`
+
+type GetUserByOIDCSubjectParams struct {
+	OidcIssuer  pgtype.Text `json:"oidc_issuer"`
+	OidcSubject pgtype.Text `json:"oidc_subject"`
+}
+
+func (q *Queries) GetUserByOIDCSubject(ctx context.Context, arg GetUserByOIDCSubjectParams) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByOIDCSubject, arg.OidcIssuer, arg.OidcSubject)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Password,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Role,
+		&i.OidcIssuer,
+		&i.OidcSubject,
 	)
 	return i, err
 }
 
 const getUsers = `-- name: GetUsers :many
-SELECT id, name, password, created_at, updated_at FROM users ORDER BY created_at DESC
+SELECT id, name, password, created_at, updated_at, role, oidc_issuer, oidc_subject FROM users ORDER BY created_at DESC
 `
 
 func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
@@ -984,6 +1224,9 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 			&i.Password,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Role,
+			&i.OidcIssuer,
+			&i.OidcSubject,
 		); err != nil {
 			return nil, err
 		}
@@ -995,128 +1238,29 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	return items, nil
 }
 
-const updateNextProcess = `-- name: UpdateNextProcess :one
-UPDATE process_info
-SET next_id = $1, next_process_id = $2, next_process_name = $3, next_process_eprocess_address = $4
-WHERE id = $5 RETURNING id, snapshot_id, user_id, process_id, parent_process_id, process_name, thread_count, handle_count, base_priority, create_time, user_time, kernel_time, working_set_size, peak_working_set_size, virtual_size, peak_virtual_size, read_operation_count, write_operation_count, other_operation_count, read_transfer_count, write_transfer_count, other_transfer_count, page_fault_count, current_process_address, next_process_eprocess_address, next_process_name, next_process_id, next_id, previous_process_eprocess_address, previous_process_name, previous_process_id, previous_id, created_at, updated_at
+const reserveProcessInfoIDs = `-- name: ReserveProcessInfoIDs :many
+SELECT nextval(pg_get_serial_sequence('process_info', 'id'))::BIGINT AS id
+FROM generate_series(1, $1::INTEGER)
 `
 
-type UpdateNextProcessParams struct {
-	NextID                     pgtype.Int8 `json:"next_id"`
-	NextProcessID              pgtype.Int8 `json:"next_process_id"`
-	NextProcessName            pgtype.Text `json:"next_process_name"`
-	NextProcessEprocessAddress pgtype.Text `json:"next_process_eprocess_address"`
-	ID                         int64       `json:"id"`
-}
-
-func (q *Queries) UpdateNextProcess(ctx context.Context, arg UpdateNextProcessParams) (ProcessInfo, error) {
-	row := q.db.QueryRow(ctx, updateNextProcess,
-		arg.NextID,
-		arg.NextProcessID,
-		arg.NextProcessName,
-		arg.NextProcessEprocessAddress,
-		arg.ID,
-	)
-	var i ProcessInfo
-	err := row.Scan(
-		&i.ID,
-		&i.SnapshotID,
-		&i.UserID,
-		&i.ProcessID,
-		&i.ParentProcessID,
-		&i.ProcessName,
-		&i.ThreadCount,
-		&i.HandleCount,
-		&i.BasePriority,
-		&i.CreateTime,
-		&i.UserTime,
-		&i.KernelTime,
-		&i.WorkingSetSize,
-		&i.PeakWorkingSetSize,
-		&i.VirtualSize,
-		&i.PeakVirtualSize,
-		&i.ReadOperationCount,
-		&i.WriteOperationCount,
-		&i.OtherOperationCount,
-		&i.ReadTransferCount,
-		&i.WriteTransferCount,
-		&i.OtherTransferCount,
-		&i.PageFaultCount,
-		&i.CurrentProcessAddress,
-		&i.NextProcessEprocessAddress,
-		&i.NextProcessName,
-		&i.NextProcessID,
-		&i.NextID,
-		&i.PreviousProcessEprocessAddress,
-		&i.PreviousProcessName,
-		&i.PreviousProcessID,
-		&i.PreviousID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
-}
-
-const updatePreviousProcess = `-- name: UpdatePreviousProcess :one
-UPDATE process_info
-SET previous_id = $1, previous_process_id = $2, previous_process_name = $3, previous_process_eprocess_address = $4
-WHERE id = $5 RETURNING id, snapshot_id, user_id, process_id, parent_process_id, process_name, thread_count, handle_count, base_priority, create_time, user_time, kernel_time, working_set_size, peak_working_set_size, virtual_size, peak_virtual_size, read_operation_count, write_operation_count, other_operation_count, read_transfer_count, write_transfer_count, other_transfer_count, page_fault_count, current_process_address, next_process_eprocess_address, next_process_name, next_process_id, next_id, previous_process_eprocess_address, previous_process_name, previous_process_id, previous_id, created_at, updated_at
-`
-
-type UpdatePreviousProcessParams struct {
-	PreviousID                     pgtype.Int8 `json:"previous_id"`
-	PreviousProcessID              pgtype.Int8 `json:"previous_process_id"`
-	PreviousProcessName            pgtype.Text `json:"previous_process_name"`
-	PreviousProcessEprocessAddress pgtype.Text `json:"previous_process_eprocess_address"`
-	ID                             int64       `json:"id"`
-}
-
-func (q *Queries) UpdatePreviousProcess(ctx context.Context, arg UpdatePreviousProcessParams) (ProcessInfo, error) {
-	row := q.db.QueryRow(ctx, updatePreviousProcess,
-		arg.PreviousID,
-		arg.PreviousProcessID,
-		arg.PreviousProcessName,
-		arg.PreviousProcessEprocessAddress,
-		arg.ID,
-	)
-	var i ProcessInfo
-	err := row.Scan(
-		&i.ID,
-		&i.SnapshotID,
-		&i.UserID,
-		&i.ProcessID,
-		&i.ParentProcessID,
-		&i.ProcessName,
-		&i.ThreadCount,
-		&i.HandleCount,
-		&i.BasePriority,
-		&i.CreateTime,
-		&i.UserTime,
-		&i.KernelTime,
-		&i.WorkingSetSize,
-		&i.PeakWorkingSetSize,
-		&i.VirtualSize,
-		&i.PeakVirtualSize,
-		&i.ReadOperationCount,
-		&i.WriteOperationCount,
-		&i.OtherOperationCount,
-		&i.ReadTransferCount,
-		&i.WriteTransferCount,
-		&i.OtherTransferCount,
-		&i.PageFaultCount,
-		&i.CurrentProcessAddress,
-		&i.NextProcessEprocessAddress,
-		&i.NextProcessName,
-		&i.NextProcessID,
-		&i.NextID,
-		&i.PreviousProcessEprocessAddress,
-		&i.PreviousProcessName,
-		&i.PreviousProcessID,
-		&i.PreviousID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-	)
-	return i, err
+func (q *Queries) ReserveProcessInfoIDs(ctx context.Context, count int32) ([]int64, error) {
+	rows, err := q.db.Query(ctx, reserveProcessInfoIDs, count)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const updateProcessSnapshotCount = `-- name: UpdateProcessSnapshotCount :exec
@@ -1136,7 +1280,7 @@ func (q *Queries) UpdateProcessSnapshotCount(ctx context.Context, arg UpdateProc
 }
 
 const updateUser = `-- name: UpdateUser :one
-UPDATE users SET name = $1, password = $2, updated_at = NOW() WHERE id = $3 RETURNING id, name, password, created_at, updated_at
+UPDATE users SET name = $1, password = $2, updated_at = NOW() WHERE id = $3 RETURNING id, name, password, created_at, updated_at, role, oidc_issuer, oidc_subject
 `
 
 type UpdateUserParams struct {
@@ -1154,6 +1298,34 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Password,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.OidcIssuer,
+		&i.OidcSubject,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2 RETURNING id, name, password, created_at, updated_at, role, oidc_issuer, oidc_subject
+`
+
+type UpdateUserRoleParams struct {
+	Role string `json:"role"`
+	ID   int64  `json:"id"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserRole, arg.Role, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Password,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Role,
+		&i.OidcIssuer,
+		&i.OidcSubject,
 	)
 	return i, err
 }
@@ -0,0 +1,564 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go-api/internal/agentclient"
+	"go-api/internal/db"
+	"go-api/internal/outbound"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AgentHandler struct {
+	queries *db.Queries
+	policy  *outbound.Policy
+}
+
+func NewAgentHandler(dbpool *pgxpool.Pool, policy *outbound.Policy) *AgentHandler {
+	return &AgentHandler{
+		queries: db.New(dbpool),
+		policy:  policy,
+	}
+}
+
+type CreateAgentRequest struct {
+	Name    string   `json:"name"`
+	BaseURL string   `json:"base_url"`
+	Tags    []string `json:"tags"`
+	OSBuild *string  `json:"os_build,omitempty"`
+}
+
+type UpdateAgentRequest struct {
+	Name    *string   `json:"name,omitempty"`
+	BaseURL *string   `json:"base_url,omitempty"`
+	Tags    *[]string `json:"tags,omitempty"`
+	OSBuild *string   `json:"os_build,omitempty"`
+}
+
+// AgentCredentialsRequest replaces all of an agent's credentials; omitted
+// fields are cleared
+type AgentCredentialsRequest struct {
+	SigningSecret  *string `json:"signing_secret,omitempty"`
+	GenerateSecret bool    `json:"generate_secret"`
+	ClientCertPEM  *string `json:"client_cert_pem,omitempty"`
+	ClientKeyPEM   *string `json:"client_key_pem,omitempty"`
+	CACertPEM      *string `json:"ca_cert_pem,omitempty"`
+}
+
+type AgentResponse struct {
+	ID             int64    `json:"id"`
+	OwnerID        *int64   `json:"ownerId,omitempty"`
+	Name           string   `json:"name"`
+	BaseURL        string   `json:"baseUrl"`
+	Tags           []string `json:"tags"`
+	OSBuild        *string  `json:"osBuild,omitempty"`
+	LastSeenAt     *string  `json:"lastSeenAt,omitempty"`
+	OfflineSince   *string  `json:"offlineSince,omitempty"` // first failed call since the agent last answered
+	SigningEnabled bool     `json:"signingEnabled"`
+	MTLSEnabled    bool     `json:"mtlsEnabled"`
+	CustomCA       bool     `json:"customCa"`
+	CreatedAt      string   `json:"createdAt"`
+	UpdatedAt      string   `json:"updatedAt"`
+}
+
+// normalizeAgentURL canonicalizes an agent base URL so the same host is not
+// registered twice under slightly different spellings
+func normalizeAgentURL(raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("invalid base_url: %w", err)
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return "", fmt.Errorf("base_url must use http or https")
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	if host == "" {
+		return "", fmt.Errorf("base_url must include a host")
+	}
+
+	port := parsed.Port()
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		// IPv6 literal without port
+		host = "[" + host + "]"
+	}
+
+	path := strings.TrimRight(parsed.EscapedPath(), "/")
+
+	return scheme + "://" + host + path, nil
+}
+
+func normalizeTags(tags []string) []string {
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// Get all registered agents
+func (h *AgentHandler) GetAgents(c *fiber.Ctx) error {
+	agents, err := h.queries.GetAgents(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch agents",
+		})
+	}
+
+	response := make([]AgentResponse, len(agents))
+	for i, agent := range agents {
+		response[i] = toAgentResponse(agent)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  response,
+		"count": len(response),
+	})
+}
+
+// Get a specific agent by ID
+func (h *AgentHandler) GetAgent(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid agent ID",
+		})
+	}
+
+	agent, err := h.queries.GetAgent(c.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Agent not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch agent",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": toAgentResponse(agent),
+	})
+}
+
+// Get all snapshots captured from an agent
+func (h *AgentHandler) GetAgentSnapshots(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid agent ID",
+		})
+	}
+
+	agent, err := h.queries.GetAgent(c.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Agent not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch agent",
+		})
+	}
+
+	snapshots, err := h.queries.GetProcessSnapshotsByAgent(c.Context(), db.GetProcessSnapshotsByAgentParams{
+		UserID:         pgtype.Int8{Int64: userID, Valid: true},
+		IncludeUnowned: isAdmin(c),
+		AgentID:        pgtype.Int8{Int64: agent.ID, Valid: true},
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch snapshots",
+		})
+	}
+
+	response := make([]SnapshotResponse, len(snapshots))
+	for i, snapshot := range snapshots {
+		response[i] = toSnapshotResponse(snapshot)
+	}
+
+	return c.JSON(fiber.Map{
+		"agent":     toAgentResponse(agent),
+		"snapshots": response,
+	})
+}
+
+// Register a new agent
+func (h *AgentHandler) CreateAgent(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	var req CreateAgentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if strings.TrimSpace(req.Name) == "" || req.BaseURL == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "name and base_url are required",
+		})
+	}
+
+	baseURL, err := normalizeAgentURL(req.BaseURL)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.policy.CheckURL(baseURL); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var osBuild pgtype.Text
+	if req.OSBuild != nil {
+		osBuild = pgtype.Text{String: *req.OSBuild, Valid: true}
+	}
+
+	agent, err := h.queries.CreateAgent(c.Context(), db.CreateAgentParams{
+		OwnerID: pgtype.Int8{Int64: userID, Valid: true},
+		Name:    strings.TrimSpace(req.Name),
+		BaseUrl: baseURL,
+		Tags:    normalizeTags(req.Tags),
+		OsBuild: osBuild,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "An agent with this base_url is already registered",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create agent",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"data":    toAgentResponse(agent),
+		"message": "Agent registered successfully",
+	})
+}
+
+// Update an agent
+func (h *AgentHandler) UpdateAgent(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid agent ID",
+		})
+	}
+
+	var req UpdateAgentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	current, err := h.queries.GetAgent(c.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Agent not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch agent",
+		})
+	}
+
+	if current.OwnerID.Valid && current.OwnerID.Int64 != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	params := db.UpdateAgentParams{
+		ID:      id,
+		Name:    current.Name,
+		BaseUrl: current.BaseUrl,
+		Tags:    current.Tags,
+		OsBuild: current.OsBuild,
+	}
+
+	if req.Name != nil {
+		if strings.TrimSpace(*req.Name) == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "name cannot be empty",
+			})
+		}
+		params.Name = strings.TrimSpace(*req.Name)
+	}
+
+	if req.BaseURL != nil {
+		baseURL, err := normalizeAgentURL(*req.BaseURL)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if err := h.policy.CheckURL(baseURL); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		params.BaseUrl = baseURL
+
+		if (current.ClientCertPem.Valid || current.CaCertPem.Valid) && !strings.HasPrefix(baseURL, "https://") {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Client certificates and CA require an https base_url",
+			})
+		}
+	}
+
+	if req.Tags != nil {
+		params.Tags = normalizeTags(*req.Tags)
+	}
+
+	if req.OSBuild != nil {
+		params.OsBuild = pgtype.Text{String: *req.OSBuild, Valid: *req.OSBuild != ""}
+	}
+
+	agent, err := h.queries.UpdateAgent(c.Context(), params)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "An agent with this base_url is already registered",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update agent",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":    toAgentResponse(agent),
+		"message": "Agent updated successfully",
+	})
+}
+
+// Set or rotate the signing secret and mTLS material of an agent
+func (h *AgentHandler) UpdateAgentCredentials(c *fiber.Ctx) error {
+	current, err := h.fetchOwnedAgent(c)
+	if err != nil {
+		return err
+	}
+
+	var req AgentCredentialsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// Empty strings clear a credential like omitted fields do
+	for _, field := range []**string{&req.SigningSecret, &req.ClientCertPEM, &req.ClientKeyPEM, &req.CACertPEM} {
+		if *field != nil && strings.TrimSpace(**field) == "" {
+			*field = nil
+		}
+	}
+
+	if req.GenerateSecret && req.SigningSecret != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Use either signing_secret or generate_secret, not both",
+		})
+	}
+
+	var generatedSecret string
+	if req.GenerateSecret {
+		generatedSecret, err = agentclient.GenerateSecret()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to generate signing secret",
+			})
+		}
+		req.SigningSecret = &generatedSecret
+	}
+
+	if req.SigningSecret != nil && len(*req.SigningSecret) < agentclient.MinSecretLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("signing_secret must be at least %d characters", agentclient.MinSecretLength),
+		})
+	}
+
+	params := db.UpdateAgentCredentialsParams{
+		ID:            current.ID,
+		SigningSecret: optionalText(req.SigningSecret),
+		ClientCertPem: optionalText(req.ClientCertPEM),
+		ClientKeyPem:  optionalText(req.ClientKeyPEM),
+		CaCertPem:     optionalText(req.CACertPEM),
+	}
+
+	tlsConfig, err := agentclient.TLSConfig(params.ClientCertPem.String, params.ClientKeyPem.String, params.CaCertPem.String)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if tlsConfig != nil && !strings.HasPrefix(current.BaseUrl, "https://") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Client certificates and CA require an https base_url",
+		})
+	}
+
+	agent, err := h.queries.UpdateAgentCredentials(c.Context(), params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update agent credentials",
+		})
+	}
+
+	response := fiber.Map{
+		"data":    toAgentResponse(agent),
+		"message": "Agent credentials updated successfully",
+	}
+
+	// A generated secret is only ever shown once
+	if generatedSecret != "" {
+		response["signingSecret"] = generatedSecret
+	}
+
+	return c.JSON(response)
+}
+
+// Delete an agent (snapshots are kept, their agent reference is cleared)
+func (h *AgentHandler) DeleteAgent(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid agent ID",
+		})
+	}
+
+	agent, err := h.queries.GetAgent(c.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Agent not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch agent",
+		})
+	}
+
+	if agent.OwnerID.Valid && agent.OwnerID.Int64 != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	if err := h.queries.DeleteAgent(c.Context(), id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete agent",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Agent deleted successfully",
+	})
+}
+
+// fetchOwnedAgent loads the agent in the :id param and checks that the
+// authenticated user may modify it
+func (h *AgentHandler) fetchOwnedAgent(c *fiber.Ctx) (db.Agent, error) {
+	userID := c.Locals("userID").(int64)
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return db.Agent{}, fiber.NewError(fiber.StatusBadRequest, "Invalid agent ID")
+	}
+
+	agent, err := h.queries.GetAgent(c.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return db.Agent{}, fiber.NewError(fiber.StatusNotFound, "Agent not found")
+		}
+		return db.Agent{}, fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch agent")
+	}
+
+	if agent.OwnerID.Valid && agent.OwnerID.Int64 != userID {
+		return db.Agent{}, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return agent, nil
+}
+
+func toAgentResponse(agent db.Agent) AgentResponse {
+	response := AgentResponse{
+		ID:             agent.ID,
+		Name:           agent.Name,
+		BaseURL:        agent.BaseUrl,
+		Tags:           agent.Tags,
+		SigningEnabled: agent.SigningSecret.Valid,
+		MTLSEnabled:    agent.ClientCertPem.Valid,
+		CustomCA:       agent.CaCertPem.Valid,
+		CreatedAt:      agent.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:      agent.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+
+	if response.Tags == nil {
+		response.Tags = []string{}
+	}
+
+	if agent.OwnerID.Valid {
+		response.OwnerID = &agent.OwnerID.Int64
+	}
+
+	if agent.OsBuild.Valid {
+		response.OSBuild = &agent.OsBuild.String
+	}
+
+	if agent.LastSeenAt.Valid {
+		lastSeen := agent.LastSeenAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		response.LastSeenAt = &lastSeen
+	}
+
+	if agent.OfflineSince.Valid {
+		offlineSince := agent.OfflineSince.Time.Format("2006-01-02T15:04:05Z07:00")
+		response.OfflineSince = &offlineSince
+	}
+
+	return response
+}
@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"strconv"
+
+	"go-api/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AlertHandler struct {
+	queries *db.Queries
+}
+
+func NewAlertHandler(dbpool *pgxpool.Pool) *AlertHandler {
+	return &AlertHandler{
+		queries: db.New(dbpool),
+	}
+}
+
+type AlertRuleRequest struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Kind        string  `json:"kind"`
+	Query       *string `json:"query,omitempty"`
+	ParentQuery *string `json:"parent_query,omitempty"`
+	IssueType   *string `json:"issue_type,omitempty"`
+	Severity    string  `json:"severity"`
+	Enabled     *bool   `json:"enabled,omitempty"`
+}
+
+type AlertRuleResponse struct {
+	ID          int64   `json:"id"`
+	UserID      int64   `json:"userId"`
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Kind        string  `json:"kind"`
+	Query       *string `json:"query,omitempty"`
+	ParentQuery *string `json:"parentQuery,omitempty"`
+	IssueType   *string `json:"issueType,omitempty"`
+	Severity    string  `json:"severity"`
+	Enabled     bool    `json:"enabled"`
+	CreatedAt   string  `json:"createdAt"`
+	UpdatedAt   string  `json:"updatedAt"`
+}
+
+type AlertResponse struct {
+	ID             int64   `json:"id"`
+	RuleID         *int64  `json:"ruleId,omitempty"` // unset once the rule is deleted
+	UserID         *int64  `json:"userId,omitempty"`
+	SnapshotID     int64   `json:"snapshotId"`
+	ProcessInfoID  *int64  `json:"processInfoId,omitempty"`
+	RuleName       string  `json:"ruleName"`
+	Severity       string  `json:"severity"`
+	Message        string  `json:"message"`
+	ProcessID      *int64  `json:"processId,omitempty"`
+	ProcessName    *string `json:"processName,omitempty"`
+	Acknowledged   bool    `json:"acknowledged"`
+	AcknowledgedAt *string `json:"acknowledgedAt,omitempty"`
+	AcknowledgedBy *int64  `json:"acknowledgedBy,omitempty"`
+	CreatedAt      string  `json:"createdAt"`
+}
+
+// List alerts, paginated, newest first
+func (h *AlertHandler) GetAlerts(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	page, err := listPage(c)
+	if err != nil {
+		return err
+	}
+	acknowledged, err := queryBool(c, "acknowledged")
+	if err != nil {
+		return err
+	}
+	ruleID, err := queryInt8(c, "rule_id")
+	if err != nil {
+		return err
+	}
+	snapshotID, err := queryInt8(c, "snapshot_id")
+	if err != nil {
+		return err
+	}
+	from, err := queryTime(c, "from")
+	if err != nil {
+		return err
+	}
+	to, err := queryTime(c, "to")
+	if err != nil {
+		return err
+	}
+
+	severity := queryText(c, "severity")
+	if severity.Valid && !alertSeverities[severity.String] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "severity must be one of low, medium, high or critical",
+		})
+	}
+
+	result, err := h.queries.ListAlerts(c.Context(), db.ListAlertsParams{
+		UserID:         pgtype.Int8{Int64: userID, Valid: true},
+		IncludeUnowned: isAdmin(c),
+		Severity:       severity,
+		Acknowledged:   acknowledged,
+		RuleID:         ruleID,
+		SnapshotID:     snapshotID,
+		CreatedFrom:    from,
+		CreatedTo:      to,
+		Page:           page,
+	})
+	if err != nil {
+		return listError(c, err, "Failed to fetch alerts")
+	}
+
+	response := make([]AlertResponse, len(result.Rows))
+	for i, alert := range result.Rows {
+		response[i] = toAlertResponse(alert)
+	}
+
+	return c.JSON(pageResponse(response, result.NextCursor))
+}
+
+// Get a specific alert by ID
+func (h *AlertHandler) GetAlert(c *fiber.Ctx) error {
+	alert, err := h.fetchAccessibleAlert(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": toAlertResponse(alert),
+	})
+}
+
+// Mark an alert as handled by the authenticated user
+func (h *AlertHandler) AcknowledgeAlert(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	current, err := h.fetchAccessibleAlert(c)
+	if err != nil {
+		return err
+	}
+
+	alert, err := h.queries.AcknowledgeAlert(c.Context(), db.AcknowledgeAlertParams{
+		ID:             current.ID,
+		AcknowledgedBy: pgtype.Int8{Int64: userID, Valid: true},
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to acknowledge alert",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":    toAlertResponse(alert),
+		"message": "Alert acknowledged",
+	})
+}
+
+// Clear the acknowledgement of an alert
+func (h *AlertHandler) ReopenAlert(c *fiber.Ctx) error {
+	current, err := h.fetchAccessibleAlert(c)
+	if err != nil {
+		return err
+	}
+
+	alert, err := h.queries.ReopenAlert(c.Context(), current.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to reopen alert",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":    toAlertResponse(alert),
+		"message": "Alert reopened",
+	})
+}
+
+// Get all alert rules of the user
+func (h *AlertHandler) GetRules(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	rules, err := h.queries.GetAlertRulesByUser(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch alert rules",
+		})
+	}
+
+	response := make([]AlertRuleResponse, len(rules))
+	for i, rule := range rules {
+		response[i] = toAlertRuleResponse(rule)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  response,
+		"count": len(response),
+	})
+}
+
+// Get a specific alert rule by ID
+func (h *AlertHandler) GetRule(c *fiber.Ctx) error {
+	rule, err := h.fetchOwnedRule(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": toAlertRuleResponse(rule),
+	})
+}
+
+// Create an alert rule, evaluated against every new snapshot of the user
+func (h *AlertHandler) CreateRule(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	var req AlertRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	rule := req.toAlertRule()
+	if err := validateAlertRule(rule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	created, err := h.queries.CreateAlertRule(c.Context(), db.CreateAlertRuleParams{
+		UserID:      userID,
+		Name:        rule.Name,
+		Description: rule.Description,
+		Kind:        rule.Kind,
+		Query:       rule.Query,
+		ParentQuery: rule.ParentQuery,
+		IssueType:   rule.IssueType,
+		Severity:    rule.Severity,
+		Enabled:     rule.Enabled,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create alert rule",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"data":    toAlertRuleResponse(created),
+		"message": "Alert rule created successfully",
+	})
+}
+
+// Replace the definition of an alert rule. Alerts it already raised keep
+// the name and severity they were raised with.
+func (h *AlertHandler) UpdateRule(c *fiber.Ctx) error {
+	current, err := h.fetchOwnedRule(c)
+	if err != nil {
+		return err
+	}
+
+	var req AlertRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	rule := req.toAlertRule()
+	if err := validateAlertRule(rule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	updated, err := h.queries.UpdateAlertRule(c.Context(), db.UpdateAlertRuleParams{
+		ID:          current.ID,
+		Name:        rule.Name,
+		Description: rule.Description,
+		Kind:        rule.Kind,
+		Query:       rule.Query,
+		ParentQuery: rule.ParentQuery,
+		IssueType:   rule.IssueType,
+		Severity:    rule.Severity,
+		Enabled:     rule.Enabled,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update alert rule",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":    toAlertRuleResponse(updated),
+		"message": "Alert rule updated successfully",
+	})
+}
+
+// Delete an alert rule. Its alerts are kept.
+func (h *AlertHandler) DeleteRule(c *fiber.Ctx) error {
+	current, err := h.fetchOwnedRule(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.queries.DeleteAlertRule(c.Context(), current.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete alert rule",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Alert rule deleted successfully",
+	})
+}
+
+// fetchAccessibleAlert loads the alert in the :id param and checks that the
+// authenticated user can read it
+func (h *AlertHandler) fetchAccessibleAlert(c *fiber.Ctx) (db.Alert, error) {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return db.Alert{}, fiber.NewError(fiber.StatusBadRequest, "Invalid alert ID")
+	}
+
+	alert, err := h.queries.GetAlert(c.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return db.Alert{}, fiber.NewError(fiber.StatusNotFound, "Alert not found")
+		}
+		return db.Alert{}, fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch alert")
+	}
+
+	if !canAccess(c, alert.UserID) {
+		return db.Alert{}, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return alert, nil
+}
+
+// fetchOwnedRule loads the alert rule in the :id param and checks that it
+// belongs to the authenticated user
+func (h *AlertHandler) fetchOwnedRule(c *fiber.Ctx) (db.AlertRule, error) {
+	userID := c.Locals("userID").(int64)
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return db.AlertRule{}, fiber.NewError(fiber.StatusBadRequest, "Invalid alert rule ID")
+	}
+
+	rule, err := h.queries.GetAlertRule(c.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return db.AlertRule{}, fiber.NewError(fiber.StatusNotFound, "Alert rule not found")
+		}
+		return db.AlertRule{}, fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch alert rule")
+	}
+
+	if rule.UserID != userID {
+		return db.AlertRule{}, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return rule, nil
+}
+
+// toAlertRule fills in the defaults: rules are enabled and of medium
+// severity unless the request says otherwise
+func (req AlertRuleRequest) toAlertRule() db.AlertRule {
+	rule := db.AlertRule{
+		Name:        req.Name,
+		Description: optionalText(req.Description),
+		Kind:        req.Kind,
+		Query:       optionalText(req.Query),
+		ParentQuery: optionalText(req.ParentQuery),
+		IssueType:   optionalText(req.IssueType),
+		Severity:    req.Severity,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+	}
+	if rule.Severity == "" {
+		rule.Severity = "medium"
+	}
+	return rule
+}
+
+func toAlertRuleResponse(rule db.AlertRule) AlertRuleResponse {
+	response := AlertRuleResponse{
+		ID:        rule.ID,
+		UserID:    rule.UserID,
+		Name:      rule.Name,
+		Kind:      rule.Kind,
+		Severity:  rule.Severity,
+		Enabled:   rule.Enabled,
+		CreatedAt: rule.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: rule.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+
+	if rule.Description.Valid {
+		response.Description = &rule.Description.String
+	}
+
+	if rule.Query.Valid {
+		response.Query = &rule.Query.String
+	}
+
+	if rule.ParentQuery.Valid {
+		response.ParentQuery = &rule.ParentQuery.String
+	}
+
+	if rule.IssueType.Valid {
+		response.IssueType = &rule.IssueType.String
+	}
+
+	return response
+}
+
+func toAlertResponse(alert db.Alert) AlertResponse {
+	response := AlertResponse{
+		ID:           alert.ID,
+		SnapshotID:   alert.SnapshotID,
+		RuleName:     alert.RuleName,
+		Severity:     alert.Severity,
+		Message:      alert.Message,
+		Acknowledged: alert.AcknowledgedAt.Valid,
+		CreatedAt:    alert.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+
+	if alert.RuleID.Valid {
+		response.RuleID = &alert.RuleID.Int64
+	}
+
+	if alert.UserID.Valid {
+		response.UserID = &alert.UserID.Int64
+	}
+
+	if alert.ProcessInfoID.Valid {
+		response.ProcessInfoID = &alert.ProcessInfoID.Int64
+	}
+
+	if alert.ProcessID.Valid {
+		response.ProcessID = &alert.ProcessID.Int64
+	}
+
+	if alert.ProcessName.Valid {
+		response.ProcessName = &alert.ProcessName.String
+	}
+
+	if alert.AcknowledgedAt.Valid {
+		acknowledgedAt := alert.AcknowledgedAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		response.AcknowledgedAt = &acknowledgedAt
+	}
+
+	if alert.AcknowledgedBy.Valid {
+		response.AcknowledgedBy = &alert.AcknowledgedBy.Int64
+	}
+
+	return response
+}
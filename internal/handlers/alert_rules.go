@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-api/internal/db"
+	"go-api/internal/notify"
+	"go-api/internal/search"
+	"go-api/internal/stream"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Alert rule kinds
+const (
+	ruleKindProcess   = "process"   // processes matching query
+	ruleKindParent    = "parent"    // processes matching query whose parent does not match parent_query
+	ruleKindIntegrity = "integrity" // EPROCESS list issues of iteration snapshots
+)
+
+// Integrity issue a rule can alert on besides the link issue types: a
+// process found by a PID lookup but missing from the list walk
+const issueHiddenProcess = "hidden_process"
+
+// Most alerts one rule raises on one snapshot; a rule matching more is too
+// broad to be useful
+const maxAlertsPerRule = 100
+
+var alertSeverities = map[string]bool{
+	"low":      true,
+	"medium":   true,
+	"high":     true,
+	"critical": true,
+}
+
+var integrityIssueTypes = map[string]bool{
+	issueBrokenLink:       true,
+	issueGap:              true,
+	issueMissingLink:      true,
+	issueDuplicateAddress: true,
+	issueHiddenProcess:    true,
+}
+
+// alertMatch is one alert a rule raised
+type alertMatch struct {
+	ProcessInfoID pgtype.Int8
+	ProcessID     pgtype.Int8
+	ProcessName   pgtype.Text
+	Message       string
+}
+
+// validateAlertRule checks that a rule has the fields its kind needs and
+// that its queries parse
+func validateAlertRule(rule db.AlertRule) error {
+	if strings.TrimSpace(rule.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !alertSeverities[rule.Severity] {
+		return fmt.Errorf("severity must be one of low, medium, high or critical")
+	}
+
+	switch rule.Kind {
+	case ruleKindProcess, ruleKindParent:
+		if !rule.Query.Valid {
+			return fmt.Errorf("query is required for %s rules", rule.Kind)
+		}
+		if _, err := search.Parse(rule.Query.String, time.Now()); err != nil {
+			return err
+		}
+		if rule.Kind == ruleKindProcess && rule.ParentQuery.Valid {
+			return fmt.Errorf("parent_query is only used by parent rules")
+		}
+		if rule.Kind == ruleKindParent {
+			if !rule.ParentQuery.Valid {
+				return fmt.Errorf("parent_query is required for parent rules")
+			}
+			if _, err := search.Parse(rule.ParentQuery.String, time.Now()); err != nil {
+				return err
+			}
+		}
+		if rule.IssueType.Valid {
+			return fmt.Errorf("issue_type is only used by integrity rules")
+		}
+	case ruleKindIntegrity:
+		if rule.Query.Valid || rule.ParentQuery.Valid {
+			return fmt.Errorf("integrity rules take no query")
+		}
+		if rule.IssueType.Valid && !integrityIssueTypes[rule.IssueType.String] {
+			return fmt.Errorf("issue_type must be one of broken_link, gap, missing_link, duplicate_address or hidden_process")
+		}
+	default:
+		return fmt.Errorf("kind must be one of process, parent or integrity")
+	}
+
+	return nil
+}
+
+// evaluateAlertRules runs the enabled rules of the snapshot owner against a
+// newly persisted snapshot and records an alert per match. When
+// processInfoID is set only that process is checked. Like rates, alerts are
+// derived data: failures are logged and never fail the capture.
+func (h *WebhookHandler) evaluateAlertRules(ctx context.Context, snapshot db.ProcessSnapshot, processInfoID pgtype.Int8) {
+	if !snapshot.UserID.Valid {
+		return
+	}
+
+	rules, err := h.queries.GetEnabledAlertRulesByUser(ctx, snapshot.UserID.Int64)
+	if err != nil {
+		log.Warnf("failed to fetch alert rules for snapshot %d: %v", snapshot.ID, err)
+		return
+	}
+
+	evaluator := &alertEvaluator{queries: h.queries, snapshot: snapshot, processInfoID: processInfoID}
+	for _, rule := range rules {
+		matches, err := evaluator.evaluate(ctx, rule)
+		if err != nil {
+			log.Warnf("failed to evaluate alert rule %d on snapshot %d: %v", rule.ID, snapshot.ID, err)
+			continue
+		}
+
+		var alertIDs []int64
+		for _, match := range matches {
+			alert, err := h.queries.CreateAlert(ctx, db.CreateAlertParams{
+				RuleID:        pgtype.Int8{Int64: rule.ID, Valid: true},
+				UserID:        snapshot.UserID,
+				SnapshotID:    snapshot.ID,
+				ProcessInfoID: match.ProcessInfoID,
+				RuleName:      rule.Name,
+				Severity:      rule.Severity,
+				Message:       match.Message,
+				ProcessID:     match.ProcessID,
+				ProcessName:   match.ProcessName,
+			})
+			if err != nil {
+				log.Warnf("failed to record alert of rule %d on snapshot %d: %v", rule.ID, snapshot.ID, err)
+				continue
+			}
+			alertIDs = append(alertIDs, alert.ID)
+			h.stream.Publish(ctx, stream.EventAlertRaised, snapshot.UserID.Int64, snapshot.AgentID, toAlertResponse(alert))
+		}
+
+		if len(alertIDs) > 0 {
+			h.notifyAlertsRaised(snapshot, rule, alertIDs, matches[0].Message)
+		}
+	}
+}
+
+// notifyAlertsRaised sends one notification per rule and snapshot, however
+// many processes matched
+func (h *WebhookHandler) notifyAlertsRaised(snapshot db.ProcessSnapshot, rule db.AlertRule, alertIDs []int64, firstMessage string) {
+	message := firstMessage
+	if len(alertIDs) > 1 {
+		message = fmt.Sprintf("%s (and %d more)", firstMessage, len(alertIDs)-1)
+	}
+
+	h.notify.Notify(notify.Event{
+		Type:     notify.EventAlertRaised,
+		UserID:   snapshot.UserID.Int64,
+		Severity: rule.Severity,
+		Title:    fmt.Sprintf("Rule %s raised %d alert(s) on snapshot %d", rule.Name, len(alertIDs), snapshot.ID),
+		Message:  message,
+		Data: map[string]any{
+			"ruleId":     rule.ID,
+			"snapshotId": snapshot.ID,
+			"alertIds":   alertIDs,
+		},
+	})
+}
+
+// alertEvaluator evaluates rules against one snapshot. The integrity check
+// runs at most once, however many integrity rules there are.
+type alertEvaluator struct {
+	queries       *db.Queries
+	snapshot      db.ProcessSnapshot
+	processInfoID pgtype.Int8
+
+	integrity *SnapshotIntegrityResponse
+	byAddress map[string]db.ProcessInfo
+}
+
+func (e *alertEvaluator) evaluate(ctx context.Context, rule db.AlertRule) ([]alertMatch, error) {
+	switch rule.Kind {
+	case ruleKindProcess:
+		return e.processMatches(ctx, rule)
+	case ruleKindParent:
+		// A query snapshot rarely holds the parent, so every process would
+		// look orphaned
+		if e.snapshot.SnapshotType != "iteration" {
+			return nil, nil
+		}
+		return e.processMatches(ctx, rule)
+	case ruleKindIntegrity:
+		if e.snapshot.SnapshotType != "iteration" || e.processInfoID.Valid {
+			return nil, nil
+		}
+		return e.integrityMatches(ctx, rule)
+	}
+	return nil, fmt.Errorf("unknown rule kind %q", rule.Kind)
+}
+
+func (e *alertEvaluator) processMatches(ctx context.Context, rule db.AlertRule) ([]alertMatch, error) {
+	now := time.Now()
+	query, err := search.Parse(rule.Query.String, now)
+	if err != nil {
+		return nil, err
+	}
+
+	where := query.Where
+	if rule.Kind == ruleKindParent {
+		parentQuery, err := search.Parse(rule.ParentQuery.String, now)
+		if err != nil {
+			return nil, err
+		}
+		// Unqualified columns in the subquery refer to the parent row
+		where = func(arg func(any) string) string {
+			return query.Where(arg) + " AND NOT EXISTS (SELECT 1 FROM process_info parent" +
+				" WHERE parent.snapshot_id = process_info.snapshot_id" +
+				" AND parent.process_id = process_info.parent_process_id" +
+				" AND " + parentQuery.Where(arg) + ")"
+		}
+	}
+
+	processes, err := e.queries.MatchProcessInfos(ctx, db.MatchProcessInfosParams{
+		SnapshotID:    e.snapshot.ID,
+		ProcessInfoID: e.processInfoID,
+		Where:         where,
+		Limit:         maxAlertsPerRule,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(processes) == maxAlertsPerRule {
+		log.Warnf("alert rule %d matched %d or more processes of snapshot %d, only the first are recorded", rule.ID, maxAlertsPerRule, e.snapshot.ID)
+	}
+
+	matches := make([]alertMatch, len(processes))
+	for i, process := range processes {
+		message := fmt.Sprintf("%s (PID %d) matches %q", process.ProcessName, process.ProcessID, rule.Query.String)
+		if rule.Kind == ruleKindParent {
+			message = fmt.Sprintf("%s (PID %d) has parent PID %d, which does not match %q",
+				process.ProcessName, process.ProcessID, process.ParentProcessID, rule.ParentQuery.String)
+		}
+		matches[i] = processMatch(process, message)
+	}
+	return matches, nil
+}
+
+func (e *alertEvaluator) integrityMatches(ctx context.Context, rule db.AlertRule) ([]alertMatch, error) {
+	if err := e.checkIntegrity(ctx); err != nil {
+		return nil, err
+	}
+
+	wanted := func(issueType string) bool {
+		return !rule.IssueType.Valid || rule.IssueType.String == issueType
+	}
+
+	var matches []alertMatch
+	for _, issue := range e.integrity.Issues {
+		if !wanted(issue.Type) || len(matches) == maxAlertsPerRule {
+			continue
+		}
+		message := describeLinkIssue(issue)
+		if process, ok := e.byAddress[normalizeAddress(issue.Address)]; ok {
+			matches = append(matches, processMatch(process, message))
+			continue
+		}
+		matches = append(matches, alertMatch{
+			ProcessID:   pgtype.Int8{Int64: issue.ProcessID, Valid: true},
+			ProcessName: pgtype.Text{String: issue.ProcessName, Valid: true},
+			Message:     message,
+		})
+	}
+
+	if wanted(issueHiddenProcess) {
+		for _, candidate := range e.integrity.HiddenCandidates {
+			if len(matches) == maxAlertsPerRule {
+				break
+			}
+			// The process is not in the snapshot; the alert points at the
+			// row of the PID lookup that found it
+			matches = append(matches, alertMatch{
+				ProcessInfoID: pgtype.Int8{Int64: candidate.ProcessInfoID, Valid: true},
+				ProcessID:     pgtype.Int8{Int64: candidate.ProcessID, Valid: true},
+				ProcessName:   pgtype.Text{String: candidate.ProcessName, Valid: true},
+				Message:       describeHiddenCandidate(candidate),
+			})
+		}
+	}
+
+	return matches, nil
+}
+
+// checkIntegrity runs the same checks as GET /snapshots/:id/integrity with
+// the default cross-view window
+func (e *alertEvaluator) checkIntegrity(ctx context.Context) error {
+	if e.integrity != nil {
+		return nil
+	}
+
+	processes, err := e.queries.GetProcessInfosBySnapshot(ctx, e.snapshot.ID)
+	if err != nil {
+		return err
+	}
+
+	integrity := checkLinkedList(processes)
+	if e.snapshot.AgentID.Valid {
+		queried, err := e.queries.GetQueriedProcessesNearSnapshot(ctx, db.GetQueriedProcessesNearSnapshotParams{
+			AgentID:  e.snapshot.AgentID,
+			UserID:   e.snapshot.UserID,
+			FromTime: pgtype.Timestamp{Time: e.snapshot.CreatedAt.Time.Add(-defaultCrossViewWindow), Valid: true},
+			ToTime:   pgtype.Timestamp{Time: e.snapshot.CreatedAt.Time.Add(defaultCrossViewWindow), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		integrity.HiddenCandidates = findHiddenCandidates(processes, queried, e.snapshot.CreatedAt.Time)
+	}
+
+	e.byAddress = make(map[string]db.ProcessInfo, len(processes))
+	for _, process := range processes {
+		e.byAddress[normalizeAddress(process.CurrentProcessAddress)] = process
+	}
+	e.integrity = &integrity
+	return nil
+}
+
+func describeLinkIssue(issue LinkIssue) string {
+	subject := fmt.Sprintf("%s (PID %d) at %s", issue.ProcessName, issue.ProcessID, issue.Address)
+	switch issue.Type {
+	case issueBrokenLink:
+		return fmt.Sprintf("%s: %s points at %s, which does not point back", subject, issue.Direction, issue.Target)
+	case issueGap:
+		return fmt.Sprintf("%s: %s points at %s, which is not in the walk", subject, issue.Direction, issue.Target)
+	case issueMissingLink:
+		return fmt.Sprintf("%s: %s is missing", subject, issue.Direction)
+	case issueDuplicateAddress:
+		return fmt.Sprintf("%s: address appears more than once in the walk", subject)
+	}
+	return fmt.Sprintf("%s: %s", subject, issue.Type)
+}
+
+func describeHiddenCandidate(candidate HiddenProcessCandidate) string {
+	message := fmt.Sprintf("%s (PID %d) at %s was returned by PID lookup %d but is missing from the list walk",
+		candidate.ProcessName, candidate.ProcessID, candidate.Address, candidate.QueryID)
+	if candidate.Unlinked {
+		message += "; its neighbours no longer point at it (unlinked)"
+	}
+	return message
+}
+
+func processMatch(process db.ProcessInfo, message string) alertMatch {
+	return alertMatch{
+		ProcessInfoID: pgtype.Int8{Int64: process.ID, Valid: true},
+		ProcessID:     pgtype.Int8{Int64: process.ProcessID, Valid: true},
+		ProcessName:   pgtype.Text{String: process.ProcessName, Valid: true},
+		Message:       message,
+	}
+}
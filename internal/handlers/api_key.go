@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-api/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// API keys look like gapi_<prefix>_<secret>. The prefix is stored in clear
+// to find the key; the whole key is stored as a SHA-256 hash.
+const apiKeyPrefix = "gapi"
+
+var errInvalidAPIKey = errors.New("invalid or expired API key")
+
+// generateAPIKey returns a new key and its lookup prefix
+func generateAPIKey() (key, prefix string, err error) {
+	prefixBytes := make([]byte, 4)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	prefix = hex.EncodeToString(prefixBytes)
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	return apiKeyPrefix + "_" + prefix + "_" + secret, prefix, nil
+}
+
+// apiKeyFromRequest reads the key from X-API-Key or "Authorization: ApiKey"
+func apiKeyFromRequest(c *fiber.Ctx) string {
+	if key := c.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if key, ok := strings.CutPrefix(c.Get("Authorization"), "ApiKey "); ok {
+		return strings.TrimSpace(key)
+	}
+	return ""
+}
+
+// authenticateAPIKey validates key and adds the owner's info to the context,
+// like the JWT middlewares do for tokens
+func authenticateAPIKey(c *fiber.Ctx, queries *db.Queries, key string) error {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || parts[1] == "" || parts[2] == "" {
+		return errInvalidAPIKey
+	}
+
+	stored, err := queries.GetActiveApiKeyByPrefix(c.Context(), parts[1])
+	if err != nil {
+		return errInvalidAPIKey
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(key)), []byte(stored.KeyHash)) != 1 {
+		return errInvalidAPIKey
+	}
+
+	if err := queries.TouchApiKeyLastUsed(c.Context(), stored.ID); err != nil {
+		log.Warnf("auth: failed to update last use of API key %d: %v", stored.ID, err)
+	}
+
+	role := stored.UserRole
+	if !IsValidRole(role) {
+		role = RoleViewer
+	}
+
+	c.Locals("userID", stored.UserID)
+	c.Locals("userName", stored.UserName)
+	c.Locals("userRole", role)
+	c.Locals("apiKeyID", stored.ID)
+	c.Locals("apiKeyScopes", stored.Scopes)
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go-api/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKeyHandler struct {
+	queries *db.Queries
+}
+
+func NewAPIKeyHandler(dbpool *pgxpool.Pool) *APIKeyHandler {
+	return &APIKeyHandler{
+		queries: db.New(dbpool),
+	}
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays *int32   `json:"expires_in_days,omitempty"` // omitted: never expires
+}
+
+type APIKeyResponse struct {
+	ID         int64    `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  *string  `json:"expiresAt,omitempty"`
+	LastUsedAt *string  `json:"lastUsedAt,omitempty"`
+	CreatedAt  string   `json:"createdAt"`
+}
+
+// Get the API keys of the user
+func (h *APIKeyHandler) GetAPIKeys(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	keys, err := h.queries.GetApiKeysByUser(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch API keys",
+		})
+	}
+
+	response := make([]APIKeyResponse, len(keys))
+	for i, key := range keys {
+		response[i] = toAPIKeyResponse(key)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  response,
+		"count": len(response),
+	})
+}
+
+// Create an API key. The key itself is only returned in this response.
+func (h *APIKeyHandler) CreateAPIKey(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+	role := c.Locals("userRole").(string)
+
+	var req CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "name is required",
+		})
+	}
+
+	if len(req.Scopes) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "scopes is required",
+		})
+	}
+
+	for _, scope := range req.Scopes {
+		if !IsValidScope(scope) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Invalid scope %q, use read or capture", scope),
+			})
+		}
+		// A key cannot do more than its owner
+		for perm := range scopePermissions[scope] {
+			if !HasPermission(role, perm) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+					"error": fmt.Sprintf("Your role does not allow the %q scope", scope),
+				})
+			}
+		}
+	}
+
+	var expiresInDays pgtype.Int4
+	if req.ExpiresInDays != nil {
+		if *req.ExpiresInDays <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "expires_in_days must be positive",
+			})
+		}
+		expiresInDays = pgtype.Int4{Int32: *req.ExpiresInDays, Valid: true}
+	}
+
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create API key",
+		})
+	}
+
+	apiKey, err := h.queries.CreateApiKey(c.Context(), db.CreateApiKeyParams{
+		UserID:        userID,
+		Name:          req.Name,
+		Prefix:        prefix,
+		KeyHash:       hashToken(key),
+		Scopes:        req.Scopes,
+		ExpiresInDays: expiresInDays,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create API key",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"data":    toAPIKeyResponse(apiKey),
+		"key":     key,
+		"message": "Store this key now, it will not be shown again",
+	})
+}
+
+// Delete (revoke) an API key of the user
+func (h *APIKeyHandler) DeleteAPIKey(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid API key ID",
+		})
+	}
+
+	apiKey, err := h.queries.GetApiKey(c.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "API key not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch API key",
+		})
+	}
+
+	if apiKey.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	if err := h.queries.DeleteApiKey(c.Context(), id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete API key",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "API key deleted successfully",
+	})
+}
+
+func toAPIKeyResponse(key db.ApiKey) APIKeyResponse {
+	response := APIKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    apiKeyPrefix + "_" + key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+
+	if key.ExpiresAt.Valid {
+		expiresAt := key.ExpiresAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		response.ExpiresAt = &expiresAt
+	}
+
+	if key.LastUsedAt.Valid {
+		lastUsedAt := key.LastUsedAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		response.LastUsedAt = &lastUsedAt
+	}
+
+	return response
+}
@@ -3,17 +3,19 @@ package handlers
 import (
 	"context"
 	"fmt"
-	"os"
 	"time"
 
 	"go-api/internal/db"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type AuthHandler struct {
+	dbpool  *pgxpool.Pool
 	queries *db.Queries
 }
 
@@ -23,6 +25,7 @@ func New(dbpool *pgxpool.Pool) *db.Queries {
 
 func NewAuthHandler(dbpool *pgxpool.Pool) *AuthHandler {
 	return &AuthHandler{
+		dbpool:  dbpool,
 		queries: New(dbpool),
 	}
 }
@@ -32,14 +35,26 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty"`
+	All          bool   `json:"all,omitempty"` // revoga todas as sessões do usuário
+}
+
 type LoginResponse struct {
-	Token string       `json:"token"`
-	User  UserResponse `json:"user"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refreshToken"`
+	ExpiresIn    int64        `json:"expiresIn"` // segundos até o token de acesso expirar
+	User         UserResponse `json:"user"`
 }
 
 type Claims struct {
 	UserID int64  `json:"user_id"`
 	Name   string `json:"name"`
+	Role   string `json:"role"`
 	jwt.RegisteredClaims
 }
 
@@ -53,71 +68,261 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 
 	user, err := h.queries.GetUserByName(context.Background(), req.Name)
 	if err != nil {
+		// Mesmo custo de um login com usuário existente
+		verifyPassword(req.Password, dummyPasswordHash)
 		return c.Status(401).JSON(fiber.Map{
 			"error": "Credenciais inválidas",
 		})
 	}
 
-	hashedPassword := hashPassword(req.Password)
-	if user.Password != hashedPassword {
+	ok, needsRehash, err := verifyPassword(req.Password, user.Password)
+	if err != nil || !ok {
 		return c.Status(401).JSON(fiber.Map{
 			"error": "Credenciais inválidas",
 		})
 	}
 
-	token, err := generateJWT(user.ID, user.Name)
+	// Senhas em SHA-512 ou com parâmetros antigos são migradas no login
+	if needsRehash {
+		h.rehashPassword(c.Context(), user, req.Password)
+	}
+
+	// Cada login inicia uma nova família de refresh tokens
+	familyID, err := randomToken(16)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Falha ao gerar o token",
+		})
+	}
+
+	response, err := issueTokens(c.Context(), h.queries, user, familyID)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Falha ao gerar o token",
 		})
 	}
 
-	return c.JSON(LoginResponse{
-		Token: token,
-		User:  toUserResponse(user),
+	return c.JSON(response)
+}
+
+// Refresh troca um refresh token por um novo par de tokens. O refresh token
+// usado é invalidado; apresentá-lo de novo revoga toda a família, já que
+// indica que ele foi copiado.
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "refresh_token é obrigatório",
+		})
+	}
+
+	tokenHash := hashToken(req.RefreshToken)
+
+	tx, err := h.dbpool.Begin(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Falha ao gerar o token",
+		})
+	}
+	defer tx.Rollback(c.Context())
+
+	qtx := h.queries.WithTx(tx)
+
+	stored, err := qtx.ConsumeRefreshToken(c.Context(), tokenHash)
+	if err != nil {
+		if err != pgx.ErrNoRows {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Falha ao gerar o token",
+			})
+		}
+
+		h.detectRefreshTokenReuse(c.Context(), tokenHash)
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Refresh token inválido ou expirado",
+		})
+	}
+
+	// Recarrega o usuário para que mudanças de role valham a partir daqui
+	user, err := qtx.GetUser(c.Context(), stored.UserID)
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Refresh token inválido ou expirado",
+		})
+	}
+
+	response, err := issueTokens(c.Context(), qtx, user, stored.FamilyID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Falha ao gerar o token",
+		})
+	}
+
+	if err := tx.Commit(c.Context()); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Falha ao gerar o token",
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// detectRefreshTokenReuse revoga a família de um refresh token já usado ou
+// revogado. Tokens desconhecidos ou apenas expirados são ignorados.
+func (h *AuthHandler) detectRefreshTokenReuse(ctx context.Context, tokenHash string) {
+	stored, err := h.queries.GetRefreshTokenByHash(ctx, tokenHash)
+	if err != nil || !stored.RevokedAt.Valid {
+		return
+	}
+
+	log.Warnf("auth: refresh token reuse for user %d, revoking token family", stored.UserID)
+	if err := h.queries.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+		log.Errorf("auth: failed to revoke refresh token family: %v", err)
+	}
+}
+
+// Logout revoga o token de acesso usado na requisição e, se informado, o
+// refresh token da sessão (ou todas as sessões com "all": true)
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	claims := c.Locals("tokenClaims").(*Claims)
+
+	var req LogoutRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Corpo da requisição inválido",
+			})
+		}
+	}
+
+	err := h.queries.RevokeAccessToken(c.Context(), db.RevokeAccessTokenParams{
+		Jti:        claims.ID,
+		UserID:     claims.UserID,
+		TtlSeconds: ttlSeconds(time.Until(claims.ExpiresAt.Time)),
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Falha ao encerrar a sessão",
+		})
+	}
+
+	if req.All {
+		err = h.queries.RevokeUserRefreshTokens(c.Context(), claims.UserID)
+	} else if req.RefreshToken != "" {
+		stored, lookupErr := h.queries.GetRefreshTokenByHash(c.Context(), hashToken(req.RefreshToken))
+		if lookupErr == nil && stored.UserID == claims.UserID {
+			err = h.queries.RevokeRefreshTokenFamily(c.Context(), stored.FamilyID)
+		}
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Falha ao encerrar a sessão",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Sessão encerrada com sucesso",
 	})
 }
 
-func generateJWT(userID int64, name string) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your-secret-key"
+// issueTokens gera um token de acesso e um refresh token na família informada
+func issueTokens(ctx context.Context, queries *db.Queries, user db.User, familyID string) (LoginResponse, error) {
+	token, err := generateJWT(user.ID, user.Name, user.Role)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	refreshToken, err := issueRefreshToken(ctx, queries, user.ID, familyID)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	return LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(tokenConfig.AccessTokenTTL / time.Second),
+		User:         toUserResponse(user),
+	}, nil
+}
+
+// rehashPassword regrava a senha com o hash atual. Falhas não impedem o
+// login, a migração é tentada de novo no próximo.
+func (h *AuthHandler) rehashPassword(ctx context.Context, user db.User, password string) {
+	hashed, err := hashPassword(password)
+	if err == nil {
+		_, err = h.queries.UpdateUser(ctx, db.UpdateUserParams{
+			Name:     user.Name,
+			Password: hashed,
+			ID:       user.ID,
+		})
 	}
+	if err != nil {
+		log.Warnf("falha ao migrar hash de senha do usuário %d: %v", user.ID, err)
+	}
+}
 
+func generateJWT(userID int64, name, role string) (string, error) {
+	// jti identifica o token na lista de revogação
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := Claims{
 		UserID: userID,
 		Name:   name,
+		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 24 hours
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(tokenConfig.AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
+	if tokenConfig.Keys != nil {
+		key := tokenConfig.Keys.SigningKey()
+		token := jwt.NewWithClaims(key.Method, claims)
+		token.Header["kid"] = key.ID
+		return token.SignedString(key.Private)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return token.SignedString([]byte(tokenConfig.Secret))
 }
 
 func verifyJWT(tokenString string) (*Claims, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "your-secret-key"
-	}
-
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(secret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, verificationKey, jwt.WithExpirationRequired())
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// Tokens sem jti (anteriores à revogação) não podem ser revogados
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.ID != "" {
 		return claims, nil
 	}
 
 	return nil, fmt.Errorf("token inválido")
 }
+
+// verificationKey escolhe a chave do token. Com chaves assimétricas, HS256 não
+// é aceito e o "kid" precisa ser de uma chave conhecida com o mesmo algoritmo.
+func verificationKey(token *jwt.Token) (any, error) {
+	if tokenConfig.Keys == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(tokenConfig.Secret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := tokenConfig.Keys.Lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.Public, nil
+}
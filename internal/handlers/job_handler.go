@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"strconv"
+
+	"go-api/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type JobHandler struct {
+	queries *db.Queries
+}
+
+func NewJobHandler(dbpool *pgxpool.Pool) *JobHandler {
+	return &JobHandler{
+		queries: db.New(dbpool),
+	}
+}
+
+type JobResponse struct {
+	ID           int64   `json:"id"`
+	AgentID      int64   `json:"agentId"`
+	Status       string  `json:"status"`
+	SnapshotID   *int64  `json:"snapshotId,omitempty"`
+	ErrorMessage *string `json:"errorMessage,omitempty"`
+	CreatedAt    string  `json:"createdAt"`
+	StartedAt    *string `json:"startedAt,omitempty"`
+	FinishedAt   *string `json:"finishedAt,omitempty"`
+}
+
+// Get the status of a capture job
+func (h *JobHandler) GetJob(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid job ID",
+		})
+	}
+
+	job, err := h.queries.GetCaptureJob(c.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Job not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch job",
+		})
+	}
+
+	if job.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Access denied",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data": toJobResponse(job),
+	})
+}
+
+func toJobResponse(job db.CaptureJob) JobResponse {
+	response := JobResponse{
+		ID:        job.ID,
+		AgentID:   job.AgentID,
+		Status:    job.Status,
+		CreatedAt: job.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+
+	if job.SnapshotID.Valid {
+		response.SnapshotID = &job.SnapshotID.Int64
+	}
+
+	if job.ErrorMessage.Valid {
+		response.ErrorMessage = &job.ErrorMessage.String
+	}
+
+	if job.StartedAt.Valid {
+		startedAt := job.StartedAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		response.StartedAt = &startedAt
+	}
+
+	if job.FinishedAt.Valid {
+		finishedAt := job.FinishedAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		response.FinishedAt = &finishedAt
+	}
+
+	return response
+}
@@ -3,12 +3,27 @@ package handlers
 import (
 	"strings"
 
+	"go-api/internal/db"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// JWTMiddleware valida JWT tokens e adiciona user info no context
-func JWTMiddleware() fiber.Handler {
+// JWTMiddleware valida JWT tokens ou API keys, rejeita tokens revogados e
+// adiciona user info no context
+func JWTMiddleware(dbpool *pgxpool.Pool) fiber.Handler {
+	queries := db.New(dbpool)
+
 	return func(c *fiber.Ctx) error {
+		if key := apiKeyFromRequest(c); key != "" {
+			if err := authenticateAPIKey(c, queries, key); err != nil {
+				return c.Status(401).JSON(fiber.Map{
+					"error": "Invalid or expired API key",
+				})
+			}
+			return c.Next()
+		}
+
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
 			return c.Status(401).JSON(fiber.Map{
@@ -36,8 +51,22 @@ func JWTMiddleware() fiber.Handler {
 			})
 		}
 
+		revoked, err := queries.IsAccessTokenRevoked(c.Context(), claims.ID)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to validate token",
+			})
+		}
+		if revoked {
+			return c.Status(401).JSON(fiber.Map{
+				"error": "Token has been revoked",
+			})
+		}
+
 		c.Locals("userID", claims.UserID)
 		c.Locals("userName", claims.Name)
+		c.Locals("userRole", claimsRole(claims))
+		c.Locals("tokenClaims", claims)
 
 		return c.Next()
 	}
@@ -45,8 +74,16 @@ func JWTMiddleware() fiber.Handler {
 
 // OptionalJWTMiddleware tenta validar JWT tokens se presente, mas não falha se ausente
 // Útil para endpoints que podem funcionar com ou sem autenticação
-func OptionalJWTMiddleware() fiber.Handler {
+func OptionalJWTMiddleware(dbpool *pgxpool.Pool) fiber.Handler {
+	queries := db.New(dbpool)
+
 	return func(c *fiber.Ctx) error {
+		// API key inválida também continua sem autenticação
+		if key := apiKeyFromRequest(c); key != "" {
+			authenticateAPIKey(c, queries, key)
+			return c.Next()
+		}
+
 		authHeader := c.Get("Authorization")
 
 		// Se não há header de autorização, continua sem autenticação
@@ -71,14 +108,44 @@ func OptionalJWTMiddleware() fiber.Handler {
 			return c.Next()
 		}
 
+		// Token revogado é tratado como ausente
+		if revoked, err := queries.IsAccessTokenRevoked(c.Context(), claims.ID); err != nil || revoked {
+			return c.Next()
+		}
+
 		// Token válido - adiciona informações do usuário ao contexto
 		c.Locals("userID", claims.UserID)
 		c.Locals("userName", claims.Name)
+		c.Locals("userRole", claimsRole(claims))
+		c.Locals("tokenClaims", claims)
+
+		return c.Next()
+	}
+}
 
+// RejectAPIKeys bloqueia requisições autenticadas com API key. Usado em
+// rotas que exigem uma sessão de usuário, como gerenciamento de contas e de
+// API keys.
+func RejectAPIKeys() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals("apiKeyID") != nil {
+			return c.Status(403).JSON(fiber.Map{
+				"error": "API keys cannot be used for this endpoint",
+			})
+		}
 		return c.Next()
 	}
 }
 
+// claimsRole returns the role carried by the token. Tokens issued before
+// roles existed have none and get the least privileged role.
+func claimsRole(claims *Claims) string {
+	if IsValidRole(claims.Role) {
+		return claims.Role
+	}
+	return RoleViewer
+}
+
 // GetUserFromContext extrai informações do usuário do fiber context
 func GetUserFromContext(c *fiber.Ctx) (userID int64, name string, ok bool) {
 	userIDInterface := c.Locals("userID")
@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-api/internal/agentclient"
+	"go-api/internal/db"
+	"go-api/internal/notify"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type NotificationHandler struct {
+	queries  *db.Queries
+	notifier *notify.Notifier
+}
+
+func NewNotificationHandler(dbpool *pgxpool.Pool, notifier *notify.Notifier) *NotificationHandler {
+	return &NotificationHandler{
+		queries:  db.New(dbpool),
+		notifier: notifier,
+	}
+}
+
+type NotificationChannelRequest struct {
+	Name        string        `json:"name"`
+	Kind        string        `json:"kind"`
+	Config      notify.Config `json:"config"`
+	Events      []string      `json:"events"`
+	MinSeverity string        `json:"min_severity"`
+	Enabled     *bool         `json:"enabled,omitempty"`
+}
+
+// NotificationConfigResponse is the channel config without the webhook
+// secret, which is only returned when generated
+type NotificationConfigResponse struct {
+	URL       string   `json:"url,omitempty"`
+	HasSecret bool     `json:"hasSecret,omitempty"`
+	To        []string `json:"to,omitempty"`
+	Network   string   `json:"network,omitempty"`
+	Address   string   `json:"address,omitempty"`
+}
+
+type NotificationChannelResponse struct {
+	ID            int64                      `json:"id"`
+	UserID        int64                      `json:"userId"`
+	Name          string                     `json:"name"`
+	Kind          string                     `json:"kind"`
+	Config        NotificationConfigResponse `json:"config"`
+	Events        []string                   `json:"events"`
+	MinSeverity   string                     `json:"minSeverity"`
+	Enabled       bool                       `json:"enabled"`
+	LastAttemptAt *string                    `json:"lastAttemptAt,omitempty"`
+	LastError     *string                    `json:"lastError,omitempty"` // unset when the last attempt succeeded
+	CreatedAt     string                     `json:"createdAt"`
+	UpdatedAt     string                     `json:"updatedAt"`
+}
+
+// Get all notification channels of the user
+func (h *NotificationHandler) GetChannels(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	channels, err := h.queries.GetNotificationChannelsByUser(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch notification channels",
+		})
+	}
+
+	response := make([]NotificationChannelResponse, len(channels))
+	for i, channel := range channels {
+		response[i] = toNotificationChannelResponse(channel)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  response,
+		"count": len(response),
+	})
+}
+
+// Get a specific notification channel by ID
+func (h *NotificationHandler) GetChannel(c *fiber.Ctx) error {
+	channel, err := h.fetchOwnedChannel(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": toNotificationChannelResponse(channel),
+	})
+}
+
+// Create a notification channel. Webhook channels without a secret get a
+// generated one, returned only in this response.
+func (h *NotificationHandler) CreateChannel(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	var req NotificationChannelRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var generatedSecret string
+	if req.Kind == notify.KindWebhook && req.Config.Secret == "" {
+		secret, err := agentclient.GenerateSecret()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to generate signing secret",
+			})
+		}
+		req.Config.Secret = secret
+		generatedSecret = secret
+	}
+
+	params, err := h.toChannelParams(req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	created, err := h.queries.CreateNotificationChannel(c.Context(), db.CreateNotificationChannelParams{
+		UserID:      userID,
+		Name:        params.Name,
+		Kind:        params.Kind,
+		Config:      params.Config,
+		Events:      params.Events,
+		MinSeverity: params.MinSeverity,
+		Enabled:     params.Enabled,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create notification channel",
+		})
+	}
+
+	response := fiber.Map{
+		"data":    toNotificationChannelResponse(created),
+		"message": "Notification channel created successfully",
+	}
+	if generatedSecret != "" {
+		response["secret"] = generatedSecret
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(response)
+}
+
+// Replace the definition of a notification channel. A webhook channel
+// updated without a secret keeps its current one.
+func (h *NotificationHandler) UpdateChannel(c *fiber.Ctx) error {
+	current, err := h.fetchOwnedChannel(c)
+	if err != nil {
+		return err
+	}
+
+	var req NotificationChannelRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Kind == notify.KindWebhook && req.Config.Secret == "" && current.Kind == notify.KindWebhook {
+		var currentConfig notify.Config
+		if err := json.Unmarshal(current.Config, &currentConfig); err == nil {
+			req.Config.Secret = currentConfig.Secret
+		}
+	}
+
+	params, err := h.toChannelParams(req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	params.ID = current.ID
+
+	updated, err := h.queries.UpdateNotificationChannel(c.Context(), params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update notification channel",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":    toNotificationChannelResponse(updated),
+		"message": "Notification channel updated successfully",
+	})
+}
+
+// Delete a notification channel
+func (h *NotificationHandler) DeleteChannel(c *fiber.Ctx) error {
+	current, err := h.fetchOwnedChannel(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.queries.DeleteNotificationChannel(c.Context(), current.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete notification channel",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Notification channel deleted successfully",
+	})
+}
+
+// Send a test event to the channel and wait for the outcome. Disabled
+// channels and their subscriptions are ignored.
+func (h *NotificationHandler) TestChannel(c *fiber.Ctx) error {
+	channel, err := h.fetchOwnedChannel(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.notifier.Send(c.Context(), channel, notify.Event{
+		Type:     notify.EventTest,
+		UserID:   channel.UserID,
+		Severity: "low",
+		Title:    "Test notification",
+		Message:  fmt.Sprintf("Test notification for channel %s", channel.Name),
+		Data: map[string]any{
+			"channelId": channel.ID,
+		},
+	})
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to deliver test notification: %v", err),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Test notification delivered successfully",
+	})
+}
+
+// fetchOwnedChannel loads the notification channel in the :id param and
+// checks that it belongs to the authenticated user
+func (h *NotificationHandler) fetchOwnedChannel(c *fiber.Ctx) (db.NotificationChannel, error) {
+	userID := c.Locals("userID").(int64)
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return db.NotificationChannel{}, fiber.NewError(fiber.StatusBadRequest, "Invalid notification channel ID")
+	}
+
+	channel, err := h.queries.GetNotificationChannel(c.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return db.NotificationChannel{}, fiber.NewError(fiber.StatusNotFound, "Notification channel not found")
+		}
+		return db.NotificationChannel{}, fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch notification channel")
+	}
+
+	if channel.UserID != userID {
+		return db.NotificationChannel{}, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return channel, nil
+}
+
+// toChannelParams validates the request and fills in the defaults: channels
+// are enabled and receive every severity unless the request says otherwise
+func (h *NotificationHandler) toChannelParams(req NotificationChannelRequest) (db.UpdateNotificationChannelParams, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return db.UpdateNotificationChannelParams{}, fmt.Errorf("name is required")
+	}
+
+	if err := h.notifier.Validate(req.Kind, req.Config); err != nil {
+		return db.UpdateNotificationChannelParams{}, err
+	}
+	if req.Kind != notify.KindWebhook && req.Config.Secret != "" {
+		return db.UpdateNotificationChannelParams{}, fmt.Errorf("secret is only used by webhook channels")
+	}
+
+	if len(req.Events) == 0 {
+		return db.UpdateNotificationChannelParams{}, fmt.Errorf("events is required")
+	}
+	for _, event := range req.Events {
+		if !slices.Contains(notify.EventTypes, event) {
+			return db.UpdateNotificationChannelParams{}, fmt.Errorf("events must be any of %s", strings.Join(notify.EventTypes, ", "))
+		}
+	}
+
+	minSeverity := req.MinSeverity
+	if minSeverity == "" {
+		minSeverity = "low"
+	}
+	if notify.SeverityRank(minSeverity) < 0 {
+		return db.UpdateNotificationChannelParams{}, fmt.Errorf("min_severity must be one of low, medium, high or critical")
+	}
+
+	config, err := json.Marshal(req.Config)
+	if err != nil {
+		return db.UpdateNotificationChannelParams{}, fmt.Errorf("invalid config")
+	}
+
+	slices.Sort(req.Events)
+	return db.UpdateNotificationChannelParams{
+		Name:        req.Name,
+		Kind:        req.Kind,
+		Config:      config,
+		Events:      slices.Compact(req.Events),
+		MinSeverity: minSeverity,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+	}, nil
+}
+
+func toNotificationChannelResponse(channel db.NotificationChannel) NotificationChannelResponse {
+	var config notify.Config
+	json.Unmarshal(channel.Config, &config)
+
+	response := NotificationChannelResponse{
+		ID:     channel.ID,
+		UserID: channel.UserID,
+		Name:   channel.Name,
+		Kind:   channel.Kind,
+		Config: NotificationConfigResponse{
+			URL:       config.URL,
+			HasSecret: config.Secret != "",
+			To:        config.To,
+			Network:   config.Network,
+			Address:   config.Address,
+		},
+		Events:      channel.Events,
+		MinSeverity: channel.MinSeverity,
+		Enabled:     channel.Enabled,
+		CreatedAt:   channel.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:   channel.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+
+	if channel.LastAttemptAt.Valid {
+		lastAttemptAt := channel.LastAttemptAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		response.LastAttemptAt = &lastAttemptAt
+	}
+
+	if channel.LastError.Valid {
+		response.LastError = &channel.LastError.String
+	}
+
+	return response
+}
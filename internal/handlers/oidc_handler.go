@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go-api/internal/db"
+	"go-api/internal/oidcauth"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Cookie que guarda state, nonce e PKCE verifier entre o redirecionamento e
+// o callback
+const (
+	oidcFlowCookie    = "oidc_flow"
+	oidcFlowCookieTTL = 10 * time.Minute
+)
+
+// Usuários OIDC não têm senha local; este valor nunca confere no login
+const oidcUnusablePassword = "!oidc"
+
+// Roles da mais para a menos privilegiada, para escolher entre grupos
+var rolesByPrivilege = []string{RoleAdmin, RoleAnalyst, RoleViewer}
+
+type OIDCOptions struct {
+	RoleMapping  map[string]string // grupo do IdP -> role
+	DefaultRole  string            // role de quem não está em grupo mapeado; vazio recusa o login
+	SecureCookie bool
+}
+
+type OIDCHandler struct {
+	queries  *db.Queries
+	provider *oidcauth.Provider
+	options  OIDCOptions
+}
+
+func NewOIDCHandler(dbpool *pgxpool.Pool, provider *oidcauth.Provider, options OIDCOptions) (*OIDCHandler, error) {
+	for group, role := range options.RoleMapping {
+		if !IsValidRole(role) {
+			return nil, fmt.Errorf("invalid role %q for OIDC group %q", role, group)
+		}
+	}
+	if options.DefaultRole != "" && !IsValidRole(options.DefaultRole) {
+		return nil, fmt.Errorf("invalid OIDC default role %q", options.DefaultRole)
+	}
+
+	return &OIDCHandler{
+		queries:  db.New(dbpool),
+		provider: provider,
+		options:  options,
+	}, nil
+}
+
+// Login redireciona para o provedor OIDC
+func (h *OIDCHandler) Login(c *fiber.Ctx) error {
+	url, flow, err := h.provider.Begin(c.Context())
+	if err != nil {
+		log.Errorf("oidc: failed to start login: %v", err)
+		return c.Status(502).JSON(fiber.Map{
+			"error": "Provedor OIDC indisponível",
+		})
+	}
+
+	encoded, err := json.Marshal(flow)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Falha ao iniciar o login",
+		})
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(encoded),
+		Path:     "/api/v1/auth/oidc",
+		Expires:  time.Now().Add(oidcFlowCookieTTL),
+		HTTPOnly: true,
+		Secure:   h.options.SecureCookie,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+
+	return c.Redirect(url, fiber.StatusFound)
+}
+
+// Callback conclui o login: valida o ID token, cria ou atualiza o usuário
+// vinculado ao subject e emite os mesmos tokens do login por senha
+func (h *OIDCHandler) Callback(c *fiber.Ctx) error {
+	if idpError := c.Query("error"); idpError != "" {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Login recusado pelo provedor OIDC: " + idpError,
+		})
+	}
+
+	flow, ok := h.readFlow(c)
+	c.ClearCookie(oidcFlowCookie)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Sessão de login OIDC ausente ou expirada",
+		})
+	}
+
+	identity, err := h.provider.Finish(c.Context(), c.Query("code"), c.Query("state"), flow)
+	if err != nil {
+		log.Warnf("oidc: login rejected: %v", err)
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Login OIDC inválido",
+		})
+	}
+
+	role := h.roleForGroups(identity.Groups)
+	if role == "" {
+		return c.Status(403).JSON(fiber.Map{
+			"error": "Nenhum grupo do usuário dá acesso a esta API",
+		})
+	}
+
+	user, err := h.linkUser(c, identity, role)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Erro ao vincular usuário",
+		})
+	}
+
+	familyID, err := randomToken(16)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Falha ao gerar o token",
+		})
+	}
+
+	response, err := issueTokens(c.Context(), h.queries, user, familyID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Falha ao gerar o token",
+		})
+	}
+
+	return c.JSON(response)
+}
+
+func (h *OIDCHandler) readFlow(c *fiber.Ctx) (oidcauth.Flow, bool) {
+	var flow oidcauth.Flow
+
+	decoded, err := base64.RawURLEncoding.DecodeString(c.Cookies(oidcFlowCookie))
+	if err != nil || len(decoded) == 0 {
+		return flow, false
+	}
+	if err := json.Unmarshal(decoded, &flow); err != nil {
+		return flow, false
+	}
+	return flow, true
+}
+
+// roleForGroups retorna a role mais privilegiada entre os grupos do usuário
+func (h *OIDCHandler) roleForGroups(groups []string) string {
+	mapped := make(map[string]bool)
+	for _, group := range groups {
+		if role, ok := h.options.RoleMapping[group]; ok {
+			mapped[role] = true
+		}
+	}
+
+	for _, role := range rolesByPrivilege {
+		if mapped[role] {
+			return role
+		}
+	}
+	return h.options.DefaultRole
+}
+
+// linkUser busca o usuário pelo issuer e subject, criando-o no primeiro
+// login. A role segue os grupos do IdP a cada login.
+func (h *OIDCHandler) linkUser(c *fiber.Ctx, identity oidcauth.Identity, role string) (db.User, error) {
+	issuer := pgtype.Text{String: identity.Issuer, Valid: true}
+	subject := pgtype.Text{String: identity.Subject, Valid: true}
+
+	user, err := h.queries.GetUserByOIDCSubject(c.Context(), db.GetUserByOIDCSubjectParams{
+		OidcIssuer:  issuer,
+		OidcSubject: subject,
+	})
+	if err == nil {
+		if user.Role == role {
+			return user, nil
+		}
+		return h.queries.UpdateUserRole(c.Context(), db.UpdateUserRoleParams{
+			Role: role,
+			ID:   user.ID,
+		})
+	}
+	if err != pgx.ErrNoRows {
+		return db.User{}, err
+	}
+
+	name, err := h.availableName(c, identity)
+	if err != nil {
+		return db.User{}, err
+	}
+
+	return h.queries.CreateOIDCUser(c.Context(), db.CreateOIDCUserParams{
+		Name:        name,
+		Password:    oidcUnusablePassword,
+		Role:        role,
+		OidcIssuer:  issuer,
+		OidcSubject: subject,
+	})
+}
+
+// availableName escolhe o nome do novo usuário: preferred_username, e-mail ou
+// o subject, o primeiro que não pertença a outro usuário
+func (h *OIDCHandler) availableName(c *fiber.Ctx, identity oidcauth.Identity) (string, error) {
+	for _, name := range []string{identity.PreferredUsername, identity.Email} {
+		if name == "" || len(name) > 255 {
+			continue
+		}
+
+		_, err := h.queries.GetUserByName(c.Context(), name)
+		if err == pgx.ErrNoRows {
+			return name, nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return "oidc:" + identity.Subject, nil
+}
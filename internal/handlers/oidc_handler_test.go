@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-api/internal/db"
+	"go-api/internal/oidcauth"
+	"go-api/internal/oidcauth/oidctest"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// userStore stands in for the users and refresh_tokens tables, answering
+// the queries an OIDC login runs
+type userStore struct {
+	mu            sync.Mutex
+	users         []db.User
+	refreshTokens int
+}
+
+func (s *userStore) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	find := func(match func(db.User) bool) pgx.Row {
+		for _, user := range s.users {
+			if match(user) {
+				return userRow(user)
+			}
+		}
+		return errRow{pgx.ErrNoRows}
+	}
+
+	switch {
+	case strings.HasPrefix(sql, "-- name: GetUserByOIDCSubject "):
+		issuer, subject := args[0].(pgtype.Text), args[1].(pgtype.Text)
+		return find(func(u db.User) bool { return u.OidcIssuer == issuer && u.OidcSubject == subject })
+	case strings.HasPrefix(sql, "-- name: GetUserByName "):
+		return find(func(u db.User) bool { return u.Name == args[0].(string) })
+	case strings.HasPrefix(sql, "-- name: CreateOIDCUser "):
+		user := db.User{
+			ID:          int64(len(s.users) + 1),
+			Name:        args[0].(string),
+			Password:    args[1].(string),
+			Role:        args[2].(string),
+			OidcIssuer:  args[3].(pgtype.Text),
+			OidcSubject: args[4].(pgtype.Text),
+		}
+		s.users = append(s.users, user)
+		return userRow(user)
+	case strings.HasPrefix(sql, "-- name: UpdateUserRole "):
+		for i := range s.users {
+			if s.users[i].ID == args[1].(int64) {
+				s.users[i].Role = args[0].(string)
+				return userRow(s.users[i])
+			}
+		}
+		return errRow{pgx.ErrNoRows}
+	case strings.HasPrefix(sql, "-- name: CreateRefreshToken "):
+		s.refreshTokens++
+		return valuesRow{}
+	}
+	return errRow{errors.New("unexpected query: " + strings.SplitN(sql, "\n", 2)[0])}
+}
+
+func (s *userStore) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errors.New("unexpected Exec")
+}
+
+func (s *userStore) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return nil, errors.New("unexpected Query")
+}
+
+func (s *userStore) CopyFrom(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error) {
+	return 0, errors.New("unexpected CopyFrom")
+}
+
+// valuesRow scans its values, in order, into the destinations; extra
+// destinations are left untouched
+type valuesRow []any
+
+func (r valuesRow) Scan(dest ...any) error {
+	for i, value := range r {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+func userRow(u db.User) valuesRow {
+	return valuesRow{u.ID, u.Name, u.Password, u.CreatedAt, u.UpdatedAt, u.Role, u.OidcIssuer, u.OidcSubject}
+}
+
+type errRow struct{ err error }
+
+func (r errRow) Scan(...any) error { return r.err }
+
+type oidcTestEnv struct {
+	stub  *oidctest.Provider
+	store *userStore
+	app   *fiber.App
+}
+
+func newOIDCTestEnv(t *testing.T, options OIDCOptions) *oidcTestEnv {
+	t.Helper()
+
+	stub, err := oidctest.New()
+	if err != nil {
+		t.Fatalf("failed to start stub provider: %v", err)
+	}
+	t.Cleanup(stub.Close)
+
+	previous := tokenConfig
+	ConfigureTokens(TokenConfig{Secret: "test-secret", AccessTokenTTL: previous.AccessTokenTTL, RefreshTokenTTL: previous.RefreshTokenTTL})
+	t.Cleanup(func() { ConfigureTokens(previous) })
+
+	store := &userStore{}
+	handler := &OIDCHandler{
+		queries: db.New(store),
+		provider: oidcauth.New(oidcauth.Options{
+			IssuerURL:    stub.Issuer,
+			ClientID:     stub.ClientID,
+			ClientSecret: stub.ClientSecret,
+			RedirectURL:  "http://localhost:3000/api/v1/auth/oidc/callback",
+		}),
+		options: options,
+	}
+
+	app := fiber.New()
+	app.Get("/api/v1/auth/oidc/login", handler.Login)
+	app.Get("/api/v1/auth/oidc/callback", handler.Callback)
+
+	return &oidcTestEnv{stub: stub, store: store, app: app}
+}
+
+// login runs the whole flow as a browser would and returns the callback
+// response. forgeState replaces the state the provider sends back.
+func (e *oidcTestEnv) login(t *testing.T, login oidctest.Login, forgeState bool) *http.Response {
+	t.Helper()
+	e.stub.SetLogin(login)
+
+	resp, err := e.app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/login", nil))
+	if err != nil {
+		t.Fatalf("login request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("login status = %d, want 302", resp.StatusCode)
+	}
+
+	var flowCookie *http.Cookie
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == oidcFlowCookie {
+			flowCookie = cookie
+		}
+	}
+	if flowCookie == nil || !flowCookie.HttpOnly {
+		t.Fatalf("login did not set an HttpOnly %s cookie", oidcFlowCookie)
+	}
+
+	code, state, err := e.stub.Authorize(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if forgeState {
+		state = "forged"
+	}
+
+	callback := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?"+url.Values{
+		"code":  {code},
+		"state": {state},
+	}.Encode(), nil)
+	callback.AddCookie(flowCookie)
+
+	resp, err = e.app.Test(callback)
+	if err != nil {
+		t.Fatalf("callback request: %v", err)
+	}
+	return resp
+}
+
+func TestOIDCCallback(t *testing.T) {
+	env := newOIDCTestEnv(t, OIDCOptions{
+		RoleMapping: map[string]string{"sec-admins": RoleAdmin, "sec-analysts": RoleAnalyst},
+	})
+
+	resp := env.login(t, oidctest.Login{Subject: "user-1", PreferredUsername: "alice", Groups: []string{"sec-analysts", "everyone"}}, false)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("callback status = %d, want 200", resp.StatusCode)
+	}
+
+	var body LoginResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode login response: %v", err)
+	}
+	if body.Token == "" || body.RefreshToken == "" {
+		t.Error("login response lacks tokens")
+	}
+	if body.User.Name != "alice" || body.User.Role != RoleAnalyst {
+		t.Errorf("user = %s/%s, want alice/%s", body.User.Name, body.User.Role, RoleAnalyst)
+	}
+	claims, err := verifyJWT(body.Token)
+	if err != nil || claims.UserID != body.User.ID || claims.Role != RoleAnalyst {
+		t.Errorf("access token claims = %+v, %v", claims, err)
+	}
+
+	created := env.store.users[0]
+	if created.OidcIssuer.String != env.stub.Issuer || created.OidcSubject.String != "user-1" || created.Password != oidcUnusablePassword {
+		t.Errorf("created user = %+v", created)
+	}
+
+	// The next login is linked by subject, not by name, and the role
+	// follows the groups
+	resp = env.login(t, oidctest.Login{Subject: "user-1", PreferredUsername: "renamed", Groups: []string{"sec-admins"}}, false)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("second callback status = %d, want 200", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode login response: %v", err)
+	}
+	if len(env.store.users) != 1 || body.User.ID != created.ID || body.User.Role != RoleAdmin {
+		t.Errorf("second login: %d users, user %+v", len(env.store.users), body.User)
+	}
+
+	// Another subject whose preferred username is taken falls back to the
+	// e-mail
+	resp = env.login(t, oidctest.Login{Subject: "user-2", PreferredUsername: "alice", Email: "alice2@example.com", Groups: []string{"sec-analysts"}}, false)
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("third callback status = %d, want 200", resp.StatusCode)
+	}
+	if len(env.store.users) != 2 || env.store.users[1].Name != "alice2@example.com" {
+		t.Errorf("users = %+v", env.store.users)
+	}
+	if env.store.refreshTokens != 3 {
+		t.Errorf("refresh tokens issued = %d, want 3", env.store.refreshTokens)
+	}
+}
+
+func TestOIDCCallbackRejects(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    OIDCOptions
+		login      oidctest.Login
+		forgeState bool
+		wantStatus int
+	}{
+		{
+			name:       "state mismatch",
+			options:    OIDCOptions{DefaultRole: RoleViewer},
+			login:      oidctest.Login{Subject: "user-1"},
+			forgeState: true,
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:       "nonce mismatch",
+			options:    OIDCOptions{DefaultRole: RoleViewer},
+			login:      oidctest.Login{Subject: "user-1", Nonce: "replayed"},
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:       "no mapped group",
+			options:    OIDCOptions{RoleMapping: map[string]string{"sec-admins": RoleAdmin}},
+			login:      oidctest.Login{Subject: "user-1", Groups: []string{"everyone"}},
+			wantStatus: fiber.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newOIDCTestEnv(t, tt.options)
+
+			resp := env.login(t, tt.login, tt.forgeState)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("callback status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if len(env.store.users) != 0 || env.store.refreshTokens != 0 {
+				t.Errorf("rejected login created %d users and %d refresh tokens", len(env.store.users), env.store.refreshTokens)
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackWithoutFlowCookie(t *testing.T) {
+	env := newOIDCTestEnv(t, OIDCOptions{DefaultRole: RoleViewer})
+
+	resp, err := env.app.Test(httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?code=x&state=y", nil))
+	if err != nil {
+		t.Fatalf("callback request: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("callback status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestRoleForGroups(t *testing.T) {
+	h := &OIDCHandler{options: OIDCOptions{
+		RoleMapping: map[string]string{"admins": RoleAdmin, "analysts": RoleAnalyst, "readers": RoleViewer},
+		DefaultRole: RoleViewer,
+	}}
+	strict := &OIDCHandler{options: OIDCOptions{RoleMapping: h.options.RoleMapping}}
+
+	tests := []struct {
+		handler *OIDCHandler
+		groups  []string
+		want    string
+	}{
+		{h, []string{"readers", "admins", "analysts"}, RoleAdmin},
+		{h, []string{"analysts", "readers"}, RoleAnalyst},
+		{h, []string{"everyone"}, RoleViewer},
+		{h, nil, RoleViewer},
+		{strict, []string{"everyone"}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.handler.roleForGroups(tt.groups); got != tt.want {
+			t.Errorf("roleForGroups(%v) = %q, want %q", tt.groups, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-api/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Page sizes for list endpoints
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// listPage reads ?cursor=, ?limit= and ?sort= (a sort key, prefixed with "-"
+// for descending order). Lists are newest first by default.
+func listPage(c *fiber.Ctx) (db.ListPage, error) {
+	page := db.ListPage{
+		SortBy: "created_at",
+		Desc:   true,
+		Cursor: c.Query("cursor"),
+		Limit:  defaultPageLimit,
+	}
+
+	if sort := c.Query("sort"); sort != "" {
+		page.SortBy, page.Desc = strings.CutPrefix(sort, "-")
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return page, fiber.NewError(fiber.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxPageLimit))
+		}
+		page.Limit = int32(limit)
+	}
+
+	return page, nil
+}
+
+// listError turns invalid cursors and sort keys into 400 responses
+func listError(c *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, db.ErrInvalidCursor) || errors.Is(err, db.ErrInvalidSort) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+// pageResponse is the envelope of every paginated list
+func pageResponse(data any, nextCursor string) fiber.Map {
+	var next *string
+	if nextCursor != "" {
+		next = &nextCursor
+	}
+	return fiber.Map{
+		"data":        data,
+		"next_cursor": next,
+	}
+}
+
+func queryInt8(c *fiber.Ctx, name string) (pgtype.Int8, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return pgtype.Int8{}, nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return pgtype.Int8{}, fiber.NewError(fiber.StatusBadRequest, name+" must be an integer")
+	}
+	return pgtype.Int8{Int64: value, Valid: true}, nil
+}
+
+func queryBool(c *fiber.Ctx, name string) (pgtype.Bool, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return pgtype.Bool{}, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return pgtype.Bool{}, fiber.NewError(fiber.StatusBadRequest, name+" must be true or false")
+	}
+	return pgtype.Bool{Bool: value, Valid: true}, nil
+}
+
+// queryTime accepts RFC 3339 timestamps or dates (midnight UTC)
+func queryTime(c *fiber.Ctx, name string) (pgtype.Timestamp, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return pgtype.Timestamp{}, nil
+	}
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		value, err = time.Parse(time.DateOnly, raw)
+	}
+	if err != nil {
+		return pgtype.Timestamp{}, fiber.NewError(fiber.StatusBadRequest, name+" must be an RFC 3339 timestamp or a date")
+	}
+	return pgtype.Timestamp{Time: value.UTC(), Valid: true}, nil
+}
+
+func queryText(c *fiber.Ctx, name string) pgtype.Text {
+	raw := c.Query(name)
+	return pgtype.Text{String: raw, Valid: raw != ""}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Parâmetros do argon2id para novas senhas. Hashes gravados com parâmetros
+// diferentes são refeitos no próximo login.
+const (
+	argon2Memory  uint32 = 64 * 1024 // KiB
+	argon2Time    uint32 = 3
+	argon2Threads uint8  = 2
+	argon2SaltLen        = 16
+	argon2KeyLen  uint32 = 32
+)
+
+var errInvalidPasswordHash = errors.New("hash de senha em formato inválido")
+
+// dummyPasswordHash é usado quando o usuário não existe, para que o login
+// leve o mesmo tempo em ambos os casos
+var dummyPasswordHash, _ = hashPassword("dummy-password")
+
+// hashPassword cria a hash argon2id da senha com salt aleatório, no formato
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("falha ao gerar salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// verifyPassword compara a senha com a hash gravada em tempo constante.
+// needsRehash indica que a hash usa o formato SHA-512 legado ou parâmetros
+// antigos e deve ser refeita.
+func verifyPassword(password, encoded string) (ok, needsRehash bool, err error) {
+	if !strings.HasPrefix(encoded, "$argon2id$") {
+		// Hash SHA-512 hex sem salt, usada antes do argon2id
+		if len(encoded) != sha512.Size*2 {
+			return false, false, errInvalidPasswordHash
+		}
+		hash := sha512.Sum512([]byte(password))
+		legacy := hex.EncodeToString(hash[:])
+		return subtle.ConstantTimeCompare([]byte(legacy), []byte(strings.ToLower(encoded))) == 1, true, nil
+	}
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, false, errInvalidPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, errInvalidPasswordHash
+	}
+
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, false, errInvalidPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, errInvalidPasswordHash
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(expected) == 0 {
+		return false, false, errInvalidPasswordHash
+	}
+
+	key := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(expected)))
+	if subtle.ConstantTimeCompare(key, expected) != 1 {
+		return false, false, nil
+	}
+
+	needsRehash = memory != argon2Memory || time != argon2Time || threads != argon2Threads ||
+		len(salt) != argon2SaltLen || uint32(len(expected)) != argon2KeyLen
+	return true, needsRehash, nil
+}
@@ -63,19 +63,27 @@ type ProcessInfoResponse struct {
 type SnapshotResponse struct {
 	ID           int64   `json:"id"`
 	UserID       *int64  `json:"userId,omitempty"`
-	WebhookURL   string  `json:"webhook_url"`
+	AgentID      *int64  `json:"agentId,omitempty"`
+	WebhookURL   *string `json:"webhook_url,omitempty"`
 	SnapshotType string  `json:"snapshotType"`
 	ProcessCount int32   `json:"processCount"`
 	Success      bool    `json:"success"`
 	ErrorMessage *string `json:"errorMessage,omitempty"`
 	CreatedAt    string  `json:"createdAt"`
 	UpdatedAt    string  `json:"updatedAt"`
+
+	// Provenance: "agent" for captures, "import" for uploaded offline
+	// captures, whose createdAt is the original capture time
+	Source     string  `json:"source"`
+	SourceHost *string `json:"sourceHost,omitempty"`
+	ImportedAt *string `json:"importedAt,omitempty"`
 }
 
 type QueryHistoryResponse struct {
 	ID            int64   `json:"id"`
 	SnapshotID    int64   `json:"snapshotId"`
 	UserID        *int64  `json:"userId,omitempty"`
+	AgentID       *int64  `json:"agentId,omitempty"`
 	WebhookURL    string  `json:"webhookUrl"`
 	RequestedPID  int32   `json:"requestedPid"`
 	ProcessInfoID *int64  `json:"processInfoId,omitempty"`
@@ -84,29 +92,49 @@ type QueryHistoryResponse struct {
 	CreatedAt     string  `json:"createdAt"`
 }
 
-// Get all snapshots for a user
+// Get a page of the user's snapshots, filtered by ?type=, ?success=,
+// ?agent_id=, ?from= and ?to=
 func (h *ProcessHandler) GetSnapshots(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(int64)
 
-	snapshots, err := h.queries.GetProcessSnapshotsByUser(c.Context(), pgtype.Int8{Int64: userID, Valid: true})
+	params := db.ListProcessSnapshotsParams{
+		UserID:         pgtype.Int8{Int64: userID, Valid: true},
+		IncludeUnowned: isAdmin(c),
+		SnapshotType:   queryText(c, "type"),
+	}
+
+	var err error
+	if params.Page, err = listPage(c); err != nil {
+		return err
+	}
+	if params.Success, err = queryBool(c, "success"); err != nil {
+		return err
+	}
+	if params.AgentID, err = queryInt8(c, "agent_id"); err != nil {
+		return err
+	}
+	if params.CreatedFrom, err = queryTime(c, "from"); err != nil {
+		return err
+	}
+	if params.CreatedTo, err = queryTime(c, "to"); err != nil {
+		return err
+	}
+
+	page, err := h.queries.ListProcessSnapshots(c.Context(), params)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch snapshots",
-		})
+		return listError(c, err, "Failed to fetch snapshots")
 	}
 
-	response := make([]SnapshotResponse, len(snapshots))
-	for i, snapshot := range snapshots {
+	response := make([]SnapshotResponse, len(page.Rows))
+	for i, snapshot := range page.Rows {
 		response[i] = toSnapshotResponse(snapshot)
 	}
 
-	return c.JSON(response)
+	return c.JSON(pageResponse(response, page.NextCursor))
 }
 
 // Get a specific snapshot by ID
 func (h *ProcessHandler) GetSnapshot(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(int64)
-
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -127,7 +155,7 @@ func (h *ProcessHandler) GetSnapshot(c *fiber.Ctx) error {
 	}
 
 	// Check if user has access to this snapshot
-	if snapshot.UserID.Valid && snapshot.UserID.Int64 != userID {
+	if !canAccess(c, snapshot.UserID) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Access denied",
 		})
@@ -138,8 +166,6 @@ func (h *ProcessHandler) GetSnapshot(c *fiber.Ctx) error {
 
 // Get all processes in a snapshot
 func (h *ProcessHandler) GetSnapshotProcesses(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(int64)
-
 	snapshotID, err := strconv.ParseInt(c.Params("id"), 10, 64)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -160,7 +186,7 @@ func (h *ProcessHandler) GetSnapshotProcesses(c *fiber.Ctx) error {
 		})
 	}
 
-	if snapshot.UserID.Valid && snapshot.UserID.Int64 != userID {
+	if !canAccess(c, snapshot.UserID) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Access denied",
 		})
@@ -197,8 +223,9 @@ func (h *ProcessHandler) GetSnapshotsByType(c *fiber.Ctx) error {
 	}
 
 	snapshots, err := h.queries.GetProcessSnapshotsByType(c.Context(), db.GetProcessSnapshotsByTypeParams{
-		UserID:       pgtype.Int8{Int64: userID, Valid: true},
-		SnapshotType: snapshotType,
+		UserID:         pgtype.Int8{Int64: userID, Valid: true},
+		IncludeUnowned: isAdmin(c),
+		SnapshotType:   snapshotType,
 	})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -217,8 +244,6 @@ func (h *ProcessHandler) GetSnapshotsByType(c *fiber.Ctx) error {
 // Get a specific process info by ID
 func (h *ProcessHandler) GetProcessInfo(c *fiber.Ctx) error {
 	log.Debug("entrou no getProcessInfo")
-	userID := c.Locals("userID").(int64)
-
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -239,7 +264,7 @@ func (h *ProcessHandler) GetProcessInfo(c *fiber.Ctx) error {
 	}
 
 	// Check if user has access
-	if processInfo.UserID.Valid && processInfo.UserID.Int64 != userID {
+	if !canAccess(c, processInfo.UserID) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Access denied",
 		})
@@ -252,23 +277,45 @@ func (h *ProcessHandler) GetProcessInfo(c *fiber.Ctx) error {
 	return c.JSON(processResponse)
 }
 
-// Get all processes for a user
+// Get a page of the user's processes, filtered by ?name= (glob), ?pid=,
+// ?agent_id=, ?from= and ?to=
 func (h *ProcessHandler) GetProcessInfos(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(int64)
 
-	processes, err := h.queries.GetProcessInfosByUser(c.Context(), pgtype.Int8{Int64: userID, Valid: true})
+	params := db.ListProcessInfosParams{
+		UserID:         pgtype.Int8{Int64: userID, Valid: true},
+		IncludeUnowned: isAdmin(c),
+		NameGlob:       c.Query("name"),
+	}
+
+	var err error
+	if params.Page, err = listPage(c); err != nil {
+		return err
+	}
+	if params.ProcessID, err = queryInt8(c, "pid"); err != nil {
+		return err
+	}
+	if params.AgentID, err = queryInt8(c, "agent_id"); err != nil {
+		return err
+	}
+	if params.CreatedFrom, err = queryTime(c, "from"); err != nil {
+		return err
+	}
+	if params.CreatedTo, err = queryTime(c, "to"); err != nil {
+		return err
+	}
+
+	page, err := h.queries.ListProcessInfos(c.Context(), params)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch processes",
-		})
+		return listError(c, err, "Failed to fetch processes")
 	}
 
-	response := make([]ProcessInfoResponse, len(processes))
-	for i, process := range processes {
+	response := make([]ProcessInfoResponse, len(page.Rows))
+	for i, process := range page.Rows {
 		response[i] = toProcessInfoResponse(process)
 	}
 
-	return c.JSON(response)
+	return c.JSON(pageResponse(response, page.NextCursor))
 }
 
 // Get all processes by process ID (across all snapshots)
@@ -283,8 +330,9 @@ func (h *ProcessHandler) GetProcessInfosByProcessID(c *fiber.Ctx) error {
 	}
 
 	processes, err := h.queries.GetProcessInfosByProcessID(c.Context(), db.GetProcessInfosByProcessIDParams{
-		UserID:    pgtype.Int8{Int64: userID, Valid: true},
-		ProcessID: int64(processID),
+		UserID:         pgtype.Int8{Int64: userID, Valid: true},
+		IncludeUnowned: isAdmin(c),
+		ProcessID:      int64(processID),
 	})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -302,8 +350,6 @@ func (h *ProcessHandler) GetProcessInfosByProcessID(c *fiber.Ctx) error {
 
 // Delete a process info
 func (h *ProcessHandler) DeleteProcessInfo(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(int64)
-
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -324,7 +370,7 @@ func (h *ProcessHandler) DeleteProcessInfo(c *fiber.Ctx) error {
 		})
 	}
 
-	if processInfo.UserID.Valid && processInfo.UserID.Int64 != userID {
+	if !canAccess(c, processInfo.UserID) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Access denied",
 		})
@@ -344,8 +390,6 @@ func (h *ProcessHandler) DeleteProcessInfo(c *fiber.Ctx) error {
 
 // Delete a snapshot (and all its processes)
 func (h *ProcessHandler) DeleteSnapshot(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(int64)
-
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -366,7 +410,7 @@ func (h *ProcessHandler) DeleteSnapshot(c *fiber.Ctx) error {
 		})
 	}
 
-	if snapshot.UserID.Valid && snapshot.UserID.Int64 != userID {
+	if !canAccess(c, snapshot.UserID) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Access denied",
 		})
@@ -384,29 +428,53 @@ func (h *ProcessHandler) DeleteSnapshot(c *fiber.Ctx) error {
 	})
 }
 
-// Get query history for a user
+// Get a page of the user's query history, filtered by ?pid=, ?success=,
+// ?agent_id=, ?from= and ?to=
 func (h *ProcessHandler) GetQueryHistory(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(int64)
 
-	queries, err := h.queries.GetProcessQueriesByUser(c.Context(), pgtype.Int8{Int64: userID, Valid: true})
+	params := db.ListProcessQueriesParams{
+		UserID:         pgtype.Int8{Int64: userID, Valid: true},
+		IncludeUnowned: isAdmin(c),
+	}
+
+	var err error
+	if params.Page, err = listPage(c); err != nil {
+		return err
+	}
+	pid, err := queryInt8(c, "pid")
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch query history",
-		})
+		return err
+	}
+	params.RequestedPid = pgtype.Int4{Int32: int32(pid.Int64), Valid: pid.Valid}
+	if params.Success, err = queryBool(c, "success"); err != nil {
+		return err
+	}
+	if params.AgentID, err = queryInt8(c, "agent_id"); err != nil {
+		return err
+	}
+	if params.CreatedFrom, err = queryTime(c, "from"); err != nil {
+		return err
+	}
+	if params.CreatedTo, err = queryTime(c, "to"); err != nil {
+		return err
 	}
 
-	response := make([]QueryHistoryResponse, len(queries))
-	for i, query := range queries {
+	page, err := h.queries.ListProcessQueries(c.Context(), params)
+	if err != nil {
+		return listError(c, err, "Failed to fetch query history")
+	}
+
+	response := make([]QueryHistoryResponse, len(page.Rows))
+	for i, query := range page.Rows {
 		response[i] = toQueryHistoryResponse(query)
 	}
 
-	return c.JSON(response)
+	return c.JSON(pageResponse(response, page.NextCursor))
 }
 
 // Get query history for a specific snapshot
 func (h *ProcessHandler) GetSnapshotQueries(c *fiber.Ctx) error {
-	userID := c.Locals("userID").(int64)
-
 	snapshotID, err := strconv.ParseInt(c.Params("id"), 10, 64)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -427,7 +495,7 @@ func (h *ProcessHandler) GetSnapshotQueries(c *fiber.Ctx) error {
 		})
 	}
 
-	if snapshot.UserID.Valid && snapshot.UserID.Int64 != userID {
+	if !canAccess(c, snapshot.UserID) {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Access denied",
 		})
@@ -474,8 +542,9 @@ func (h *ProcessHandler) GetStatistics(c *fiber.Ctx) error {
 	}
 
 	mostQueried, err := h.queries.GetMostQueriedProcesses(c.Context(), db.GetMostQueriedProcessesParams{
-		UserID: pgtype.Int8{Int64: userID, Valid: true},
-		Limit:  10,
+		UserID:         pgtype.Int8{Int64: userID, Valid: true},
+		IncludeUnowned: isAdmin(c),
+		Limit:          10,
 	})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -483,7 +552,10 @@ func (h *ProcessHandler) GetStatistics(c *fiber.Ctx) error {
 		})
 	}
 
-	snapshotStats, err := h.queries.GetSnapshotStatistics(c.Context(), pgtype.Int8{Int64: userID, Valid: true})
+	snapshotStats, err := h.queries.GetSnapshotStatistics(c.Context(), db.GetSnapshotStatisticsParams{
+		UserID:         pgtype.Int8{Int64: userID, Valid: true},
+		IncludeUnowned: isAdmin(c),
+	})
 	if err != nil {
 		log.Debug(err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -501,6 +573,29 @@ func (h *ProcessHandler) GetStatistics(c *fiber.Ctx) error {
 }
 
 // Helper functions
+// fetchAccessibleSnapshot loads the snapshot whose ID is in the given route
+// param and checks that the authenticated user can read it
+func (h *ProcessHandler) fetchAccessibleSnapshot(c *fiber.Ctx, param string) (db.ProcessSnapshot, error) {
+	id, err := strconv.ParseInt(c.Params(param), 10, 64)
+	if err != nil {
+		return db.ProcessSnapshot{}, fiber.NewError(fiber.StatusBadRequest, "Invalid snapshot ID")
+	}
+
+	snapshot, err := h.queries.GetProcessSnapshot(c.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return db.ProcessSnapshot{}, fiber.NewError(fiber.StatusNotFound, "Snapshot not found")
+		}
+		return db.ProcessSnapshot{}, fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch snapshot")
+	}
+
+	if !canAccess(c, snapshot.UserID) {
+		return db.ProcessSnapshot{}, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return snapshot, nil
+}
+
 func toProcessInfoResponse(info db.ProcessInfo) ProcessInfoResponse {
 	response := ProcessInfoResponse{
 		ID:                    info.ID,
@@ -575,18 +670,35 @@ func toProcessInfoResponse(info db.ProcessInfo) ProcessInfoResponse {
 func toSnapshotResponse(snapshot db.ProcessSnapshot) SnapshotResponse {
 	response := SnapshotResponse{
 		ID:           snapshot.ID,
-		WebhookURL:   snapshot.WebhookUrl,
 		SnapshotType: snapshot.SnapshotType,
 		ProcessCount: snapshot.ProcessCount,
 		Success:      snapshot.Success,
 		CreatedAt:    snapshot.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:    snapshot.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		Source:       snapshot.Source,
+	}
+
+	if snapshot.WebhookUrl.Valid {
+		response.WebhookURL = &snapshot.WebhookUrl.String
+	}
+
+	if snapshot.SourceHost.Valid {
+		response.SourceHost = &snapshot.SourceHost.String
+	}
+
+	if snapshot.ImportedAt.Valid {
+		importedAt := snapshot.ImportedAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		response.ImportedAt = &importedAt
 	}
 
 	if snapshot.UserID.Valid {
 		response.UserID = &snapshot.UserID.Int64
 	}
 
+	if snapshot.AgentID.Valid {
+		response.AgentID = &snapshot.AgentID.Int64
+	}
+
 	if snapshot.ErrorMessage.Valid {
 		response.ErrorMessage = &snapshot.ErrorMessage.String
 	}
@@ -608,6 +720,10 @@ func toQueryHistoryResponse(query db.ProcessQuery) QueryHistoryResponse {
 		response.UserID = &query.UserID.Int64
 	}
 
+	if query.AgentID.Valid {
+		response.AgentID = &query.AgentID.Int64
+	}
+
 	if query.ProcessInfoID.Valid {
 		response.ProcessInfoID = &query.ProcessInfoID.Int64
 	}
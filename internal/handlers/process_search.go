@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"go-api/internal/db"
+	"go-api/internal/search"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Search the user's processes across all snapshots with the search query
+// language, e.g. ?q=name:svchost* ppid:4 handles>1000 ws>500MB since:7d
+func (h *ProcessHandler) SearchProcesses(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	if c.Query("q") == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "q is required",
+		})
+	}
+
+	query, err := search.Parse(c.Query("q"), time.Now())
+	if err != nil {
+		var searchErr *search.Error
+		if errors.As(err, &searchErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return err
+	}
+
+	page, err := listPage(c)
+	if err != nil {
+		return err
+	}
+
+	result, err := h.queries.ListProcessInfos(c.Context(), db.ListProcessInfosParams{
+		UserID:         pgtype.Int8{Int64: userID, Valid: true},
+		IncludeUnowned: isAdmin(c),
+		Where:          query.Where,
+		Page:           page,
+	})
+	if err != nil {
+		return listError(c, err, "Failed to search processes")
+	}
+
+	response := make([]ProcessInfoResponse, len(result.Rows))
+	for i, process := range result.Rows {
+		response[i] = toProcessInfoResponse(process)
+	}
+
+	return c.JSON(pageResponse(response, result.NextCursor))
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"strings"
+
+	"go-api/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Most points returned by one timeline
+const maxTimelinePoints = 5000
+
+// ProcessInstanceResponse identifies one process instance. PIDs are reused,
+// so an instance is a PID plus its create time, EPROCESS address and agent.
+type ProcessInstanceResponse struct {
+	ProcessID       int64  `json:"processId"`
+	ProcessName     string `json:"processName"`
+	CreateTime      string `json:"createTime"`
+	EProcessAddress string `json:"eProcessAddress"`
+	AgentID         *int64 `json:"agentId,omitempty"`
+	FirstSeen       string `json:"firstSeen"`
+	LastSeen        string `json:"lastSeen"`
+	SnapshotCount   int    `json:"snapshotCount"`
+}
+
+// TimelinePoint holds the counters of the process in one snapshot
+type TimelinePoint struct {
+	SnapshotID          int64  `json:"snapshotId"`
+	ProcessInfoID       int64  `json:"processInfoId"`
+	CapturedAt          string `json:"capturedAt"`
+	ParentProcessID     int64  `json:"parentProcessId"`
+	ThreadCount         int32  `json:"threadCount"`
+	HandleCount         int32  `json:"handleCount"`
+	UserTime            int32  `json:"userTime"`
+	KernelTime          int32  `json:"kernelTime"`
+	WorkingSetSize      int64  `json:"workingSetSize"`
+	PeakWorkingSetSize  int64  `json:"peakWorkingSetSize"`
+	VirtualSize         int64  `json:"virtualSize"`
+	PeakVirtualSize     int64  `json:"peakVirtualSize"`
+	ReadOperationCount  int64  `json:"readOperationCount"`
+	WriteOperationCount int64  `json:"writeOperationCount"`
+	OtherOperationCount int64  `json:"otherOperationCount"`
+	ReadTransferCount   int64  `json:"readTransferCount"`
+	WriteTransferCount  int64  `json:"writeTransferCount"`
+	OtherTransferCount  int64  `json:"otherTransferCount"`
+	PageFaultCount      int64  `json:"pageFaultCount"`
+
+	// Rates since the previous iteration of the agent, when it saw the process
+	Rates *ProcessRatesResponse `json:"rates,omitempty"`
+}
+
+type ProcessTimelineResponse struct {
+	Instance  ProcessInstanceResponse `json:"instance"`
+	Points    []TimelinePoint         `json:"points"`
+	Count     int                     `json:"count"`
+	Truncated bool                    `json:"truncated"` // more than maxTimelinePoints snapshots
+}
+
+type processInstanceKey struct {
+	agentID    int64
+	createTime string
+	address    string
+}
+
+// Get the counter history of one process instance across every snapshot it
+// appeared in. ?pid= is required; ?create_time=, ?address= and ?agent_id=
+// pick the instance when the PID was reused.
+func (h *ProcessHandler) GetProcessTimeline(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	pid, err := queryInt8(c, "pid")
+	if err != nil {
+		return err
+	}
+	if !pid.Valid {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "pid is required",
+		})
+	}
+
+	agentID, err := queryInt8(c, "agent_id")
+	if err != nil {
+		return err
+	}
+
+	rows, err := h.queries.GetProcessTimeline(c.Context(), db.GetProcessTimelineParams{
+		ProcessID:       pid.Int64,
+		UserID:          pgtype.Int8{Int64: userID, Valid: true},
+		IncludeUnowned:  isAdmin(c),
+		CreateTime:      queryText(c, "create_time"),
+		EprocessAddress: queryText(c, "address"),
+		AgentID:         agentID,
+		MaxPoints:       maxTimelinePoints + 1,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch process timeline",
+		})
+	}
+
+	if len(rows) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Process not found",
+		})
+	}
+
+	truncated := len(rows) > maxTimelinePoints
+	if truncated {
+		rows = rows[:maxTimelinePoints]
+	}
+
+	// Group the rows by instance; the timeline needs exactly one
+	var keys []processInstanceKey
+	instances := make(map[processInstanceKey]*ProcessInstanceResponse)
+	for _, row := range rows {
+		key := processInstanceKey{
+			agentID:    row.AgentID.Int64,
+			createTime: row.CreateTime,
+			address:    strings.ToLower(row.CurrentProcessAddress),
+		}
+
+		capturedAt := row.CapturedAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		instance, ok := instances[key]
+		if !ok {
+			instance = &ProcessInstanceResponse{
+				ProcessID:       row.ProcessID,
+				ProcessName:     row.ProcessName,
+				CreateTime:      row.CreateTime,
+				EProcessAddress: row.CurrentProcessAddress,
+				FirstSeen:       capturedAt,
+			}
+			if row.AgentID.Valid {
+				agentID := row.AgentID.Int64
+				instance.AgentID = &agentID
+			}
+			instances[key] = instance
+			keys = append(keys, key)
+		}
+		instance.LastSeen = capturedAt
+		instance.SnapshotCount++
+	}
+
+	if len(keys) > 1 {
+		candidates := make([]ProcessInstanceResponse, len(keys))
+		for i, key := range keys {
+			candidates[i] = *instances[key]
+		}
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":     "PID matches more than one process instance, pick one with create_time, address or agent_id",
+			"instances": candidates,
+		})
+	}
+
+	points := make([]TimelinePoint, len(rows))
+	for i, row := range rows {
+		points[i] = toTimelinePoint(row)
+	}
+
+	return c.JSON(ProcessTimelineResponse{
+		Instance:  *instances[keys[0]],
+		Points:    points,
+		Count:     len(points),
+		Truncated: truncated,
+	})
+}
+
+func toTimelinePoint(row db.GetProcessTimelineRow) TimelinePoint {
+	point := TimelinePoint{
+		SnapshotID:          row.SnapshotID,
+		ProcessInfoID:       row.ID,
+		CapturedAt:          row.CapturedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		ParentProcessID:     row.ParentProcessID,
+		ThreadCount:         row.ThreadCount,
+		HandleCount:         row.HandleCount,
+		UserTime:            row.UserTime,
+		KernelTime:          row.KernelTime,
+		WorkingSetSize:      row.WorkingSetSize,
+		PeakWorkingSetSize:  row.PeakWorkingSetSize,
+		VirtualSize:         row.VirtualSize,
+		PeakVirtualSize:     row.PeakVirtualSize,
+		ReadOperationCount:  row.ReadOperationCount,
+		WriteOperationCount: row.WriteOperationCount,
+		OtherOperationCount: row.OtherOperationCount,
+		ReadTransferCount:   row.ReadTransferCount,
+		WriteTransferCount:  row.WriteTransferCount,
+		OtherTransferCount:  row.OtherTransferCount,
+		PageFaultCount:      row.PageFaultCount,
+	}
+
+	if row.IntervalSeconds.Valid {
+		point.Rates = &ProcessRatesResponse{
+			IntervalSeconds:    row.IntervalSeconds.Float64,
+			CPUPercent:         float8Ptr(row.CpuPercent),
+			ReadBytesPerSec:    float8Ptr(row.ReadBytesPerSec),
+			WriteBytesPerSec:   float8Ptr(row.WriteBytesPerSec),
+			OtherBytesPerSec:   float8Ptr(row.OtherBytesPerSec),
+			ReadOpsPerSec:      float8Ptr(row.ReadOpsPerSec),
+			WriteOpsPerSec:     float8Ptr(row.WriteOpsPerSec),
+			PageFaultsPerSec:   float8Ptr(row.PageFaultsPerSec),
+			HandleGrowthPerSec: row.HandleGrowthPerSec.Float64,
+		}
+	}
+
+	return point
+}
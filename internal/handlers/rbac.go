@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// User roles, from most to least privileged
+const (
+	RoleAdmin   = "admin"
+	RoleAnalyst = "analyst"
+	RoleViewer  = "viewer"
+)
+
+// Permission names an action a route requires
+type Permission string
+
+const (
+	PermReadData     Permission = "data:read"     // list and read snapshots, processes, agents, schedules, jobs and alerts
+	PermCapture      Permission = "capture:run"   // trigger captures and manage schedules
+	PermManageAgents Permission = "agents:manage" // register, update and delete agents
+	PermDeleteData   Permission = "data:delete"   // delete snapshots and process records
+	PermManageUsers  Permission = "users:manage"  // manage user accounts and roles
+	PermManageAlerts Permission = "alerts:manage" // manage alert rules and acknowledge alerts
+)
+
+var rolePermissions = map[string]map[Permission]bool{
+	RoleAdmin: {
+		PermReadData:     true,
+		PermCapture:      true,
+		PermManageAgents: true,
+		PermDeleteData:   true,
+		PermManageUsers:  true,
+		PermManageAlerts: true,
+	},
+	RoleAnalyst: {
+		PermReadData:     true,
+		PermCapture:      true,
+		PermManageAgents: true,
+		PermDeleteData:   true,
+		PermManageAlerts: true,
+	},
+	RoleViewer: {
+		PermReadData: true,
+	},
+}
+
+// API key scopes. A key never grants more than its owner's role allows.
+const (
+	ScopeRead    = "read"
+	ScopeCapture = "capture"
+)
+
+var scopePermissions = map[string]map[Permission]bool{
+	ScopeRead: {
+		PermReadData: true,
+	},
+	ScopeCapture: {
+		PermReadData: true,
+		PermCapture:  true,
+	},
+}
+
+// IsValidScope reports whether scope is a known API key scope
+func IsValidScope(scope string) bool {
+	_, ok := scopePermissions[scope]
+	return ok
+}
+
+func scopesAllow(scopes []string, perm Permission) bool {
+	for _, scope := range scopes {
+		if scopePermissions[scope][perm] {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether role grants perm
+func HasPermission(role string, perm Permission) bool {
+	return rolePermissions[role][perm]
+}
+
+// hasPermission checks the role of the authenticated user and, for requests
+// made with an API key, the key's scopes
+func hasPermission(c *fiber.Ctx, perm Permission) bool {
+	role, _ := c.Locals("userRole").(string)
+	if !HasPermission(role, perm) {
+		return false
+	}
+	if scopes, ok := c.Locals("apiKeyScopes").([]string); ok {
+		return scopesAllow(scopes, perm)
+	}
+	return true
+}
+
+// RequirePermission rejects requests whose user role (or API key scopes) do
+// not grant perm. It must run after JWTMiddleware.
+func RequirePermission(perm Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Locals("userRole") == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Authentication required",
+			})
+		}
+
+		if !hasPermission(c, perm) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Insufficient permissions",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// RequirePermissionWhenAuthenticated is RequirePermission for routes behind
+// OptionalJWTMiddleware: anonymous requests pass through, authenticated ones
+// must have perm.
+func RequirePermissionWhenAuthenticated(perm Permission) fiber.Handler {
+	check := RequirePermission(perm)
+	return func(c *fiber.Ctx) error {
+		if c.Locals("userRole") == nil {
+			return c.Next()
+		}
+		return check(c)
+	}
+}
+
+func isAdmin(c *fiber.Ctx) bool {
+	role, _ := c.Locals("userRole").(string)
+	return role == RoleAdmin
+}
+
+// canAccess reports whether the authenticated user may read a row owned by
+// owner. Rows without an owner are only visible to admins.
+func canAccess(c *fiber.Ctx, owner pgtype.Int8) bool {
+	if !owner.Valid {
+		return isAdmin(c)
+	}
+	userID, ok := c.Locals("userID").(int64)
+	return ok && owner.Int64 == userID
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"go-api/internal/db"
+	"go-api/internal/scheduler"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ScheduleHandler struct {
+	queries *db.Queries
+}
+
+func NewScheduleHandler(dbpool *pgxpool.Pool) *ScheduleHandler {
+	return &ScheduleHandler{
+		queries: db.New(dbpool),
+	}
+}
+
+type CreateScheduleRequest struct {
+	AgentID         int64   `json:"agent_id"`
+	CronExpression  *string `json:"cron_expression,omitempty"`
+	IntervalSeconds *int32  `json:"interval_seconds,omitempty"`
+	Paused          bool    `json:"paused"`
+}
+
+type UpdateScheduleRequest struct {
+	CronExpression  *string `json:"cron_expression,omitempty"`
+	IntervalSeconds *int32  `json:"interval_seconds,omitempty"`
+}
+
+type ScheduleResponse struct {
+	ID              int64   `json:"id"`
+	AgentID         int64   `json:"agentId"`
+	UserID          int64   `json:"userId"`
+	CronExpression  *string `json:"cronExpression,omitempty"`
+	IntervalSeconds *int32  `json:"intervalSeconds,omitempty"`
+	Paused          bool    `json:"paused"`
+	NextRunAt       string  `json:"nextRunAt"`
+	LastRunAt       *string `json:"lastRunAt,omitempty"`
+	LastSnapshotID  *int64  `json:"lastSnapshotId,omitempty"`
+	LastError       *string `json:"lastError,omitempty"`
+	CreatedAt       string  `json:"createdAt"`
+	UpdatedAt       string  `json:"updatedAt"`
+}
+
+// Get all schedules of the user
+func (h *ScheduleHandler) GetSchedules(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	schedules, err := h.queries.GetSchedulesByUser(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch schedules",
+		})
+	}
+
+	response := make([]ScheduleResponse, len(schedules))
+	for i, schedule := range schedules {
+		response[i] = toScheduleResponse(schedule)
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  response,
+		"count": len(response),
+	})
+}
+
+// Get a specific schedule by ID
+func (h *ScheduleHandler) GetSchedule(c *fiber.Ctx) error {
+	schedule, err := h.fetchOwnedSchedule(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"data": toScheduleResponse(schedule),
+	})
+}
+
+// Create a periodic capture schedule for an agent
+func (h *ScheduleHandler) CreateSchedule(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	var req CreateScheduleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.AgentID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "agent_id is required",
+		})
+	}
+
+	spec, err := scheduler.ParseSpec(req.CronExpression, req.IntervalSeconds)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if _, err := h.queries.GetAgent(c.Context(), req.AgentID); err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Agent not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch agent",
+		})
+	}
+
+	schedule, err := h.queries.CreateSchedule(c.Context(), db.CreateScheduleParams{
+		AgentID:         req.AgentID,
+		UserID:          userID,
+		CronExpression:  optionalText(req.CronExpression),
+		IntervalSeconds: optionalInt4(req.IntervalSeconds),
+		Paused:          req.Paused,
+		NextRunAt:       pgtype.Timestamp{Time: spec.Next(time.Now()).UTC(), Valid: true},
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create schedule",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"data":    toScheduleResponse(schedule),
+		"message": "Schedule created successfully",
+	})
+}
+
+// Replace the cron expression or interval of a schedule
+func (h *ScheduleHandler) UpdateSchedule(c *fiber.Ctx) error {
+	current, err := h.fetchOwnedSchedule(c)
+	if err != nil {
+		return err
+	}
+
+	var req UpdateScheduleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	spec, err := scheduler.ParseSpec(req.CronExpression, req.IntervalSeconds)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	schedule, err := h.queries.UpdateSchedule(c.Context(), db.UpdateScheduleParams{
+		ID:              current.ID,
+		CronExpression:  optionalText(req.CronExpression),
+		IntervalSeconds: optionalInt4(req.IntervalSeconds),
+		NextRunAt:       pgtype.Timestamp{Time: spec.Next(time.Now()).UTC(), Valid: true},
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update schedule",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":    toScheduleResponse(schedule),
+		"message": "Schedule updated successfully",
+	})
+}
+
+// Pause a schedule
+func (h *ScheduleHandler) PauseSchedule(c *fiber.Ctx) error {
+	current, err := h.fetchOwnedSchedule(c)
+	if err != nil {
+		return err
+	}
+
+	schedule, err := h.queries.SetSchedulePaused(c.Context(), db.SetSchedulePausedParams{
+		ID:        current.ID,
+		Paused:    true,
+		NextRunAt: current.NextRunAt,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to pause schedule",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":    toScheduleResponse(schedule),
+		"message": "Schedule paused",
+	})
+}
+
+// Resume a paused schedule. The next run is computed from now so the time
+// spent paused is not reported as missed runs.
+func (h *ScheduleHandler) ResumeSchedule(c *fiber.Ctx) error {
+	current, err := h.fetchOwnedSchedule(c)
+	if err != nil {
+		return err
+	}
+
+	spec, err := scheduler.SpecFromRow(current)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	schedule, err := h.queries.SetSchedulePaused(c.Context(), db.SetSchedulePausedParams{
+		ID:        current.ID,
+		Paused:    false,
+		NextRunAt: pgtype.Timestamp{Time: spec.Next(time.Now()).UTC(), Valid: true},
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to resume schedule",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":    toScheduleResponse(schedule),
+		"message": "Schedule resumed",
+	})
+}
+
+// Delete a schedule
+func (h *ScheduleHandler) DeleteSchedule(c *fiber.Ctx) error {
+	current, err := h.fetchOwnedSchedule(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.queries.DeleteSchedule(c.Context(), current.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete schedule",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Schedule deleted successfully",
+	})
+}
+
+// fetchOwnedSchedule loads the schedule in the :id param and checks that it
+// belongs to the authenticated user
+func (h *ScheduleHandler) fetchOwnedSchedule(c *fiber.Ctx) (db.CaptureSchedule, error) {
+	userID := c.Locals("userID").(int64)
+
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return db.CaptureSchedule{}, fiber.NewError(fiber.StatusBadRequest, "Invalid schedule ID")
+	}
+
+	schedule, err := h.queries.GetSchedule(c.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return db.CaptureSchedule{}, fiber.NewError(fiber.StatusNotFound, "Schedule not found")
+		}
+		return db.CaptureSchedule{}, fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch schedule")
+	}
+
+	if schedule.UserID != userID {
+		return db.CaptureSchedule{}, fiber.NewError(fiber.StatusForbidden, "Access denied")
+	}
+
+	return schedule, nil
+}
+
+func optionalText(value *string) pgtype.Text {
+	if value == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *value, Valid: true}
+}
+
+func optionalInt4(value *int32) pgtype.Int4 {
+	if value == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: *value, Valid: true}
+}
+
+func toScheduleResponse(schedule db.CaptureSchedule) ScheduleResponse {
+	response := ScheduleResponse{
+		ID:        schedule.ID,
+		AgentID:   schedule.AgentID,
+		UserID:    schedule.UserID,
+		Paused:    schedule.Paused,
+		NextRunAt: schedule.NextRunAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		CreatedAt: schedule.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: schedule.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+
+	if schedule.CronExpression.Valid {
+		response.CronExpression = &schedule.CronExpression.String
+	}
+
+	if schedule.IntervalSeconds.Valid {
+		response.IntervalSeconds = &schedule.IntervalSeconds.Int32
+	}
+
+	if schedule.LastRunAt.Valid {
+		lastRun := schedule.LastRunAt.Time.Format("2006-01-02T15:04:05Z07:00")
+		response.LastRunAt = &lastRun
+	}
+
+	if schedule.LastSnapshotID.Valid {
+		response.LastSnapshotID = &schedule.LastSnapshotID.Int64
+	}
+
+	if schedule.LastError.Valid {
+		response.LastError = &schedule.LastError.String
+	}
+
+	return response
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"sort"
+
+	"go-api/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Default thresholds for reporting counter changes between two snapshots
+const (
+	defaultWorkingSetThreshold  = 10 * 1024 * 1024 // 10 MiB
+	defaultVirtualSizeThreshold = 50 * 1024 * 1024 // 50 MiB
+	defaultHandleThreshold      = 100
+	defaultThreadThreshold      = 5
+)
+
+// processKey identifies a process instance. The PID alone is not enough
+// because Windows reuses PIDs once a process exits.
+type processKey struct {
+	ProcessID  int64
+	CreateTime string
+}
+
+func keyOf(info db.ProcessInfo) processKey {
+	return processKey{ProcessID: info.ProcessID, CreateTime: info.CreateTime}
+}
+
+type DiffThresholds struct {
+	WorkingSetSize int64 `json:"workingSetSize"`
+	VirtualSize    int64 `json:"virtualSize"`
+	HandleCount    int64 `json:"handleCount"`
+	ThreadCount    int64 `json:"threadCount"`
+}
+
+type CounterChange struct {
+	From  int64 `json:"from"`
+	To    int64 `json:"to"`
+	Delta int64 `json:"delta"`
+}
+
+type ReparentedProcess struct {
+	ProcessID          int64  `json:"processId"`
+	CreateTime         string `json:"createTime"`
+	ProcessName        string `json:"processName"`
+	OldParentProcessID int64  `json:"oldParentProcessId"`
+	NewParentProcessID int64  `json:"newParentProcessId"`
+}
+
+type ChangedProcess struct {
+	ProcessID   int64                    `json:"processId"`
+	CreateTime  string                   `json:"createTime"`
+	ProcessName string                   `json:"processName"`
+	Changes     map[string]CounterChange `json:"changes"`
+}
+
+type DiffSummary struct {
+	Started    int `json:"started"`
+	Exited     int `json:"exited"`
+	Reparented int `json:"reparented"`
+	Changed    int `json:"changed"`
+	Unchanged  int `json:"unchanged"`
+}
+
+type SnapshotDiffResponse struct {
+	Base       SnapshotResponse      `json:"base"`
+	Other      SnapshotResponse      `json:"other"`
+	Thresholds DiffThresholds        `json:"thresholds"`
+	Summary    DiffSummary           `json:"summary"`
+	Started    []ProcessInfoResponse `json:"started"`
+	Exited     []ProcessInfoResponse `json:"exited"`
+	Reparented []ReparentedProcess   `json:"reparented"`
+	Changed    []ChangedProcess      `json:"changed"`
+}
+
+// Compare the processes of two snapshots
+func (h *ProcessHandler) GetSnapshotDiff(c *fiber.Ctx) error {
+	base, err := h.fetchAccessibleSnapshot(c, "id")
+	if err != nil {
+		return err
+	}
+
+	other, err := h.fetchAccessibleSnapshot(c, "otherId")
+	if err != nil {
+		return err
+	}
+
+	thresholds := DiffThresholds{
+		WorkingSetSize: int64(c.QueryInt("ws_threshold", defaultWorkingSetThreshold)),
+		VirtualSize:    int64(c.QueryInt("vm_threshold", defaultVirtualSizeThreshold)),
+		HandleCount:    int64(c.QueryInt("handle_threshold", defaultHandleThreshold)),
+		ThreadCount:    int64(c.QueryInt("thread_threshold", defaultThreadThreshold)),
+	}
+	if thresholds.WorkingSetSize < 0 || thresholds.VirtualSize < 0 || thresholds.HandleCount < 0 || thresholds.ThreadCount < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Thresholds must not be negative",
+		})
+	}
+
+	baseProcesses, err := h.queries.GetProcessInfosBySnapshot(c.Context(), base.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch processes",
+		})
+	}
+
+	otherProcesses, err := h.queries.GetProcessInfosBySnapshot(c.Context(), other.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch processes",
+		})
+	}
+
+	response := diffSnapshots(baseProcesses, otherProcesses, thresholds)
+	response.Base = toSnapshotResponse(base)
+	response.Other = toSnapshotResponse(other)
+
+	return c.JSON(response)
+}
+
+func diffSnapshots(baseProcesses, otherProcesses []db.ProcessInfo, thresholds DiffThresholds) SnapshotDiffResponse {
+	response := SnapshotDiffResponse{
+		Thresholds: thresholds,
+		Started:    []ProcessInfoResponse{},
+		Exited:     []ProcessInfoResponse{},
+		Reparented: []ReparentedProcess{},
+		Changed:    []ChangedProcess{},
+	}
+
+	baseByKey := make(map[processKey]db.ProcessInfo, len(baseProcesses))
+	for _, process := range baseProcesses {
+		baseByKey[keyOf(process)] = process
+	}
+
+	otherByKey := make(map[processKey]db.ProcessInfo, len(otherProcesses))
+	for _, process := range otherProcesses {
+		otherByKey[keyOf(process)] = process
+	}
+
+	for key, before := range baseByKey {
+		after, ok := otherByKey[key]
+		if !ok {
+			response.Exited = append(response.Exited, toProcessInfoResponse(before))
+			continue
+		}
+
+		if before.ParentProcessID != after.ParentProcessID {
+			response.Reparented = append(response.Reparented, ReparentedProcess{
+				ProcessID:          key.ProcessID,
+				CreateTime:         key.CreateTime,
+				ProcessName:        after.ProcessName,
+				OldParentProcessID: before.ParentProcessID,
+				NewParentProcessID: after.ParentProcessID,
+			})
+		}
+
+		changes := make(map[string]CounterChange)
+		compareCounter(changes, "workingSetSize", before.WorkingSetSize, after.WorkingSetSize, thresholds.WorkingSetSize)
+		compareCounter(changes, "virtualSize", before.VirtualSize, after.VirtualSize, thresholds.VirtualSize)
+		compareCounter(changes, "handleCount", int64(before.HandleCount), int64(after.HandleCount), thresholds.HandleCount)
+		compareCounter(changes, "threadCount", int64(before.ThreadCount), int64(after.ThreadCount), thresholds.ThreadCount)
+
+		if len(changes) > 0 {
+			response.Changed = append(response.Changed, ChangedProcess{
+				ProcessID:   key.ProcessID,
+				CreateTime:  key.CreateTime,
+				ProcessName: after.ProcessName,
+				Changes:     changes,
+			})
+		} else if before.ParentProcessID == after.ParentProcessID {
+			response.Summary.Unchanged++
+		}
+	}
+
+	for key, after := range otherByKey {
+		if _, ok := baseByKey[key]; !ok {
+			response.Started = append(response.Started, toProcessInfoResponse(after))
+		}
+	}
+
+	sort.Slice(response.Started, func(i, j int) bool { return response.Started[i].ProcessID < response.Started[j].ProcessID })
+	sort.Slice(response.Exited, func(i, j int) bool { return response.Exited[i].ProcessID < response.Exited[j].ProcessID })
+	sort.Slice(response.Reparented, func(i, j int) bool { return response.Reparented[i].ProcessID < response.Reparented[j].ProcessID })
+	sort.Slice(response.Changed, func(i, j int) bool { return response.Changed[i].ProcessID < response.Changed[j].ProcessID })
+
+	response.Summary.Started = len(response.Started)
+	response.Summary.Exited = len(response.Exited)
+	response.Summary.Reparented = len(response.Reparented)
+	response.Summary.Changed = len(response.Changed)
+
+	return response
+}
+
+// compareCounter records a counter change when its absolute delta reaches the
+// threshold. A zero threshold reports every change.
+func compareCounter(changes map[string]CounterChange, name string, from, to, threshold int64) {
+	delta := to - from
+	if delta == 0 {
+		return
+	}
+
+	magnitude := delta
+	if magnitude < 0 {
+		magnitude = -magnitude
+	}
+	if magnitude < threshold {
+		return
+	}
+
+	changes[name] = CounterChange{From: from, To: to, Delta: delta}
+}
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-api/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Rows buffered per Parquet row group. Bounds the memory of a Parquet
+// export, the only format that cannot write rows as they arrive.
+const parquetRowGroupSize = 10000
+
+// ProcessExportRow is one exported process, flattened for spreadsheets and
+// columnar stores. Every format has the same columns, in this order.
+type ProcessExportRow struct {
+	ID                     int64     `json:"id" parquet:"id"`
+	SnapshotID             int64     `json:"snapshotId" parquet:"snapshotId"`
+	CapturedAt             time.Time `json:"capturedAt" parquet:"capturedAt,timestamp"`
+	AgentID                *int64    `json:"agentId" parquet:"agentId"`
+	SnapshotType           string    `json:"snapshotType" parquet:"snapshotType,dict"`
+	ProcessID              int64     `json:"processId" parquet:"processId"`
+	ParentProcessID        int64     `json:"parentProcessId" parquet:"parentProcessId"`
+	ProcessName            string    `json:"processName" parquet:"processName,dict"`
+	ThreadCount            int32     `json:"threadCount" parquet:"threadCount"`
+	HandleCount            int32     `json:"handleCount" parquet:"handleCount"`
+	BasePriority           int32     `json:"basePriority" parquet:"basePriority"`
+	CreateTime             string    `json:"createTime" parquet:"createTime"`
+	UserTime               int32     `json:"userTime" parquet:"userTime"`
+	KernelTime             int32     `json:"kernelTime" parquet:"kernelTime"`
+	WorkingSetSize         int64     `json:"workingSetSize" parquet:"workingSetSize"`
+	PeakWorkingSetSize     int64     `json:"peakWorkingSetSize" parquet:"peakWorkingSetSize"`
+	VirtualSize            int64     `json:"virtualSize" parquet:"virtualSize"`
+	PeakVirtualSize        int64     `json:"peakVirtualSize" parquet:"peakVirtualSize"`
+	ReadOperationCount     int64     `json:"readOperationCount" parquet:"readOperationCount"`
+	WriteOperationCount    int64     `json:"writeOperationCount" parquet:"writeOperationCount"`
+	OtherOperationCount    int64     `json:"otherOperationCount" parquet:"otherOperationCount"`
+	ReadTransferCount      int64     `json:"readTransferCount" parquet:"readTransferCount"`
+	WriteTransferCount     int64     `json:"writeTransferCount" parquet:"writeTransferCount"`
+	OtherTransferCount     int64     `json:"otherTransferCount" parquet:"otherTransferCount"`
+	PageFaultCount         int64     `json:"pageFaultCount" parquet:"pageFaultCount"`
+	CurrentProcessAddress  string    `json:"currentProcessAddress" parquet:"currentProcessAddress"`
+	NextProcessAddress     *string   `json:"nextProcessAddress" parquet:"nextProcessAddress"`
+	NextProcessName        *string   `json:"nextProcessName" parquet:"nextProcessName"`
+	NextProcessID          *int64    `json:"nextProcessId" parquet:"nextProcessId"`
+	PreviousProcessAddress *string   `json:"previousProcessAddress" parquet:"previousProcessAddress"`
+	PreviousProcessName    *string   `json:"previousProcessName" parquet:"previousProcessName"`
+	PreviousProcessID      *int64    `json:"previousProcessId" parquet:"previousProcessId"`
+}
+
+var processExportColumns = []string{
+	"id", "snapshotId", "capturedAt", "agentId", "snapshotType",
+	"processId", "parentProcessId", "processName",
+	"threadCount", "handleCount", "basePriority", "createTime", "userTime", "kernelTime",
+	"workingSetSize", "peakWorkingSetSize", "virtualSize", "peakVirtualSize",
+	"readOperationCount", "writeOperationCount", "otherOperationCount",
+	"readTransferCount", "writeTransferCount", "otherTransferCount", "pageFaultCount",
+	"currentProcessAddress",
+	"nextProcessAddress", "nextProcessName", "nextProcessId",
+	"previousProcessAddress", "previousProcessName", "previousProcessId",
+}
+
+// exportFormat writes rows to an export file
+type exportFormat struct {
+	contentType string
+	extension   string
+	newWriter   func(w io.Writer) exportWriter
+}
+
+type exportWriter interface {
+	Write(row ProcessExportRow) error
+	Close() error
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv": {
+		contentType: "text/csv; charset=utf-8",
+		extension:   "csv",
+		newWriter:   newCSVExportWriter,
+	},
+	"ndjson": {
+		contentType: "application/x-ndjson",
+		extension:   "ndjson",
+		newWriter:   newNDJSONExportWriter,
+	},
+	"parquet": {
+		contentType: "application/vnd.apache.parquet",
+		extension:   "parquet",
+		newWriter:   newParquetExportWriter,
+	},
+}
+
+// Export the processes of a snapshot as ?format=csv (default), ndjson or
+// parquet
+func (h *ProcessHandler) ExportSnapshot(c *fiber.Ctx) error {
+	format, err := exportFormatOf(c)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := h.fetchAccessibleSnapshot(c, "id")
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("snapshot-%d.%s", snapshot.ID, format.extension)
+	return h.streamExport(c, format, filename, db.ExportProcessInfosParams{
+		SnapshotID: pgtype.Int8{Int64: snapshot.ID, Valid: true},
+	})
+}
+
+// Export the processes of every snapshot of the user created in a date
+// range. ?from= is required; ?to=, ?agent_id= and ?type= narrow the
+// snapshots.
+func (h *ProcessHandler) ExportSnapshots(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	format, err := exportFormatOf(c)
+	if err != nil {
+		return err
+	}
+
+	params := db.ExportProcessInfosParams{
+		UserID:         pgtype.Int8{Int64: userID, Valid: true},
+		IncludeUnowned: isAdmin(c),
+		SnapshotType:   queryText(c, "type"),
+	}
+	if params.AgentID, err = queryInt8(c, "agent_id"); err != nil {
+		return err
+	}
+	if params.CreatedFrom, err = queryTime(c, "from"); err != nil {
+		return err
+	}
+	if params.CreatedTo, err = queryTime(c, "to"); err != nil {
+		return err
+	}
+	if !params.CreatedFrom.Valid {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "from is required",
+		})
+	}
+
+	to := time.Now().UTC()
+	if params.CreatedTo.Valid {
+		to = params.CreatedTo.Time
+	}
+	filename := fmt.Sprintf("processes-%s-%s.%s",
+		params.CreatedFrom.Time.Format("20060102T150405Z"), to.Format("20060102T150405Z"), format.extension)
+
+	return h.streamExport(c, format, filename, params)
+}
+
+// streamExport sends the export as the response body, writing rows as the
+// query returns them. Once streaming has started the status can no longer
+// change, so a failure is logged and leaves a truncated file (a Parquet
+// file without its footer does not open).
+func (h *ProcessHandler) streamExport(c *fiber.Ctx, format exportFormat, filename string, params db.ExportProcessInfosParams) error {
+	c.Set(fiber.HeaderContentType, format.contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		writer := format.newWriter(w)
+		rows := 0
+
+		err := h.queries.ExportProcessInfos(context.Background(), params, func(row db.ExportProcessInfoRow) error {
+			rows++
+			return writer.Write(toProcessExportRow(row))
+		})
+		if err == nil {
+			err = writer.Close()
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			log.Warnf("export %s stopped after %d rows: %v", filename, rows, err)
+		}
+	})
+
+	return nil
+}
+
+func exportFormatOf(c *fiber.Ctx) (exportFormat, error) {
+	format, ok := exportFormats[c.Query("format", "csv")]
+	if !ok {
+		return exportFormat{}, fiber.NewError(fiber.StatusBadRequest, "format must be csv, ndjson or parquet")
+	}
+	return format, nil
+}
+
+func toProcessExportRow(row db.ExportProcessInfoRow) ProcessExportRow {
+	export := ProcessExportRow{
+		ID:                    row.ID,
+		SnapshotID:            row.SnapshotID,
+		CapturedAt:            row.CapturedAt.Time.Truncate(time.Second),
+		SnapshotType:          row.SnapshotType,
+		ProcessID:             row.ProcessID,
+		ParentProcessID:       row.ParentProcessID,
+		ProcessName:           row.ProcessName,
+		ThreadCount:           row.ThreadCount,
+		HandleCount:           row.HandleCount,
+		BasePriority:          row.BasePriority,
+		CreateTime:            row.CreateTime,
+		UserTime:              row.UserTime,
+		KernelTime:            row.KernelTime,
+		WorkingSetSize:        row.WorkingSetSize,
+		PeakWorkingSetSize:    row.PeakWorkingSetSize,
+		VirtualSize:           row.VirtualSize,
+		PeakVirtualSize:       row.PeakVirtualSize,
+		ReadOperationCount:    row.ReadOperationCount,
+		WriteOperationCount:   row.WriteOperationCount,
+		OtherOperationCount:   row.OtherOperationCount,
+		ReadTransferCount:     row.ReadTransferCount,
+		WriteTransferCount:    row.WriteTransferCount,
+		OtherTransferCount:    row.OtherTransferCount,
+		PageFaultCount:        row.PageFaultCount,
+		CurrentProcessAddress: row.CurrentProcessAddress,
+	}
+
+	if row.AgentID.Valid {
+		export.AgentID = &row.AgentID.Int64
+	}
+	if row.NextProcessEprocessAddress.Valid {
+		export.NextProcessAddress = &row.NextProcessEprocessAddress.String
+	}
+	if row.NextProcessName.Valid {
+		export.NextProcessName = &row.NextProcessName.String
+	}
+	if row.NextProcessID.Valid {
+		export.NextProcessID = &row.NextProcessID.Int64
+	}
+	if row.PreviousProcessEprocessAddress.Valid {
+		export.PreviousProcessAddress = &row.PreviousProcessEprocessAddress.String
+	}
+	if row.PreviousProcessName.Valid {
+		export.PreviousProcessName = &row.PreviousProcessName.String
+	}
+	if row.PreviousProcessID.Valid {
+		export.PreviousProcessID = &row.PreviousProcessID.Int64
+	}
+
+	return export
+}
+
+// csvExportWriter writes a header line and one record per row. Missing
+// values are empty fields and text reported by the agent goes through
+// csvText.
+type csvExportWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+func newCSVExportWriter(w io.Writer) exportWriter {
+	return &csvExportWriter{writer: csv.NewWriter(w)}
+}
+
+func (e *csvExportWriter) Write(row ProcessExportRow) error {
+	if !e.header {
+		if err := e.writer.Write(processExportColumns); err != nil {
+			return err
+		}
+		e.header = true
+	}
+
+	formatInt := func(value int64) string { return strconv.FormatInt(value, 10) }
+	optionalInt := func(value *int64) string {
+		if value == nil {
+			return ""
+		}
+		return formatInt(*value)
+	}
+	optionalString := func(value *string) string {
+		if value == nil {
+			return ""
+		}
+		return csvText(*value)
+	}
+
+	return e.writer.Write([]string{
+		formatInt(row.ID),
+		formatInt(row.SnapshotID),
+		row.CapturedAt.Format("2006-01-02T15:04:05Z07:00"),
+		optionalInt(row.AgentID),
+		row.SnapshotType,
+		formatInt(row.ProcessID),
+		formatInt(row.ParentProcessID),
+		csvText(row.ProcessName),
+		formatInt(int64(row.ThreadCount)),
+		formatInt(int64(row.HandleCount)),
+		formatInt(int64(row.BasePriority)),
+		csvText(row.CreateTime),
+		formatInt(int64(row.UserTime)),
+		formatInt(int64(row.KernelTime)),
+		formatInt(row.WorkingSetSize),
+		formatInt(row.PeakWorkingSetSize),
+		formatInt(row.VirtualSize),
+		formatInt(row.PeakVirtualSize),
+		formatInt(row.ReadOperationCount),
+		formatInt(row.WriteOperationCount),
+		formatInt(row.OtherOperationCount),
+		formatInt(row.ReadTransferCount),
+		formatInt(row.WriteTransferCount),
+		formatInt(row.OtherTransferCount),
+		formatInt(row.PageFaultCount),
+		csvText(row.CurrentProcessAddress),
+		optionalString(row.NextProcessAddress),
+		optionalString(row.NextProcessName),
+		optionalInt(row.NextProcessID),
+		optionalString(row.PreviousProcessAddress),
+		optionalString(row.PreviousProcessName),
+		optionalInt(row.PreviousProcessID),
+	})
+}
+
+// csvText keeps a text cell from being run as a formula when the file is
+// opened in a spreadsheet: cells starting with = + - @, a tab or a carriage
+// return get a leading '. So does a cell starting with ', which lets the
+// CSV import remove exactly one.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r'", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// Close writes the header of an empty export and flushes
+func (e *csvExportWriter) Close() error {
+	if !e.header {
+		if err := e.writer.Write(processExportColumns); err != nil {
+			return err
+		}
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// ndjsonExportWriter writes one JSON object per line. Missing values are
+// null.
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONExportWriter(w io.Writer) exportWriter {
+	return &ndjsonExportWriter{encoder: json.NewEncoder(w)}
+}
+
+func (e *ndjsonExportWriter) Write(row ProcessExportRow) error {
+	return e.encoder.Encode(row)
+}
+
+func (e *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+// parquetExportWriter writes Snappy-compressed row groups. Missing values
+// are nulls of optional columns.
+type parquetExportWriter struct {
+	writer *parquet.GenericWriter[ProcessExportRow]
+}
+
+func newParquetExportWriter(w io.Writer) exportWriter {
+	return &parquetExportWriter{
+		writer: parquet.NewGenericWriter[ProcessExportRow](w,
+			parquet.Compression(&parquet.Snappy),
+			parquet.MaxRowsPerRowGroup(parquetRowGroupSize),
+		),
+	}
+}
+
+func (e *parquetExportWriter) Write(row ProcessExportRow) error {
+	_, err := e.writer.Write([]ProcessExportRow{row})
+	return err
+}
+
+func (e *parquetExportWriter) Close() error {
+	return e.writer.Close()
+}
@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-api/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// How far in the future a capture time may be, for clock skew between the
+// air-gapped host and the API
+const importClockSkew = 5 * time.Minute
+
+// ImportSnapshot stores an offline capture, made by an agent on a host the
+// API cannot reach, as an iteration snapshot. The capture is uploaded as the
+// "file" field of a multipart form or as the raw request body, in one of the
+// formats of parseImport. host (where the capture was taken) and
+// captured_at (RFC 3339) are required form or query fields; agent_id
+// optionally ties the snapshot to a registered agent.
+func (h *WebhookHandler) ImportSnapshot(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	host := strings.TrimSpace(c.FormValue("host"))
+	if host == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "host is required",
+		})
+	}
+	if len(host) > 255 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "host must be at most 255 characters",
+		})
+	}
+
+	capturedAt, err := time.Parse(time.RFC3339, c.FormValue("captured_at"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "captured_at must be an RFC 3339 timestamp",
+		})
+	}
+	if capturedAt.After(time.Now().Add(importClockSkew)) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "captured_at is in the future",
+		})
+	}
+
+	var agentID pgtype.Int8
+	if raw := c.FormValue("agent_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "agent_id must be an integer",
+			})
+		}
+
+		agent, err := h.queries.GetAgent(c.Context(), id)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": "Agent not found",
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch agent",
+			})
+		}
+		if agent.OwnerID.Valid && agent.OwnerID.Int64 != userID {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Access denied",
+			})
+		}
+		agentID = pgtype.Int8{Int64: agent.ID, Valid: true}
+	}
+
+	body, filename, err := importUpload(c)
+	if err != nil {
+		return err
+	}
+
+	format := c.FormValue("format")
+	if format == "" {
+		format = importFormatOf(filename, c.Get(fiber.HeaderContentType))
+	}
+
+	processes, err := parseImport(format, body)
+	if err == nil {
+		err = validateImportedProcesses(processes)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	snapshot, err := h.persistSnapshot(c.Context(), userID, processes, func(qtx *db.Queries) (db.ProcessSnapshot, error) {
+		return qtx.CreateImportedSnapshot(c.Context(), db.CreateImportedSnapshotParams{
+			UserID:       pgtype.Int8{Int64: userID, Valid: true},
+			ProcessCount: int32(len(processes)),
+			AgentID:      agentID,
+			SourceHost:   pgtype.Text{String: host, Valid: true},
+			CapturedAt:   pgtype.Timestamp{Time: capturedAt.UTC(), Valid: true},
+		})
+	})
+	if err != nil {
+		log.Warnf("failed to import snapshot: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to persist snapshot",
+		})
+	}
+
+	// Imports get alerts but no rates: the previous snapshot of the agent,
+	// if any, was not taken just before this capture
+	h.evaluateAlertRules(c.Context(), snapshot, pgtype.Int8{})
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":      "Capture imported successfully",
+		"snapshotId":   snapshot.ID,
+		"processCount": len(processes),
+		"snapshot":     toSnapshotResponse(snapshot),
+	})
+}
+
+// importUpload returns the uploaded capture and its file name, if known
+func importUpload(c *fiber.Ctx) ([]byte, string, error) {
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		if len(c.Body()) == 0 {
+			return nil, "", fiber.NewError(fiber.StatusBadRequest, "capture is required")
+		}
+		return c.Body(), "", nil
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, "", fiber.NewError(fiber.StatusBadRequest, "file is required")
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return nil, "", fiber.NewError(fiber.StatusBadRequest, "Failed to read file")
+	}
+	defer file.Close()
+
+	body, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", fiber.NewError(fiber.StatusBadRequest, "Failed to read file")
+	}
+	return body, header.Filename, nil
+}
+
+// importFormatOf guesses the format of an upload from its file name, then
+// its content type. JSON is the default.
+func importFormatOf(filename, contentType string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".csv":
+		return "csv"
+	case ".json":
+		return "json"
+	}
+
+	switch {
+	case strings.HasPrefix(contentType, "application/x-ndjson"), strings.HasPrefix(contentType, "application/jsonl"):
+		return "ndjson"
+	case strings.HasPrefix(contentType, "text/csv"):
+		return "csv"
+	}
+	return "json"
+}
+
+// parseImport reads the processes of a capture in one of these formats:
+//
+//   - json: the agent's iterate-processes response
+//   - ndjson: one process per line, shaped like the processes of the
+//     agent's response
+//   - csv: a header line and one process per line, with the columns of a
+//     snapshot CSV export (columns describing the snapshot are ignored)
+func parseImport(format string, body []byte) ([]ProcessInfo, error) {
+	switch format {
+	case "json":
+		var capture struct {
+			Processes []ProcessInfo `json:"processes"`
+			Success   *bool         `json:"success"`
+		}
+		if err := json.Unmarshal(body, &capture); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		if capture.Success != nil && !*capture.Success {
+			return nil, errors.New("the capture reports success: false")
+		}
+		return capture.Processes, nil
+	case "ndjson":
+		return parseImportNDJSON(body)
+	case "csv":
+		return parseImportCSV(body)
+	default:
+		return nil, errors.New("format must be json, ndjson or csv")
+	}
+}
+
+func parseImportNDJSON(body []byte) ([]ProcessInfo, error) {
+	var processes []ProcessInfo
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var processInfo ProcessInfo
+		if err := json.Unmarshal(scanner.Bytes(), &processInfo); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %v", line, err)
+		}
+		processes = append(processes, processInfo)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid NDJSON: %v", err)
+	}
+
+	return processes, nil
+}
+
+// Columns of processExportColumns an imported CSV must have
+var importRequiredColumns = []string{
+	"processId", "parentProcessId", "processName",
+	"threadCount", "handleCount", "basePriority", "createTime", "userTime", "kernelTime",
+	"workingSetSize", "peakWorkingSetSize", "virtualSize", "peakVirtualSize",
+	"readOperationCount", "writeOperationCount", "otherOperationCount",
+	"readTransferCount", "writeTransferCount", "otherTransferCount", "pageFaultCount",
+	"currentProcessAddress",
+}
+
+func parseImportCSV(body []byte) ([]ProcessInfo, error) {
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range importRequiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV column %s is missing", name)
+		}
+	}
+
+	var processes []ProcessInfo
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+
+		row := csvRow{record: record, columns: columns}
+		processInfo := ProcessInfo{
+			ProcessID:             row.int64("processId"),
+			ParentProcessID:       row.int64("parentProcessId"),
+			ProcessName:           row.text("processName"),
+			ThreadCount:           row.int32("threadCount"),
+			HandleCount:           row.int32("handleCount"),
+			BasePriority:          row.int32("basePriority"),
+			CreateTime:            row.text("createTime"),
+			UserTime:              row.int32("userTime"),
+			KernelTime:            row.int32("kernelTime"),
+			WorkingSetSize:        row.int64("workingSetSize"),
+			PeakWorkingSetSize:    row.int64("peakWorkingSetSize"),
+			VirtualSize:           row.int64("virtualSize"),
+			PeakVirtualSize:       row.int64("peakVirtualSize"),
+			ReadOperationCount:    row.int64("readOperationCount"),
+			WriteOperationCount:   row.int64("writeOperationCount"),
+			OtherOperationCount:   row.int64("otherOperationCount"),
+			ReadTransferCount:     row.int64("readTransferCount"),
+			WriteTransferCount:    row.int64("writeTransferCount"),
+			OtherTransferCount:    row.int64("otherTransferCount"),
+			PageFaultCount:        row.int64("pageFaultCount"),
+			CurrentProcessAddress: row.text("currentProcessAddress"),
+			NextProcess:           row.adjacent("nextProcessAddress", "nextProcessName", "nextProcessId"),
+			PreviousProcess:       row.adjacent("previousProcessAddress", "previousProcessName", "previousProcessId"),
+		}
+		if row.err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, row.err)
+		}
+		processes = append(processes, processInfo)
+	}
+
+	return processes, nil
+}
+
+// csvRow reads the fields of one CSV record by column name, keeping the
+// first parse error
+type csvRow struct {
+	record  []string
+	columns map[string]int
+	err     error
+}
+
+// text returns a field without the ' the export puts in front of values a
+// spreadsheet would run as formulas (see csvText)
+func (r *csvRow) text(name string) string {
+	i, ok := r.columns[name]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(r.record[i], "'"))
+}
+
+func (r *csvRow) int64(name string) int64 {
+	return r.parseInt(name, 64)
+}
+
+func (r *csvRow) int32(name string) int32 {
+	return int32(r.parseInt(name, 32))
+}
+
+func (r *csvRow) parseInt(name string, bitSize int) int64 {
+	raw := r.text(name)
+	if raw == "" {
+		return 0
+	}
+	value, err := strconv.ParseInt(raw, 10, bitSize)
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("%s must be an integer", name)
+	}
+	return value
+}
+
+// adjacent reads a next/previous process, nil when its columns are empty
+func (r *csvRow) adjacent(address, name, id string) *AdjacentProcess {
+	if r.text(address) == "" && r.text(name) == "" && r.text(id) == "" {
+		return nil
+	}
+	return &AdjacentProcess{
+		EProcessAddress: r.text(address),
+		ProcessName:     r.text(name),
+		ProcessID:       r.int64(id),
+	}
+}
+
+// validateImportedProcesses checks what the agent always reports, since an
+// imported file may have been edited or truncated on the way
+func validateImportedProcesses(processes []ProcessInfo) error {
+	if len(processes) == 0 {
+		return errors.New("the capture has no processes")
+	}
+
+	for i, p := range processes {
+		var problem string
+		switch {
+		case p.ProcessName == "":
+			problem = "processName is required"
+		case p.CurrentProcessAddress == "":
+			problem = "currentProcessAddress is required"
+		case p.ProcessID < 0 || p.ParentProcessID < 0:
+			problem = "process IDs must not be negative"
+		case p.ThreadCount < 0 || p.HandleCount < 0 || p.UserTime < 0 || p.KernelTime < 0:
+			problem = "thread, handle and CPU time counters must not be negative"
+		case p.WorkingSetSize < 0 || p.PeakWorkingSetSize < 0 || p.VirtualSize < 0 || p.PeakVirtualSize < 0:
+			problem = "memory sizes must not be negative"
+		case p.ReadOperationCount < 0 || p.WriteOperationCount < 0 || p.OtherOperationCount < 0 ||
+			p.ReadTransferCount < 0 || p.WriteTransferCount < 0 || p.OtherTransferCount < 0 || p.PageFaultCount < 0:
+			problem = "I/O and page fault counters must not be negative"
+		}
+		if problem != "" {
+			return fmt.Errorf("process %d (%q): %s", i+1, p.ProcessName, problem)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-api/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Default window around the snapshot in which PID lookups are cross-checked
+// against the iteration walk
+const defaultCrossViewWindow = 15 * time.Minute
+
+// Link issue types reported by the integrity check
+const (
+	issueBrokenLink       = "broken_link"
+	issueGap              = "gap"
+	issueMissingLink      = "missing_link"
+	issueDuplicateAddress = "duplicate_address"
+)
+
+type LinkIssue struct {
+	Type            string `json:"type"`
+	Direction       string `json:"direction,omitempty"` // flink or blink
+	ProcessID       int64  `json:"processId"`
+	ProcessName     string `json:"processName"`
+	Address         string `json:"address"`
+	Target          string `json:"target,omitempty"`
+	TargetProcessID *int64 `json:"targetProcessId,omitempty"`
+	BackLink        string `json:"backLink,omitempty"`
+}
+
+type HiddenProcessCandidate struct {
+	QueryID         int64  `json:"queryId"`
+	QuerySnapshotID int64  `json:"querySnapshotId"`
+	QueriedAt       string `json:"queriedAt"`
+	ProcessInfoID   int64  `json:"processInfoId"` // the process returned by the PID lookup
+	ProcessID       int64  `json:"processId"`
+	ProcessName     string `json:"processName"`
+	CreateTime      string `json:"createTime"`
+	Address         string `json:"address"`
+	Flink           string `json:"flink,omitempty"`
+	Blink           string `json:"blink,omitempty"`
+	// Unlinked is set when the candidate's own neighbours are in the walk but
+	// no longer point back at it, the signature of a DKOM unlink
+	Unlinked bool `json:"unlinked"`
+}
+
+type IntegritySummary struct {
+	Entries          int `json:"entries"`
+	BrokenLinks      int `json:"brokenLinks"`
+	Gaps             int `json:"gaps"`
+	MissingLinks     int `json:"missingLinks"`
+	Duplicates       int `json:"duplicates"`
+	HiddenCandidates int `json:"hiddenCandidates"`
+}
+
+type SnapshotIntegrityResponse struct {
+	Snapshot         SnapshotResponse         `json:"snapshot"`
+	Consistent       bool                     `json:"consistent"`
+	ListHead         *string                  `json:"listHead,omitempty"`
+	CrossViewChecked bool                     `json:"crossViewChecked"`
+	Summary          IntegritySummary         `json:"summary"`
+	Issues           []LinkIssue              `json:"issues"`
+	HiddenCandidates []HiddenProcessCandidate `json:"hiddenCandidates"`
+}
+
+// Validate the EPROCESS linked list recorded in an iteration snapshot and
+// cross-check it against PID lookups made on the same agent
+func (h *ProcessHandler) GetSnapshotIntegrity(c *fiber.Ctx) error {
+	snapshot, err := h.fetchAccessibleSnapshot(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if snapshot.SnapshotType != "iteration" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Integrity checks are only available for iteration snapshots",
+		})
+	}
+
+	seconds := c.QueryInt("window", int(defaultCrossViewWindow.Seconds()))
+	if seconds < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "window must not be negative",
+		})
+	}
+	window := time.Duration(seconds) * time.Second
+
+	processes, err := h.queries.GetProcessInfosBySnapshot(c.Context(), snapshot.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch processes",
+		})
+	}
+
+	response := checkLinkedList(processes)
+	response.Snapshot = toSnapshotResponse(snapshot)
+
+	// Cross-view: processes the agent returned when asked by PID but that the
+	// list walk did not see. Legacy snapshots without an agent are skipped.
+	if snapshot.AgentID.Valid {
+		queried, err := h.queries.GetQueriedProcessesNearSnapshot(c.Context(), db.GetQueriedProcessesNearSnapshotParams{
+			AgentID:        snapshot.AgentID,
+			UserID:         snapshot.UserID,
+			IncludeUnowned: isAdmin(c),
+			FromTime:       pgtype.Timestamp{Time: snapshot.CreatedAt.Time.Add(-window), Valid: true},
+			ToTime:         pgtype.Timestamp{Time: snapshot.CreatedAt.Time.Add(window), Valid: true},
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch process queries",
+			})
+		}
+
+		response.HiddenCandidates = findHiddenCandidates(processes, queried, snapshot.CreatedAt.Time)
+		response.CrossViewChecked = true
+	}
+
+	response.Summary.HiddenCandidates = len(response.HiddenCandidates)
+	response.Consistent = len(response.Issues) == 0 && len(response.HiddenCandidates) == 0
+
+	return c.JSON(response)
+}
+
+// checkLinkedList verifies that every entry's Flink points at an entry whose
+// Blink points back, and vice versa. The walk is circular through
+// PsActiveProcessHead, which is not a process: exactly one dangling Flink and
+// one dangling Blink pointing at the same address are taken to be the head.
+func checkLinkedList(processes []db.ProcessInfo) SnapshotIntegrityResponse {
+	response := SnapshotIntegrityResponse{
+		Issues:           []LinkIssue{},
+		HiddenCandidates: []HiddenProcessCandidate{},
+	}
+	response.Summary.Entries = len(processes)
+
+	byAddress := make(map[string]db.ProcessInfo, len(processes))
+	for _, process := range processes {
+		address := normalizeAddress(process.CurrentProcessAddress)
+		if existing, ok := byAddress[address]; ok {
+			existingPID := existing.ProcessID
+			response.Issues = append(response.Issues, LinkIssue{
+				Type:            issueDuplicateAddress,
+				ProcessID:       process.ProcessID,
+				ProcessName:     process.ProcessName,
+				Address:         process.CurrentProcessAddress,
+				TargetProcessID: &existingPID,
+			})
+			continue
+		}
+		byAddress[address] = process
+	}
+
+	var dangling []LinkIssue
+	for _, process := range processes {
+		links := []struct {
+			direction string
+			target    pgtype.Text
+			backLink  func(db.ProcessInfo) pgtype.Text
+		}{
+			{"flink", process.NextProcessEprocessAddress, func(p db.ProcessInfo) pgtype.Text { return p.PreviousProcessEprocessAddress }},
+			{"blink", process.PreviousProcessEprocessAddress, func(p db.ProcessInfo) pgtype.Text { return p.NextProcessEprocessAddress }},
+		}
+
+		for _, link := range links {
+			issue := LinkIssue{
+				Direction:   link.direction,
+				ProcessID:   process.ProcessID,
+				ProcessName: process.ProcessName,
+				Address:     process.CurrentProcessAddress,
+			}
+
+			if !link.target.Valid || link.target.String == "" {
+				issue.Type = issueMissingLink
+				response.Issues = append(response.Issues, issue)
+				continue
+			}
+			issue.Target = link.target.String
+
+			neighbour, ok := byAddress[normalizeAddress(link.target.String)]
+			if !ok {
+				issue.Type = issueGap
+				dangling = append(dangling, issue)
+				continue
+			}
+
+			back := link.backLink(neighbour)
+			if !back.Valid || normalizeAddress(back.String) != normalizeAddress(process.CurrentProcessAddress) {
+				neighbourPID := neighbour.ProcessID
+				issue.Type = issueBrokenLink
+				issue.TargetProcessID = &neighbourPID
+				issue.BackLink = back.String
+				response.Issues = append(response.Issues, issue)
+			}
+		}
+	}
+
+	if head, ok := listHead(dangling); ok {
+		response.ListHead = &head
+	} else {
+		response.Issues = append(response.Issues, dangling...)
+	}
+
+	for _, issue := range response.Issues {
+		switch issue.Type {
+		case issueBrokenLink:
+			response.Summary.BrokenLinks++
+		case issueGap:
+			response.Summary.Gaps++
+		case issueMissingLink:
+			response.Summary.MissingLinks++
+		case issueDuplicateAddress:
+			response.Summary.Duplicates++
+		}
+	}
+
+	return response
+}
+
+// listHead reports the list head address when the dangling links are exactly
+// the last entry's Flink and the first entry's Blink, both pointing at it
+func listHead(dangling []LinkIssue) (string, bool) {
+	if len(dangling) != 2 || dangling[0].Direction == dangling[1].Direction {
+		return "", false
+	}
+	if normalizeAddress(dangling[0].Target) != normalizeAddress(dangling[1].Target) {
+		return "", false
+	}
+	return dangling[0].Target, true
+}
+
+// findHiddenCandidates returns processes found by PID lookups that are
+// absent from the walk. A walked process with the same PID but another
+// create time does not count, since an unlinked process may reuse a live
+// PID. Processes created after the snapshot are ignored since the walk could
+// not have seen them.
+func findHiddenCandidates(processes []db.ProcessInfo, queried []db.GetQueriedProcessesNearSnapshotRow, snapshotTime time.Time) []HiddenProcessCandidate {
+	walked := make(map[processKey]bool, len(processes))
+	byAddress := make(map[string]db.ProcessInfo, len(processes))
+	for _, process := range processes {
+		walked[keyOf(process)] = true
+		byAddress[normalizeAddress(process.CurrentProcessAddress)] = process
+	}
+
+	candidates := []HiddenProcessCandidate{}
+	seen := make(map[processKey]bool)
+	for _, row := range queried {
+		key := processKey{ProcessID: row.ProcessID, CreateTime: row.CreateTime}
+		if walked[key] || seen[key] {
+			continue
+		}
+		if created, ok := parseCreateTime(row.CreateTime); ok && created.After(snapshotTime) {
+			continue
+		}
+		seen[key] = true
+
+		candidate := HiddenProcessCandidate{
+			QueryID:         row.QueryID,
+			QuerySnapshotID: row.SnapshotID,
+			QueriedAt:       row.QueriedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+			ProcessInfoID:   row.ProcessInfoID,
+			ProcessID:       row.ProcessID,
+			ProcessName:     row.ProcessName,
+			CreateTime:      row.CreateTime,
+			Address:         row.CurrentProcessAddress,
+			Flink:           row.NextProcessEprocessAddress.String,
+			Blink:           row.PreviousProcessEprocessAddress.String,
+		}
+
+		address := normalizeAddress(row.CurrentProcessAddress)
+		if next, ok := byAddress[normalizeAddress(row.NextProcessEprocessAddress.String)]; ok && row.NextProcessEprocessAddress.Valid {
+			candidate.Unlinked = normalizeAddress(next.PreviousProcessEprocessAddress.String) != address
+		}
+		if previous, ok := byAddress[normalizeAddress(row.PreviousProcessEprocessAddress.String)]; ok && row.PreviousProcessEprocessAddress.Valid {
+			candidate.Unlinked = candidate.Unlinked || normalizeAddress(previous.NextProcessEprocessAddress.String) != address
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].ProcessID < candidates[j].ProcessID })
+	return candidates
+}
+
+// normalizeAddress makes kernel addresses comparable regardless of case,
+// 0x prefix or zero padding
+func normalizeAddress(address string) string {
+	address = strings.ToLower(strings.TrimSpace(address))
+	address = strings.TrimPrefix(address, "0x")
+	address = strings.TrimLeft(address, "0")
+	if address == "" {
+		return "0"
+	}
+	return address
+}
+
+// parseCreateTime reads a process create time as reported by the agent,
+// either a Windows FILETIME (100ns intervals since 1601) or RFC 3339
+func parseCreateTime(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if filetime, err := strconv.ParseInt(value, 10, 64); err == nil {
+		// 116444736000000000 is the FILETIME of the Unix epoch
+		const epochDelta = 116444736000000000
+		if filetime <= epochDelta || filetime-epochDelta > math.MaxInt64/100 {
+			return time.Time{}, false
+		}
+		return time.Unix(0, (filetime-epochDelta)*100).UTC(), true
+	}
+
+	if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return parsed.UTC(), true
+	}
+
+	return time.Time{}, false
+}
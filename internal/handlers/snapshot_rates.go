@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ProcessRatesResponse holds per-second rates derived from the counters of
+// two consecutive iteration snapshots of the same agent. Rates are null when
+// the counter went backwards between the captures.
+type ProcessRatesResponse struct {
+	IntervalSeconds    float64  `json:"intervalSeconds"`
+	CPUPercent         *float64 `json:"cpuPercent"` // share of one CPU, may exceed 100
+	ReadBytesPerSec    *float64 `json:"readBytesPerSec"`
+	WriteBytesPerSec   *float64 `json:"writeBytesPerSec"`
+	OtherBytesPerSec   *float64 `json:"otherBytesPerSec"`
+	ReadOpsPerSec      *float64 `json:"readOpsPerSec"`
+	WriteOpsPerSec     *float64 `json:"writeOpsPerSec"`
+	PageFaultsPerSec   *float64 `json:"pageFaultsPerSec"`
+	HandleGrowthPerSec float64  `json:"handleGrowthPerSec"`
+}
+
+type SnapshotProcessRatesResponse struct {
+	ProcessInfoID         int64  `json:"processInfoId"`
+	PreviousProcessInfoID *int64 `json:"previousProcessInfoId,omitempty"`
+	PreviousSnapshotID    *int64 `json:"previousSnapshotId,omitempty"`
+	ProcessID             int64  `json:"processId"`
+	ProcessName           string `json:"processName"`
+	ProcessRatesResponse
+}
+
+// Get the derived rates of the processes of a snapshot, busiest CPU first.
+// Only processes also seen in the previous iteration of the agent have rates.
+func (h *ProcessHandler) GetSnapshotRates(c *fiber.Ctx) error {
+	snapshot, err := h.fetchAccessibleSnapshot(c, "id")
+	if err != nil {
+		return err
+	}
+
+	rates, err := h.queries.GetProcessRatesBySnapshot(c.Context(), snapshot.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch process rates",
+		})
+	}
+
+	response := make([]SnapshotProcessRatesResponse, len(rates))
+	for i, rate := range rates {
+		response[i] = SnapshotProcessRatesResponse{
+			ProcessInfoID: rate.ProcessInfoID,
+			ProcessID:     rate.ProcessID,
+			ProcessName:   rate.ProcessName,
+			ProcessRatesResponse: ProcessRatesResponse{
+				IntervalSeconds:    rate.IntervalSeconds,
+				CPUPercent:         float8Ptr(rate.CpuPercent),
+				ReadBytesPerSec:    float8Ptr(rate.ReadBytesPerSec),
+				WriteBytesPerSec:   float8Ptr(rate.WriteBytesPerSec),
+				OtherBytesPerSec:   float8Ptr(rate.OtherBytesPerSec),
+				ReadOpsPerSec:      float8Ptr(rate.ReadOpsPerSec),
+				WriteOpsPerSec:     float8Ptr(rate.WriteOpsPerSec),
+				PageFaultsPerSec:   float8Ptr(rate.PageFaultsPerSec),
+				HandleGrowthPerSec: rate.HandleGrowthPerSec,
+			},
+		}
+		if rate.PreviousProcessInfoID.Valid {
+			response[i].PreviousProcessInfoID = &rate.PreviousProcessInfoID.Int64
+		}
+		if rate.PreviousSnapshotID.Valid {
+			response[i].PreviousSnapshotID = &rate.PreviousSnapshotID.Int64
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"snapshot": toSnapshotResponse(snapshot),
+		"rates":    response,
+		"count":    len(response),
+	})
+}
+
+func float8Ptr(value pgtype.Float8) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-api/internal/db"
+	"go-api/internal/stix"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Export the suspicious findings of a snapshot as a STIX 2.1 bundle for
+// incident handoff: the alerts raised on it and, for iteration snapshots,
+// the EPROCESS list issues and hidden process candidates of the integrity
+// check. Every finding is an indicator based on an observed-data object
+// holding the flagged process, its image and, when in the snapshot, its
+// parent. The snapshot and its agent are external references of both.
+func (h *ProcessHandler) GetSnapshotSTIX(c *fiber.Ctx) error {
+	snapshot, err := h.fetchAccessibleSnapshot(c, "id")
+	if err != nil {
+		return err
+	}
+
+	processes, err := h.queries.GetProcessInfosBySnapshot(c.Context(), snapshot.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch processes",
+		})
+	}
+
+	alerts, err := h.queries.GetAlertsBySnapshot(c.Context(), snapshot.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch alerts",
+		})
+	}
+
+	var agent *db.Agent
+	if snapshot.AgentID.Valid {
+		if found, err := h.queries.GetAgent(c.Context(), snapshot.AgentID.Int64); err == nil {
+			agent = &found
+		}
+	}
+
+	export := newSTIXExport(snapshot, agent, processes)
+
+	var integrity *alertEvaluator
+	if snapshot.SnapshotType == "iteration" {
+		integrity = &alertEvaluator{queries: h.queries, snapshot: snapshot}
+		if err := integrity.checkIntegrity(c.Context()); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to check snapshot integrity",
+			})
+		}
+		export.hidden = integrity.integrity.HiddenCandidates
+	}
+
+	// Alerts first, so findings an integrity rule already raised are
+	// exported once, as the alert
+	for _, alert := range alerts {
+		export.addAlert(alert)
+	}
+	if integrity != nil {
+		for _, issue := range integrity.integrity.Issues {
+			export.addLinkIssue(issue, integrity.byAddress)
+		}
+		for _, candidate := range integrity.integrity.HiddenCandidates {
+			export.addHiddenCandidate(candidate)
+		}
+	}
+
+	body, err := json.Marshal(export.bundle())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to encode bundle",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, stix.MediaType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="snapshot-%d.stix.json"`, snapshot.ID))
+	return c.Send(body)
+}
+
+// Creation time of the identity of the API. The identity has the same ID in
+// every bundle, so it must not change between exports.
+var identityCreated = time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+// stixExport collects the objects of the bundle of one snapshot
+type stixExport struct {
+	snapshot   db.ProcessSnapshot
+	observedAt time.Time
+	identity   stix.Identity
+	references []stix.ExternalReference // the snapshot and its agent
+
+	byID     map[int64]db.ProcessInfo
+	byPID    map[int64]db.ProcessInfo
+	hidden   []HiddenProcessCandidate
+	reported map[int64]bool // hidden candidates an alert reports, by ProcessInfoID
+
+	observables  []any
+	refs         map[string][]string // process ID -> the objects an observation of it holds
+	findings     []stixFinding
+	descriptions map[string]bool // of the findings, to skip link issues an alert already reports
+}
+
+// stixFinding is an indicator and the process object it was raised on
+type stixFinding struct {
+	indicator  stix.Indicator
+	processRef string
+}
+
+func newSTIXExport(snapshot db.ProcessSnapshot, agent *db.Agent, processes []db.ProcessInfo) *stixExport {
+	observedAt := snapshot.CreatedAt.Time
+
+	identity := stix.Identity{
+		Common:        stix.NewCommon("identity", stix.ID("identity", "api"), identityCreated),
+		Name:          "go-api",
+		IdentityClass: "system",
+	}
+
+	references := []stix.ExternalReference{{
+		SourceName:  "go-api",
+		ExternalID:  fmt.Sprintf("snapshot:%d", snapshot.ID),
+		Description: fmt.Sprintf("%s snapshot %d", snapshot.SnapshotType, snapshot.ID),
+	}}
+	if snapshot.AgentID.Valid {
+		reference := stix.ExternalReference{
+			SourceName: "go-api",
+			ExternalID: fmt.Sprintf("agent:%d", snapshot.AgentID.Int64),
+		}
+		if agent != nil {
+			reference.Description = "agent " + agent.Name
+		}
+		references = append(references, reference)
+	}
+	if snapshot.SourceHost.Valid {
+		references = append(references, stix.ExternalReference{
+			SourceName:  "go-api",
+			ExternalID:  "host:" + snapshot.SourceHost.String,
+			Description: "offline capture imported from host " + snapshot.SourceHost.String,
+		})
+	}
+
+	e := &stixExport{
+		snapshot:     snapshot,
+		observedAt:   observedAt,
+		identity:     identity,
+		references:   references,
+		byID:         make(map[int64]db.ProcessInfo, len(processes)),
+		byPID:        make(map[int64]db.ProcessInfo, len(processes)),
+		reported:     make(map[int64]bool),
+		refs:         make(map[string][]string),
+		descriptions: make(map[string]bool),
+	}
+	for _, process := range processes {
+		e.byID[process.ID] = process
+		if _, ok := e.byPID[process.ProcessID]; !ok {
+			e.byPID[process.ProcessID] = process
+		}
+	}
+	return e
+}
+
+func (e *stixExport) addAlert(alert db.Alert) {
+	if !alert.ProcessID.Valid {
+		return
+	}
+
+	var ref string
+	indicatorType := "anomalous-activity"
+	candidate, hidden := e.hiddenCandidateOf(alert)
+	switch process, ok := e.byID[alert.ProcessInfoID.Int64]; {
+	case alert.ProcessInfoID.Valid && ok:
+		ref = e.addProcess(process, true)
+	case hidden:
+		e.reported[candidate.ProcessInfoID] = true
+		ref = e.addHiddenObservable(candidate)
+		indicatorType = "malicious-activity"
+	default:
+		// Integrity alerts on processes outside the walk keep only the PID
+		// and name
+		ref = e.addObservable(stix.ID("process", fmt.Sprintf("alert/%d", alert.ID)), false, alert.ProcessID.Int64, alert.ProcessName.String, "", "")
+	}
+
+	e.addIndicator(stix.ID("indicator", fmt.Sprintf("alert/%d", alert.ID)), alert.CreatedAt.Time, stix.Indicator{
+		Name:           alert.RuleName,
+		Description:    alert.Message,
+		IndicatorTypes: []string{indicatorType},
+		Pattern:        processPattern(alert.ProcessID.Int64, alert.ProcessName.String, hidden),
+	}, []string{"alert", alert.Severity}, ref, stix.ExternalReference{
+		SourceName: "go-api",
+		ExternalID: fmt.Sprintf("alert:%d", alert.ID),
+	})
+}
+
+// hiddenCandidateOf returns the hidden process candidate an alert was raised
+// on, matched on the PID lookup row the alert points at. Alerts recorded
+// before alerts pointed at it are matched on PID and name.
+func (e *stixExport) hiddenCandidateOf(alert db.Alert) (HiddenProcessCandidate, bool) {
+	for _, candidate := range e.hidden {
+		if alert.ProcessInfoID.Valid {
+			if candidate.ProcessInfoID == alert.ProcessInfoID.Int64 {
+				return candidate, true
+			}
+		} else if candidate.ProcessID == alert.ProcessID.Int64 && candidate.ProcessName == alert.ProcessName.String {
+			return candidate, true
+		}
+	}
+	return HiddenProcessCandidate{}, false
+}
+
+func (e *stixExport) addLinkIssue(issue LinkIssue, byAddress map[string]db.ProcessInfo) {
+	if e.descriptions[describeLinkIssue(issue)] {
+		return
+	}
+
+	var ref string
+	if process, ok := byAddress[normalizeAddress(issue.Address)]; ok {
+		ref = e.addProcess(process, true)
+	} else {
+		ref = e.addObservable(stix.ID("process", fmt.Sprintf("walk/%d/%s", e.snapshot.ID, normalizeAddress(issue.Address))), false, issue.ProcessID, issue.ProcessName, "", "")
+	}
+
+	key := fmt.Sprintf("integrity/%d/%s/%s/%s", e.snapshot.ID, issue.Type, issue.Direction, normalizeAddress(issue.Address))
+	e.addIndicator(stix.ID("indicator", key), e.observedAt, stix.Indicator{
+		Name:           "EPROCESS list " + issue.Type,
+		Description:    describeLinkIssue(issue),
+		IndicatorTypes: []string{"anomalous-activity"},
+		Pattern:        processPattern(issue.ProcessID, issue.ProcessName, false),
+	}, []string{"integrity", issue.Type}, ref)
+}
+
+func (e *stixExport) addHiddenCandidate(candidate HiddenProcessCandidate) {
+	if e.reported[candidate.ProcessInfoID] {
+		return
+	}
+
+	ref := e.addHiddenObservable(candidate)
+
+	key := fmt.Sprintf("integrity/%d/%s/%d/%s", e.snapshot.ID, issueHiddenProcess, candidate.QuerySnapshotID, normalizeAddress(candidate.Address))
+	e.addIndicator(stix.ID("indicator", key), e.observedAt, stix.Indicator{
+		Name:           "Hidden process " + candidate.ProcessName,
+		Description:    describeHiddenCandidate(candidate),
+		IndicatorTypes: []string{"malicious-activity"},
+		Pattern:        processPattern(candidate.ProcessID, candidate.ProcessName, true),
+	}, []string{"integrity", issueHiddenProcess}, ref, stix.ExternalReference{
+		SourceName:  "go-api",
+		ExternalID:  fmt.Sprintf("snapshot:%d", candidate.QuerySnapshotID),
+		Description: fmt.Sprintf("query snapshot of PID lookup %d", candidate.QueryID),
+	})
+}
+
+// addProcess adds the process object of a row of the snapshot and, when
+// withParent is set and the parent is in the snapshot, its parent
+func (e *stixExport) addProcess(process db.ProcessInfo, withParent bool) string {
+	var parentRef string
+	if withParent {
+		if parent, ok := e.byPID[process.ParentProcessID]; ok && parent.ID != process.ID {
+			parentRef = e.addProcess(parent, false)
+		}
+	}
+	return e.addObservable(stix.ID("process", strconv.FormatInt(process.ID, 10)), false,
+		process.ProcessID, process.ProcessName, process.CreateTime, parentRef)
+}
+
+// addHiddenObservable adds the process object of a hidden process candidate
+func (e *stixExport) addHiddenObservable(candidate HiddenProcessCandidate) string {
+	id := stix.ID("process", fmt.Sprintf("hidden/%d/%s", candidate.QuerySnapshotID, normalizeAddress(candidate.Address)))
+	return e.addObservable(id, true, candidate.ProcessID, candidate.ProcessName, candidate.CreateTime, "")
+}
+
+// addObservable adds a process object, with a file object for its image
+// name, unless it was already added. It returns the ID of the process.
+func (e *stixExport) addObservable(id string, hidden bool, pid int64, name, createTime, parentRef string) string {
+	if _, ok := e.refs[id]; ok {
+		return id
+	}
+	refs := []string{id}
+
+	process := stix.Process{
+		Type:        "process",
+		SpecVersion: stix.SpecVersion,
+		ID:          id,
+		IsHidden:    hidden,
+		PID:         pid,
+		ParentRef:   parentRef,
+	}
+	if created, ok := parseCreateTime(createTime); ok {
+		timestamp := stix.Timestamp(created)
+		process.CreatedTime = &timestamp
+	}
+	if name != "" {
+		image := stix.NewFile(name)
+		process.ImageRef = image.ID
+		refs = append(refs, image.ID)
+		if _, ok := e.refs[image.ID]; !ok {
+			e.refs[image.ID] = nil
+			e.observables = append(e.observables, image)
+		}
+	}
+	if parentRef != "" {
+		refs = append(refs, e.refs[parentRef]...)
+	}
+
+	e.refs[id] = refs
+	e.observables = append(e.observables, process)
+	return id
+}
+
+// addIndicator adds a finding about the process object ref
+func (e *stixExport) addIndicator(id string, created time.Time, indicator stix.Indicator, labels []string, ref string, references ...stix.ExternalReference) {
+	e.descriptions[indicator.Description] = true
+
+	indicator.Common = stix.NewCommon("indicator", id, created)
+	indicator.CreatedByRef = e.identity.ID
+	indicator.Labels = labels
+	indicator.ExternalReferences = append(append([]stix.ExternalReference{}, e.references...), references...)
+	indicator.PatternType = "stix"
+	indicator.ValidFrom = stix.Timestamp(e.observedAt)
+	e.findings = append(e.findings, stixFinding{indicator: indicator, processRef: ref})
+}
+
+// bundle assembles the bundle: the identity of the API, the process and
+// file objects, then per finding its observed-data, indicator and the
+// based-on relationship between them
+func (e *stixExport) bundle() *stix.Bundle {
+	bundle := stix.NewBundle()
+	bundle.Add(e.identity)
+	bundle.Add(e.observables...)
+
+	for _, finding := range e.findings {
+		indicator := finding.indicator
+
+		observed := stix.ObservedData{
+			Common:         stix.NewCommon("observed-data", stix.ID("observed-data", indicator.ID), e.observedAt),
+			FirstObserved:  stix.Timestamp(e.observedAt),
+			LastObserved:   stix.Timestamp(e.observedAt),
+			NumberObserved: 1,
+			ObjectRefs:     e.refs[finding.processRef],
+		}
+		observed.CreatedByRef = e.identity.ID
+		observed.ExternalReferences = e.references
+
+		relationship := stix.Relationship{
+			Common:           stix.NewCommon("relationship", stix.ID("relationship", indicator.ID+"/based-on"), time.Time(indicator.Created)),
+			RelationshipType: "based-on",
+			SourceRef:        indicator.ID,
+			TargetRef:        observed.ID,
+		}
+		relationship.CreatedByRef = e.identity.ID
+
+		bundle.Add(observed, indicator, relationship)
+	}
+
+	return bundle
+}
+
+// processPattern matches a process by PID and image name
+func processPattern(pid int64, name string, hidden bool) string {
+	pattern := fmt.Sprintf("process:pid = %d", pid)
+	if name != "" {
+		pattern += " AND process:image_ref.name = " + stix.Quote(name)
+	}
+	if hidden {
+		pattern += " AND process:is_hidden = true"
+	}
+	return "[" + pattern + "]"
+}
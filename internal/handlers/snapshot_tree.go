@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"go-api/internal/db"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Reasons a process is reported as an orphan instead of under its parent
+const (
+	orphanParentExited = "parent_exited"
+	orphanParentReused = "parent_pid_reused"
+	orphanCycle        = "cycle"
+)
+
+type ProcessTreeNode struct {
+	ID              int64              `json:"id"`
+	ProcessID       int64              `json:"processId"`
+	ParentProcessID int64              `json:"parentProcessId"`
+	ProcessName     string             `json:"processName"`
+	CreateTime      string             `json:"createTime"`
+	ThreadCount     int32              `json:"threadCount"`
+	WorkingSetSize  int64              `json:"workingSetSize"`
+	OrphanReason    string             `json:"orphanReason,omitempty"`
+	Children        []*ProcessTreeNode `json:"children"`
+}
+
+type ProcessTreeResponse struct {
+	Snapshot SnapshotResponse   `json:"snapshot"`
+	Roots    []*ProcessTreeNode `json:"roots"`
+	Orphans  []*ProcessTreeNode `json:"orphans"`
+	Count    int                `json:"count"`
+}
+
+// Get the parent/child tree of the processes in a snapshot
+func (h *ProcessHandler) GetSnapshotTree(c *fiber.Ctx) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "text" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid format. Must be 'json' or 'text'",
+		})
+	}
+
+	snapshot, err := h.fetchAccessibleSnapshot(c, "id")
+	if err != nil {
+		return err
+	}
+
+	processes, err := h.queries.GetProcessInfosBySnapshot(c.Context(), snapshot.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch processes",
+		})
+	}
+
+	roots, orphans := buildProcessTree(processes)
+
+	if format == "text" {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.SendString(renderProcessTree(roots, orphans))
+	}
+
+	return c.JSON(ProcessTreeResponse{
+		Snapshot: toSnapshotResponse(snapshot),
+		Roots:    roots,
+		Orphans:  orphans,
+		Count:    len(processes),
+	})
+}
+
+// buildProcessTree links each process to its parent. A process whose parent
+// PID is not in the snapshot, or belongs to a process created after it (the
+// PID was reused), is returned as an orphan rather than attached to the
+// wrong parent.
+func buildProcessTree(processes []db.ProcessInfo) ([]*ProcessTreeNode, []*ProcessTreeNode) {
+	nodes := make([]*ProcessTreeNode, len(processes))
+	byPID := make(map[int64][]int, len(processes))
+	for i, process := range processes {
+		nodes[i] = &ProcessTreeNode{
+			ID:              process.ID,
+			ProcessID:       process.ProcessID,
+			ParentProcessID: process.ParentProcessID,
+			ProcessName:     process.ProcessName,
+			CreateTime:      process.CreateTime,
+			ThreadCount:     process.ThreadCount,
+			WorkingSetSize:  process.WorkingSetSize,
+			Children:        []*ProcessTreeNode{},
+		}
+		byPID[process.ProcessID] = append(byPID[process.ProcessID], i)
+	}
+
+	parent := make([]int, len(processes))
+	roots := []*ProcessTreeNode{}
+	orphans := []*ProcessTreeNode{}
+	for i, process := range processes {
+		parent[i] = -1
+
+		// PID 0 (Idle) and System have no real parent
+		if process.ParentProcessID == 0 || process.ParentProcessID == process.ProcessID {
+			roots = append(roots, nodes[i])
+			continue
+		}
+
+		candidates := byPID[process.ParentProcessID]
+		if len(candidates) == 0 {
+			nodes[i].OrphanReason = orphanParentExited
+			orphans = append(orphans, nodes[i])
+			continue
+		}
+
+		parent[i] = pickParent(processes, candidates, process)
+		if parent[i] < 0 {
+			nodes[i].OrphanReason = orphanParentReused
+			orphans = append(orphans, nodes[i])
+		}
+	}
+
+	for i := range processes {
+		if parent[i] >= 0 {
+			nodes[parent[i]].Children = append(nodes[parent[i]].Children, nodes[i])
+		}
+	}
+
+	// Without usable create times PID reuse can form a cycle that is not
+	// reachable from any root; break it at one member and report it as an
+	// orphan so every process appears exactly once. An unreached process may
+	// only hang off the cycle, so follow its parents until one repeats to
+	// find a member.
+	reached := make(map[*ProcessTreeNode]bool, len(nodes))
+	for _, node := range append(append([]*ProcessTreeNode{}, roots...), orphans...) {
+		markReached(node, reached)
+	}
+	for i, node := range nodes {
+		if reached[node] {
+			continue
+		}
+		visited := make(map[int]bool)
+		member := i
+		for !visited[member] {
+			visited[member] = true
+			member = parent[member]
+		}
+
+		cut := nodes[member]
+		p := nodes[parent[member]]
+		for j, child := range p.Children {
+			if child == cut {
+				p.Children = append(p.Children[:j], p.Children[j+1:]...)
+				break
+			}
+		}
+		cut.OrphanReason = orphanCycle
+		orphans = append(orphans, cut)
+		markReached(cut, reached)
+	}
+
+	sortProcessTree(roots)
+	sortProcessTree(orphans)
+	return roots, orphans
+}
+
+// pickParent returns the index of the candidate that can be the parent of
+// child, or -1 when every candidate was created after it
+func pickParent(processes []db.ProcessInfo, candidates []int, child db.ProcessInfo) int {
+	childCreated, childOK := parseCreateTime(child.CreateTime)
+	for _, candidate := range candidates {
+		if processes[candidate].ProcessID == child.ProcessID && processes[candidate].CreateTime == child.CreateTime {
+			continue
+		}
+		parentCreated, parentOK := parseCreateTime(processes[candidate].CreateTime)
+		if !childOK || !parentOK || !parentCreated.After(childCreated) {
+			return candidate
+		}
+	}
+	return -1
+}
+
+func markReached(node *ProcessTreeNode, reached map[*ProcessTreeNode]bool) {
+	if reached[node] {
+		return
+	}
+	reached[node] = true
+	for _, child := range node.Children {
+		markReached(child, reached)
+	}
+}
+
+func sortProcessTree(nodes []*ProcessTreeNode) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ProcessID < nodes[j].ProcessID })
+	for _, node := range nodes {
+		sortProcessTree(node.Children)
+	}
+}
+
+// renderProcessTree draws the tree in pstree style for terminal use
+func renderProcessTree(roots, orphans []*ProcessTreeNode) string {
+	var b strings.Builder
+	for _, root := range roots {
+		writeTreeNode(&b, root, "", "", "")
+	}
+
+	if len(orphans) > 0 {
+		b.WriteString("\nOrphans:\n")
+		for _, orphan := range orphans {
+			note := fmt.Sprintf(" [parent %d: %s]", orphan.ParentProcessID, strings.ReplaceAll(orphan.OrphanReason, "_", " "))
+			writeTreeNode(&b, orphan, "", "", note)
+		}
+	}
+
+	return b.String()
+}
+
+func writeTreeNode(b *strings.Builder, node *ProcessTreeNode, prefix, branch, note string) {
+	fmt.Fprintf(b, "%s%s%s (%d)%s\n", prefix, branch, node.ProcessName, node.ProcessID, note)
+
+	childPrefix := prefix
+	switch branch {
+	case "├─ ":
+		childPrefix += "│  "
+	case "└─ ":
+		childPrefix += "   "
+	}
+
+	for i, child := range node.Children {
+		childBranch := "├─ "
+		if i == len(node.Children)-1 {
+			childBranch = "└─ "
+		}
+		writeTreeNode(b, child, childPrefix, childBranch, "")
+	}
+}
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-api/internal/db"
+	"go-api/internal/stream"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// How often an idle stream is pinged so proxies keep it open
+const streamKeepAlive = 15 * time.Second
+
+// Longest error message of a compacted stream event; clients fetch the row
+// for the rest
+const streamErrorLength = 300
+
+type StreamHandler struct {
+	queries *db.Queries
+	hub     *stream.Hub
+}
+
+func NewStreamHandler(dbpool *pgxpool.Pool, hub *stream.Hub) *StreamHandler {
+	return &StreamHandler{
+		queries: db.New(dbpool),
+		hub:     hub,
+	}
+}
+
+// streamAuth is what ends a stream: the expiry of the token or API key that
+// opened it and, for API keys, a check that the key is still active
+type streamAuth struct {
+	expiresAt time.Time
+	revoked   func() bool
+}
+
+// StreamTokenFromQuery lets browsers, whose EventSource and WebSocket APIs
+// cannot set headers, pass the JWT in ?access_token=. It must run before
+// JWTMiddleware and only on stream routes.
+func StreamTokenFromQuery(c *fiber.Ctx) error {
+	if token := c.Query("access_token"); token != "" && c.Get("Authorization") == "" {
+		c.Request().Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.Next()
+}
+
+// Stream the events of the caller's data as they happen: snapshots created,
+// processes persisted, capture jobs changing state and alerts raised.
+// WebSocket upgrade requests get a WebSocket, anything else Server-Sent
+// Events. ?types= (comma-separated) and ?agent_id= narrow the events.
+func (h *StreamHandler) Stream(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int64)
+
+	var filter stream.Filter
+	if types := c.Query("types"); types != "" {
+		filter.Types = strings.Split(types, ",")
+		if err := stream.ValidateTypes(filter.Types); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	agentID, err := queryInt8(c, "agent_id")
+	if err != nil {
+		return err
+	}
+	filter.AgentID = agentID
+
+	// A stream outlives the request that authenticated it, so it ends when
+	// the access token or API key expires. An API key is checked again on
+	// every keep-alive so that deleting it also ends its streams.
+	var auth streamAuth
+	if claims, ok := c.Locals("tokenClaims").(*Claims); ok && claims.ExpiresAt != nil {
+		auth.expiresAt = claims.ExpiresAt.Time
+	}
+	if keyID, ok := c.Locals("apiKeyID").(int64); ok {
+		key, err := h.queries.GetActiveApiKey(c.Context(), keyID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": errInvalidAPIKey.Error(),
+			})
+		}
+		if key.ExpiresAt.Valid {
+			auth.expiresAt = key.ExpiresAt.Time
+		}
+		auth.revoked = func() bool { return h.apiKeyRevoked(keyID) }
+	}
+
+	if websocket.IsWebSocketUpgrade(c) {
+		return websocket.New(func(conn *websocket.Conn) {
+			h.serveWebSocket(conn, userID, filter, auth)
+		})(c)
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	// Subscribe before the handler returns so no event is missed between
+	// the response headers and the first read
+	subscription := h.hub.Subscribe(userID, filter)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer subscription.Close()
+		h.serveEvents(w, subscription, auth)
+	})
+
+	return nil
+}
+
+// serveEvents writes events in the text/event-stream format until the
+// client goes away, the subscription is dropped or the token expires
+func (h *StreamHandler) serveEvents(w *bufio.Writer, subscription *stream.Subscription, auth streamAuth) {
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	expired := expiryTimer(auth.expiresAt)
+	defer expired.Stop()
+
+	// Comment line: flushes the headers so the client sees the stream open
+	fmt.Fprint(w, ": connected\n\n")
+	if err := w.Flush(); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case event, ok := <-subscription.Events():
+			if !ok {
+				// Dropped for falling behind; the client reconnects
+				return
+			}
+			message, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, message)
+		case <-keepAlive.C:
+			if auth.isRevoked() {
+				fmt.Fprint(w, "event: expired\ndata: {}\n\n")
+				w.Flush()
+				return
+			}
+			fmt.Fprint(w, ": ping\n\n")
+		case <-expired.C:
+			fmt.Fprint(w, "event: expired\ndata: {}\n\n")
+			w.Flush()
+			return
+		}
+
+		// A failed flush means the client disconnected
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// serveWebSocket sends each event as a JSON text message. Messages from the
+// client are ignored; reading them is how a closed connection is noticed.
+func (h *StreamHandler) serveWebSocket(conn *websocket.Conn, userID int64, filter stream.Filter, auth streamAuth) {
+	subscription := h.hub.Subscribe(userID, filter)
+	defer subscription.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	expired := expiryTimer(auth.expiresAt)
+	defer expired.Stop()
+
+	closeWith := func(code int, text string) {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(time.Second))
+	}
+
+	for {
+		var err error
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+
+		select {
+		case <-closed:
+			return
+		case event, ok := <-subscription.Events():
+			if !ok {
+				closeWith(websocket.CloseTryAgainLater, "too far behind, reconnect")
+				return
+			}
+			err = conn.WriteJSON(event)
+		case <-keepAlive.C:
+			if auth.isRevoked() {
+				closeWith(websocket.ClosePolicyViolation, "token expired")
+				return
+			}
+			err = conn.WriteMessage(websocket.PingMessage, nil)
+		case <-expired.C:
+			closeWith(websocket.ClosePolicyViolation, "token expired")
+			return
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// expiryTimer fires when the token expires, or never when it does not
+func expiryTimer(expiresAt time.Time) *time.Timer {
+	if expiresAt.IsZero() {
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		return timer
+	}
+	return time.NewTimer(time.Until(expiresAt))
+}
+
+// isRevoked reports whether the API key of the stream was deleted or has
+// expired; tokens are only ended by their expiry
+func (a streamAuth) isRevoked() bool {
+	return a.revoked != nil && a.revoked()
+}
+
+// apiKeyRevoked looks the key up again. A failed lookup keeps the stream
+// open; only a key that is gone or expired ends it.
+func (h *StreamHandler) apiKeyRevoked(keyID int64) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := h.queries.GetActiveApiKey(ctx, keyID)
+	if err == pgx.ErrNoRows {
+		return true
+	}
+	if err != nil {
+		log.Warnf("stream: failed to check API key %d: %v", keyID, err)
+	}
+	return false
+}
+
+// publishSnapshot streams a newly created snapshot, successful or failed
+func (h *WebhookHandler) publishSnapshot(ctx context.Context, snapshot db.ProcessSnapshot) {
+	if !snapshot.UserID.Valid {
+		return
+	}
+	h.stream.Publish(ctx, stream.EventSnapshotCreated, snapshot.UserID.Int64, snapshot.AgentID, toSnapshotResponse(snapshot))
+}
+
+// Compact cuts the error message of a snapshot event too large to stream
+func (r SnapshotResponse) Compact() any {
+	r.ErrorMessage = truncateText(r.ErrorMessage, streamErrorLength)
+	return r
+}
+
+// Compact cuts the error message of a job event too large to stream
+func (r JobResponse) Compact() any {
+	r.ErrorMessage = truncateText(r.ErrorMessage, streamErrorLength)
+	return r
+}
+
+// truncateText cuts text to at most limit bytes without splitting a UTF-8
+// sequence, marking the cut with an ellipsis
+func truncateText(text *string, limit int) *string {
+	if text == nil || len(*text) <= limit {
+		return text
+	}
+	cut := limit - len("…")
+	for cut > 0 && !utf8.RuneStart((*text)[cut]) {
+		cut--
+	}
+	truncated := (*text)[:cut] + "…"
+	return &truncated
+}
+
+// publishProcesses streams that count processes were persisted to the
+// snapshot. Iterations send only the count; a PID query also sends the ID
+// of the process it added.
+func (h *WebhookHandler) publishProcesses(ctx context.Context, snapshot db.ProcessSnapshot, count int, processInfoID pgtype.Int8) {
+	if !snapshot.UserID.Valid {
+		return
+	}
+
+	data := fiber.Map{
+		"snapshotId": snapshot.ID,
+		"count":      count,
+	}
+	if processInfoID.Valid {
+		data["processInfoId"] = processInfoID.Int64
+	}
+
+	h.stream.Publish(ctx, stream.EventProcessesPersisted, snapshot.UserID.Int64, snapshot.AgentID, data)
+}
+
+// JobChanged streams the new state of a capture job
+func (h *WebhookHandler) JobChanged(ctx context.Context, job db.CaptureJob) {
+	h.stream.Publish(ctx, stream.EventJobUpdated, job.UserID, pgtype.Int8{Int64: job.AgentID, Valid: true}, toJobResponse(job))
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go-api/internal/db"
+	"go-api/internal/jwtkeys"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TokenConfig configura os tokens de acesso e de refresh. Com Keys, os tokens
+// de acesso são assinados com chave assimétrica; sem, com HS256 e Secret.
+type TokenConfig struct {
+	Secret          string
+	Keys            *jwtkeys.KeySet
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+var tokenConfig = TokenConfig{
+	AccessTokenTTL:  15 * time.Minute,
+	RefreshTokenTTL: 30 * 24 * time.Hour,
+}
+
+// ConfigureTokens define as configurações dos tokens. Deve ser chamada antes
+// de as rotas serem servidas.
+func ConfigureTokens(cfg TokenConfig) {
+	tokenConfig = cfg
+}
+
+// GetJWKS publica as chaves públicas que verificam os tokens de acesso, para
+// que outros serviços validem tokens sem conhecer segredos. Com HS256 a
+// lista é vazia.
+func GetJWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+
+	if tokenConfig.Keys == nil {
+		return c.JSON(jwtkeys.JWKSet{Keys: []jwtkeys.JWK{}})
+	}
+	return c.JSON(tokenConfig.Keys.JWKS())
+}
+
+// randomToken retorna n bytes aleatórios em base64 URL-safe
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("falha ao gerar token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken é a forma em que refresh tokens são gravados no banco
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// ttlSeconds converte uma duração para os parâmetros em segundos das queries,
+// com no mínimo um segundo
+func ttlSeconds(d time.Duration) int32 {
+	seconds := int32(d / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+// issueRefreshToken cria um refresh token na família informada e retorna o
+// valor em claro, que só é mostrado ao cliente
+func issueRefreshToken(ctx context.Context, queries *db.Queries, userID int64, familyID string) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = queries.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
+		UserID:     userID,
+		FamilyID:   familyID,
+		TokenHash:  hashToken(token),
+		TtlSeconds: ttlSeconds(tokenConfig.RefreshTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// StartTokenCleanup remove periodicamente refresh tokens e revogações
+// expirados até ctx ser cancelado
+func StartTokenCleanup(ctx context.Context, dbpool *pgxpool.Pool, interval time.Duration) {
+	queries := db.New(dbpool)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := queries.DeleteExpiredRefreshTokens(ctx); err != nil {
+					log.Errorf("auth: failed to delete expired refresh tokens: %v", err)
+				}
+				if _, err := queries.DeleteExpiredRevokedTokens(ctx); err != nil {
+					log.Errorf("auth: failed to delete expired token revocations: %v", err)
+				}
+			}
+		}
+	}()
+}
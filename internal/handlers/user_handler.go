@@ -1,8 +1,6 @@
 package handlers
 
 import (
-	"crypto/sha512"
-	"encoding/hex"
 	"strconv"
 
 	"go-api/internal/db"
@@ -25,6 +23,7 @@ func NewUserHandler(dbpool *pgxpool.Pool) *UserHandler {
 type CreateUserRequest struct {
 	Name     string `json:"name" validate:"required"`
 	Password string `json:"password" validate:"required,min=6"`
+	Role     string `json:"role,omitempty"` // admin, analyst ou viewer (padrão: viewer)
 }
 
 type UpdateUserRequest struct {
@@ -32,15 +31,14 @@ type UpdateUserRequest struct {
 	Password *string `json:"password,omitempty"`
 }
 
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+}
+
 type UserResponse struct {
 	ID   int64  `json:"id"`
 	Name string `json:"name"`
-}
-
-// hashPassword cria a hash SHA-512 da senha
-func hashPassword(password string) string {
-	hash := sha512.Sum512([]byte(password))
-	return hex.EncodeToString(hash[:])
+	Role string `json:"role"`
 }
 
 func toUserResponse(user interface{}) UserResponse {
@@ -49,30 +47,52 @@ func toUserResponse(user interface{}) UserResponse {
 		return UserResponse{
 			ID:   u.ID,
 			Name: u.Name,
+			Role: u.Role,
 		}
 	default:
 		return UserResponse{}
 	}
 }
 
-// GetUsers - Listar todos os usuários
+// GetUsers - Listar usuários, paginado e filtrado por ?name= (glob) e ?role=
 func (h *UserHandler) GetUsers(c *fiber.Ctx) error {
-	users, err := h.queries.GetUsers(c.Context())
+	page, err := listPage(c)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Erro ao buscar usuários",
+		return err
+	}
+
+	role := queryText(c, "role")
+	if role.Valid && !IsValidRole(role.String) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Role inválida, use admin, analyst ou viewer",
 		})
 	}
 
-	response := make([]UserResponse, len(users))
-	for i, user := range users {
+	users, err := h.queries.ListUsers(c.Context(), db.ListUsersParams{
+		NameGlob: c.Query("name"),
+		Role:     role,
+		Page:     page,
+	})
+	if err != nil {
+		return listError(c, err, "Erro ao buscar usuários")
+	}
+
+	response := make([]UserResponse, len(users.Rows))
+	for i, user := range users.Rows {
 		response[i] = toUserResponse(user)
 	}
 
-	return c.JSON(fiber.Map{
-		"data":  response,
-		"count": len(response),
-	})
+	return c.JSON(pageResponse(response, users.NextCursor))
+}
+
+// canManageUser permite que administradores acessem qualquer usuário e os
+// demais apenas a própria conta
+func canManageUser(c *fiber.Ctx, id int64) bool {
+	if isAdmin(c) {
+		return true
+	}
+	userID, ok := c.Locals("userID").(int64)
+	return ok && userID == id
 }
 
 // GetUser - Buscar usuário por ID
@@ -84,6 +104,12 @@ func (h *UserHandler) GetUser(c *fiber.Ctx) error {
 		})
 	}
 
+	if !canManageUser(c, id) {
+		return c.Status(403).JSON(fiber.Map{
+			"error": "Acesso negado",
+		})
+	}
+
 	user, err := h.queries.GetUser(c.Context(), id)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -110,11 +136,57 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 		})
 	}
 
-	hashedPassword := hashPassword(req.Password)
+	hashedPassword, err := hashPassword(req.Password)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Erro ao criar usuário",
+		})
+	}
+
+	// Sem autenticação só é possível criar o primeiro usuário, que se torna
+	// administrador
+	if c.Locals("userRole") == nil {
+		user, err := h.queries.CreateFirstUser(c.Context(), db.CreateFirstUserParams{
+			Name:     req.Name,
+			Password: hashedPassword,
+		})
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return c.Status(401).JSON(fiber.Map{
+					"error": "Autenticação necessária",
+				})
+			}
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Erro ao criar usuário",
+			})
+		}
+
+		return c.Status(201).JSON(fiber.Map{
+			"data":    toUserResponse(user),
+			"message": "Administrador inicial criado com sucesso",
+		})
+	}
+
+	if !isAdmin(c) {
+		return c.Status(403).JSON(fiber.Map{
+			"error": "Acesso negado",
+		})
+	}
+
+	role := req.Role
+	if role == "" {
+		role = RoleViewer
+	}
+	if !IsValidRole(role) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Role inválida, use admin, analyst ou viewer",
+		})
+	}
 
 	params := db.CreateUserParams{
 		Name:     req.Name,
 		Password: hashedPassword,
+		Role:     role,
 	}
 
 	user, err := h.queries.CreateUser(c.Context(), params)
@@ -139,6 +211,12 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 		})
 	}
 
+	if !canManageUser(c, id) {
+		return c.Status(403).JSON(fiber.Map{
+			"error": "Acesso negado",
+		})
+	}
+
 	var req UpdateUserRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{
@@ -169,7 +247,12 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 	}
 
 	if req.Password != nil {
-		params.Password = hashPassword(*req.Password)
+		params.Password, err = hashPassword(*req.Password)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Erro ao atualizar usuário",
+			})
+		}
 	} else {
 		params.Password = currentUser.Password
 	}
@@ -187,6 +270,74 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 	})
 }
 
+// UpdateUserRole - Alterar a role de um usuário (somente administradores)
+func (h *UserHandler) UpdateUserRole(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "ID inválido",
+		})
+	}
+
+	var req UpdateUserRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Dados inválidos",
+		})
+	}
+
+	if !IsValidRole(req.Role) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Role inválida, use admin, analyst ou viewer",
+		})
+	}
+
+	currentUser, err := h.queries.GetUser(c.Context(), id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Usuário não encontrado",
+			})
+		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Erro ao buscar usuário",
+		})
+	}
+
+	if currentUser.Role == RoleAdmin && req.Role != RoleAdmin {
+		if err := h.ensureAnotherAdmin(c); err != nil {
+			return err
+		}
+	}
+
+	user, err := h.queries.UpdateUserRole(c.Context(), db.UpdateUserRoleParams{
+		Role: req.Role,
+		ID:   id,
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Erro ao atualizar usuário",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":    toUserResponse(user),
+		"message": "Role atualizada com sucesso",
+	})
+}
+
+// ensureAnotherAdmin impede que o último administrador seja removido ou rebaixado
+func (h *UserHandler) ensureAnotherAdmin(c *fiber.Ctx) error {
+	admins, err := h.queries.CountAdmins(c.Context())
+	if err != nil {
+		return fiber.NewError(500, "Erro ao buscar usuários")
+	}
+	if admins <= 1 {
+		return fiber.NewError(409, "Não é possível remover o último administrador")
+	}
+	return nil
+}
+
 // DeleteUser - Deletar usuário
 func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
@@ -196,13 +347,26 @@ func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.queries.DeleteUser(c.Context(), id)
+	user, err := h.queries.GetUser(c.Context(), id)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return c.Status(404).JSON(fiber.Map{
 				"error": "Usuário não encontrado",
 			})
 		}
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Erro ao buscar usuário",
+		})
+	}
+
+	if user.Role == RoleAdmin {
+		if err := h.ensureAnotherAdmin(c); err != nil {
+			return err
+		}
+	}
+
+	err = h.queries.DeleteUser(c.Context(), id)
+	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Erro ao deletar usuário",
 		})
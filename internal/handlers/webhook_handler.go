@@ -1,32 +1,61 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
+	"net/url"
 	"time"
 
+	"go-api/internal/agentclient"
 	"go-api/internal/db"
+	"go-api/internal/jobs"
+	"go-api/internal/notify"
+	"go-api/internal/outbound"
+	"go-api/internal/stream"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type WebhookHandler struct {
+	dbpool  *pgxpool.Pool
 	queries *db.Queries
+	jobs    *jobs.Pool
+	agents  *agentclient.Client
+	notify  *notify.Notifier
+	stream  *stream.Hub
+
+	// Length of one unit of the agents' user/kernel time counters
+	cpuTimeUnit time.Duration
 }
 
-func NewWebhookHandler(dbpool *pgxpool.Pool) *WebhookHandler {
+func NewWebhookHandler(dbpool *pgxpool.Pool, jobPool *jobs.Pool, policy *outbound.Policy, notifier *notify.Notifier, hub *stream.Hub, cpuTimeUnit time.Duration) *WebhookHandler {
 	return &WebhookHandler{
-		queries: db.New(dbpool),
+		dbpool:      dbpool,
+		queries:     db.New(dbpool),
+		jobs:        jobPool,
+		agents:      agentclient.New(policy),
+		notify:      notifier,
+		stream:      hub,
+		cpuTimeUnit: cpuTimeUnit,
 	}
 }
 
+// agentCallError maps a failed agent call to an HTTP error. Destinations
+// refused by the outbound policy are the caller's fault, not ours.
+func agentCallError(err error) *fiber.Error {
+	var policyErr *outbound.PolicyError
+	if errors.As(err, &policyErr) {
+		return fiber.NewError(fiber.StatusForbidden, policyErr.Error())
+	}
+	return fiber.NewError(fiber.StatusInternalServerError, fmt.Sprintf("Failed to call webhook: %v", err))
+}
+
 type ProcessByPidRequest struct {
 	Pid int32 `json:"pid"`
 }
@@ -73,46 +102,89 @@ type ProcessByPidResponse struct {
 	Success     bool        `json:"success"`
 }
 
-func (h *WebhookHandler) makeHTTPRequest(url string, method string, body any) ([]byte, error) {
-	var reqBody io.Reader
-	if body != nil {
-		jsonBody, err := json.Marshal(body)
+// resolveAgent looks up the registered agent a capture refers to. agent_id is
+// preferred; webhook_url is still accepted from older clients but must match
+// the base URL of a registered agent
+func (h *WebhookHandler) resolveAgent(ctx context.Context, agentID *int64, webhookURL string) (db.Agent, error) {
+	if agentID != nil {
+		agent, err := h.queries.GetAgent(ctx, *agentID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+			if err == pgx.ErrNoRows {
+				return db.Agent{}, fiber.NewError(fiber.StatusNotFound, "Agent not found")
+			}
+			return db.Agent{}, fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch agent")
 		}
-		reqBody = bytes.NewBuffer(jsonBody)
+		return agent, nil
 	}
 
-	req, err := http.NewRequest(method, url, reqBody)
+	baseURL, err := normalizeAgentURL(webhookURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return db.Agent{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	agent, err := h.queries.GetAgentByBaseURL(ctx, baseURL)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return db.Agent{}, fiber.NewError(fiber.StatusBadRequest,
+				fmt.Sprintf("No registered agent for %s; register it at /api/v1/agents and use agent_id", baseURL))
+		}
+		return db.Agent{}, fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch agent")
+	}
+
+	return agent, nil
+}
 
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+// markAgentSeen records that the agent answered a capture request
+func (h *WebhookHandler) markAgentSeen(ctx context.Context, agentID int64) {
+	if err := h.queries.TouchAgentLastSeen(ctx, agentID); err != nil {
+		log.Warnf("failed to update agent %d last seen: %v", agentID, err)
 	}
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+// markAgentUnreachable records that a call to the agent could not connect.
+// The first failure since the agent last answered notifies its owner and
+// the user whose capture failed. Policy rejections and error statuses mean
+// the agent is misconfigured, not offline.
+func (h *WebhookHandler) markAgentUnreachable(ctx context.Context, agent db.Agent, userID *int64, callErr error) {
+	var urlErr *url.Error
+	var policyErr *outbound.PolicyError
+	if !errors.As(callErr, &urlErr) || errors.As(callErr, &policyErr) {
+		return
 	}
-	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	marked, err := h.queries.MarkAgentOffline(ctx, agent.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		log.Warnf("failed to mark agent %d offline: %v", agent.ID, err)
+		return
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(respBody))
+	if marked == 0 {
+		return
 	}
 
-	return respBody, nil
+	var recipients []int64
+	if agent.OwnerID.Valid {
+		recipients = append(recipients, agent.OwnerID.Int64)
+	}
+	if userID != nil && (!agent.OwnerID.Valid || *userID != agent.OwnerID.Int64) {
+		recipients = append(recipients, *userID)
+	}
+	for _, recipient := range recipients {
+		h.notify.Notify(notify.Event{
+			Type:     notify.EventAgentOffline,
+			UserID:   recipient,
+			Severity: "high",
+			Title:    fmt.Sprintf("Agent %s is offline", agent.Name),
+			Message:  fmt.Sprintf("Agent %s (%s) could not be reached: %v", agent.Name, agent.BaseUrl, callErr),
+			Data: map[string]any{
+				"agentId":   agent.ID,
+				"agentName": agent.Name,
+				"error":     callErr.Error(),
+			},
+		})
+	}
 }
 
-func (h *WebhookHandler) persistProcessInfo(ctx context.Context, snapshotID int64, previousID *int64, userID *int64, processInfo ProcessInfo) (db.ProcessInfo, error) {
+func (h *WebhookHandler) persistProcessInfo(ctx context.Context, snapshotID int64, userID *int64, processInfo ProcessInfo) (db.ProcessInfo, error) {
 	var userIDParam pgtype.Int8
 	if userID != nil {
 		userIDParam = pgtype.Int8{Int64: *userID, Valid: true}
@@ -127,15 +199,11 @@ func (h *WebhookHandler) persistProcessInfo(ctx context.Context, snapshotID int6
 	}
 
 	var previousProcessEProcessAddress, previousProcessName pgtype.Text
-	var previousProcessID, previousIDParam pgtype.Int8
+	var previousProcessID pgtype.Int8
 	if processInfo.PreviousProcess != nil {
 		previousProcessEProcessAddress = pgtype.Text{String: processInfo.PreviousProcess.EProcessAddress, Valid: true}
 		previousProcessName = pgtype.Text{String: processInfo.PreviousProcess.ProcessName, Valid: true}
 		previousProcessID = pgtype.Int8{Int64: processInfo.PreviousProcess.ProcessID, Valid: true}
-
-		if previousID != nil {
-			previousIDParam = pgtype.Int8{Int64: *previousID, Valid: true}
-		}
 	}
 
 	createdProcess, err := h.queries.CreateProcessInfo(ctx, db.CreateProcessInfoParams{
@@ -168,7 +236,6 @@ func (h *WebhookHandler) persistProcessInfo(ctx context.Context, snapshotID int6
 		PreviousProcessEprocessAddress: previousProcessEProcessAddress,
 		PreviousProcessName:            previousProcessName,
 		PreviousProcessID:              previousProcessID,
-		PreviousID:                     previousIDParam,
 	})
 
 	if err != nil {
@@ -180,7 +247,8 @@ func (h *WebhookHandler) persistProcessInfo(ctx context.Context, snapshotID int6
 
 func (h *WebhookHandler) IterateProcesses(c *fiber.Ctx) error {
 	var req struct {
-		WebhookURL string `json:"webhook_url"`
+		AgentID    *int64 `json:"agent_id"`
+		WebhookURL string `json:"webhook_url"` // Deprecated: must match a registered agent
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -189,12 +257,17 @@ func (h *WebhookHandler) IterateProcesses(c *fiber.Ctx) error {
 		})
 	}
 
-	if req.WebhookURL == "" {
+	if req.AgentID == nil && req.WebhookURL == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "webhook_url is required",
+			"error": "agent_id is required",
 		})
 	}
 
+	agent, err := h.resolveAgent(c.Context(), req.AgentID, req.WebhookURL)
+	if err != nil {
+		return err
+	}
+
 	// Get user ID from JWT context (if authenticated)
 	var userID *int64
 	if userIDVal := c.Locals("userID"); userIDVal != nil {
@@ -203,121 +276,331 @@ func (h *WebhookHandler) IterateProcesses(c *fiber.Ctx) error {
 		}
 	}
 
-	// Make request to webhook
-	respBody, err := h.makeHTTPRequest(req.WebhookURL+"/webhook/iterate-processes", "POST", nil)
-	if err != nil {
-		// If authenticated, create failed snapshot
-		if userID != nil {
-			userIDParam := pgtype.Int8{Int64: *userID, Valid: true}
-
-			_, _ = h.queries.CreateProcessSnapshot(c.Context(), db.CreateProcessSnapshotParams{
-				UserID:       userIDParam,
-				WebhookUrl:   req.WebhookURL,
-				SnapshotType: "iteration",
-				ProcessCount: 0,
-				Success:      false,
-				ErrorMessage: pgtype.Text{String: err.Error(), Valid: true},
+	// Async mode: queue the capture and let the client poll the job
+	if c.QueryBool("async") {
+		if userID == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Authentication is required for async captures",
 			})
 		}
 
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("Failed to call webhook: %v", err),
+		job, err := h.jobs.Enqueue(c.Context(), agent.ID, *userID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to queue capture job",
+			})
+		}
+		h.JobChanged(c.Context(), job)
+
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"message": "Capture queued",
+			"jobId":   job.ID,
+			"agentId": agent.ID,
+			"status":  job.Status,
 		})
 	}
 
+	capture, err := h.captureIteration(c.Context(), agent, userID)
+	if err != nil {
+		return err
+	}
+
+	// If not authenticated, return processes without persisting
+	if capture.Snapshot == nil {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message":      "Processes iterated successfully (not persisted - no authentication)",
+			"agentId":      agent.ID,
+			"processCount": len(capture.Processes),
+			"processes":    capture.Processes,
+			"success":      capture.Success,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":      "Processes iterated and persisted successfully",
+		"snapshotId":   capture.Snapshot.ID,
+		"agentId":      agent.ID,
+		"processCount": capture.Persisted,
+		"processes":    capture.Processes,
+		"success":      capture.Success,
+	})
+}
+
+// IterationCapture is the outcome of a single iterate-processes call
+type IterationCapture struct {
+	Snapshot  *db.ProcessSnapshot // nil when the capture was not persisted
+	Processes []ProcessInfo
+	Persisted int
+	Success   bool
+}
+
+// CaptureAgent runs an iteration capture for a registered agent on behalf of
+// a user and returns the snapshot it produced. Failed captures still return
+// the ID of the failed snapshot recorded for them.
+func (h *WebhookHandler) CaptureAgent(ctx context.Context, agentID int64, userID int64) (int64, error) {
+	agent, err := h.queries.GetAgent(ctx, agentID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch agent %d: %w", agentID, err)
+	}
+
+	capture, err := h.captureIteration(ctx, agent, &userID)
+	if capture.Snapshot != nil {
+		return capture.Snapshot.ID, err
+	}
+	return 0, err
+}
+
+// captureIteration calls the agent's iterate-processes endpoint and, when a
+// user is given, persists the result as an iteration snapshot. Failed calls
+// are recorded as failed snapshots so they show up in the history.
+func (h *WebhookHandler) captureIteration(ctx context.Context, agent db.Agent, userID *int64) (IterationCapture, error) {
+	// Make request to webhook
+	respBody, err := h.agents.Post(ctx, agent, "/webhook/iterate-processes", nil)
+	if err != nil {
+		h.markAgentUnreachable(ctx, agent, userID, err)
+		failed := h.recordFailedIteration(ctx, agent, userID, err.Error())
+		return IterationCapture{Snapshot: failed}, agentCallError(err)
+	}
+
 	// Parse response
 	var webhookResp IterateProcessesResponse
 	log.Debug(string(respBody))
 	if err := json.Unmarshal(respBody, &webhookResp); err != nil {
 		log.Debug(err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to parse webhook response",
-		})
+		failed := h.recordFailedIteration(ctx, agent, userID, fmt.Sprintf("failed to parse webhook response: %v", err))
+		return IterationCapture{Snapshot: failed}, fiber.NewError(fiber.StatusInternalServerError, "Failed to parse webhook response")
+	}
+
+	h.markAgentSeen(ctx, agent.ID)
+
+	capture := IterationCapture{
+		Processes: webhookResp.Processes,
+		Success:   webhookResp.Success,
 	}
 
 	// If not authenticated, return processes without persisting
 	if userID == nil {
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"message":      "Processes iterated successfully (not persisted - no authentication)",
-			"processCount": len(webhookResp.Processes),
-			"processes":    webhookResp.Processes,
-			"success":      webhookResp.Success,
-		})
+		return capture, nil
 	}
 
 	// Authenticated: Create snapshot and persist
-	userIDParam := pgtype.Int8{Int64: *userID, Valid: true}
+	snapshot, err := h.persistIteration(ctx, agent, *userID, webhookResp.Processes)
+	if err != nil {
+		log.Warnf("failed to persist snapshot: %v", err)
+		failed := h.recordFailedIteration(ctx, agent, userID, err.Error())
+		return IterationCapture{Snapshot: failed}, fiber.NewError(fiber.StatusInternalServerError, "Failed to persist snapshot")
+	}
 
-	snapshot, err := h.queries.CreateProcessSnapshot(c.Context(), db.CreateProcessSnapshotParams{
-		UserID:       userIDParam,
-		WebhookUrl:   req.WebhookURL,
-		SnapshotType: "iteration",
-		ProcessCount: int32(len(webhookResp.Processes)),
-		Success:      true,
-		ErrorMessage: pgtype.Text{Valid: false},
+	capture.Snapshot = &snapshot
+	capture.Persisted = len(webhookResp.Processes)
+	return capture, nil
+}
+
+// persistIteration writes an iteration snapshot captured from the agent and
+// derives its rates and alerts
+func (h *WebhookHandler) persistIteration(ctx context.Context, agent db.Agent, userID int64, processes []ProcessInfo) (db.ProcessSnapshot, error) {
+	snapshot, err := h.persistSnapshot(ctx, userID, processes, func(qtx *db.Queries) (db.ProcessSnapshot, error) {
+		return qtx.CreateProcessSnapshot(ctx, db.CreateProcessSnapshotParams{
+			UserID:       pgtype.Int8{Int64: userID, Valid: true},
+			WebhookUrl:   pgtype.Text{String: agent.BaseUrl, Valid: true},
+			SnapshotType: "iteration",
+			ProcessCount: int32(len(processes)),
+			Success:      true,
+			ErrorMessage: pgtype.Text{Valid: false},
+			AgentID:      pgtype.Int8{Int64: agent.ID, Valid: true},
+		})
 	})
+	if err != nil {
+		return db.ProcessSnapshot{}, err
+	}
+
+	h.deriveRates(ctx, snapshot.ID)
+	h.evaluateAlertRules(ctx, snapshot, pgtype.Int8{})
+
+	return snapshot, nil
+}
 
+// persistSnapshot writes the snapshot made by create and all of its
+// processes, an iteration walk, in one transaction, so a capture is either
+// stored whole or not at all. Row IDs are reserved up front, which lets the
+// next/previous links be resolved in memory and the rows be sent with a
+// single COPY.
+func (h *WebhookHandler) persistSnapshot(ctx context.Context, userID int64, processes []ProcessInfo, create func(qtx *db.Queries) (db.ProcessSnapshot, error)) (db.ProcessSnapshot, error) {
+	tx, err := h.dbpool.Begin(ctx)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create snapshot",
-		})
+		return db.ProcessSnapshot{}, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.queries.WithTx(tx)
+	userIDParam := pgtype.Int8{Int64: userID, Valid: true}
 
-	// Persist all processes to this snapshot
-	processIDs := make([]int64, 0, len(webhookResp.Processes))
-	var previousProcess *db.ProcessInfo = nil
-	for _, processInfo := range webhookResp.Processes {
+	snapshot, err := create(qtx)
+	if err != nil {
+		return db.ProcessSnapshot{}, fmt.Errorf("failed to create snapshot: %w", err)
+	}
 
-		var previousID *int64 = nil
-		if previousProcess != nil && previousProcess.ID > 0 {
-			previousID = &previousProcess.ID
+	if len(processes) > 0 {
+		ids, err := qtx.ReserveProcessInfoIDs(ctx, int32(len(processes)))
+		if err != nil {
+			return db.ProcessSnapshot{}, fmt.Errorf("failed to reserve process ids: %w", err)
 		}
-		if previousID != nil {
-			log.Debug(*previousID)
-		} else {
-			log.Debug(nil)
+		if len(ids) != len(processes) {
+			return db.ProcessSnapshot{}, fmt.Errorf("reserved %d process ids, expected %d", len(ids), len(processes))
 		}
 
-		createdProcess, err := h.persistProcessInfo(c.Context(), snapshot.ID, previousID, userID, processInfo)
-		log.Debug(createdProcess.ID)
-		if previousProcess != nil {
-			h.queries.UpdateNextProcess(c.Context(), db.UpdateNextProcessParams{
-				ID:                         previousProcess.ID,
-				NextID:                     pgtype.Int8{Int64: createdProcess.ID, Valid: true},
-				NextProcessID:              pgtype.Int8{Int64: createdProcess.ProcessID, Valid: true},
-				NextProcessName:            pgtype.Text{String: createdProcess.ProcessName, Valid: true},
-				NextProcessEprocessAddress: pgtype.Text{String: createdProcess.CurrentProcessAddress, Valid: true},
-			})
-
-			h.queries.UpdatePreviousProcess(c.Context(), db.UpdatePreviousProcessParams{
-				ID:                             createdProcess.ID,
-				PreviousID:                     pgtype.Int8{Int64: previousProcess.ID, Valid: true},
-				PreviousProcessID:              pgtype.Int8{Int64: previousProcess.ProcessID, Valid: true},
-				PreviousProcessName:            pgtype.Text{String: previousProcess.ProcessName, Valid: true},
-				PreviousProcessEprocessAddress: pgtype.Text{String: previousProcess.CurrentProcessAddress, Valid: true},
-			})
+		rows := make([]db.CreateProcessInfosParams, len(processes))
+		for i := range processes {
+			rows[i] = linkedProcessInfoRow(snapshot.ID, userIDParam, processes, ids, i)
 		}
+
+		copied, err := qtx.CreateProcessInfos(ctx, rows)
 		if err != nil {
-			// Log error but continue with other processes
-			fmt.Printf("Failed to persist process %d: %v\n", processInfo.ProcessID, err)
-			continue
+			return db.ProcessSnapshot{}, fmt.Errorf("failed to copy processes: %w", err)
+		}
+		if copied != int64(len(rows)) {
+			return db.ProcessSnapshot{}, fmt.Errorf("copied %d processes, expected %d", copied, len(rows))
 		}
-		processIDs = append(processIDs, createdProcess.ID)
-		previousProcess = &createdProcess
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message":      "Processes iterated and persisted successfully",
-		"snapshotId":   snapshot.ID,
-		"processCount": len(processIDs),
-		"processes":    webhookResp.Processes,
-		"success":      webhookResp.Success,
+	if err := tx.Commit(ctx); err != nil {
+		return db.ProcessSnapshot{}, fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+
+	h.publishSnapshot(ctx, snapshot)
+	h.publishProcesses(ctx, snapshot, len(processes), pgtype.Int8{})
+
+	return snapshot, nil
+}
+
+// deriveRates stores per-second rates for the processes the snapshot shares
+// with the previous iteration of the same agent. Rates are derived data, so
+// a failure is only logged and never loses the capture.
+func (h *WebhookHandler) deriveRates(ctx context.Context, snapshotID int64) {
+	_, err := h.queries.CreateProcessRates(ctx, db.CreateProcessRatesParams{
+		SnapshotID:         snapshotID,
+		CpuTimeUnitSeconds: h.cpuTimeUnit.Seconds(),
+	})
+	if err != nil {
+		log.Warnf("failed to derive process rates for snapshot %d: %v", snapshotID, err)
+	}
+}
+
+// linkedProcessInfoRow builds the row for processes[i] of an iteration walk.
+// next_id/previous_id point at the neighbouring rows of the walk. The
+// next/previous EPROCESS fields keep what the agent reported and fall back
+// to the walk neighbour only when the agent sent nothing.
+func linkedProcessInfoRow(snapshotID int64, userID pgtype.Int8, processes []ProcessInfo, ids []int64, i int) db.CreateProcessInfosParams {
+	processInfo := processes[i]
+
+	row := db.CreateProcessInfosParams{
+		ID:                    ids[i],
+		SnapshotID:            snapshotID,
+		UserID:                userID,
+		ProcessID:             processInfo.ProcessID,
+		ParentProcessID:       processInfo.ParentProcessID,
+		ProcessName:           processInfo.ProcessName,
+		ThreadCount:           processInfo.ThreadCount,
+		HandleCount:           processInfo.HandleCount,
+		BasePriority:          processInfo.BasePriority,
+		CreateTime:            processInfo.CreateTime,
+		UserTime:              processInfo.UserTime,
+		KernelTime:            processInfo.KernelTime,
+		WorkingSetSize:        processInfo.WorkingSetSize,
+		PeakWorkingSetSize:    processInfo.PeakWorkingSetSize,
+		VirtualSize:           processInfo.VirtualSize,
+		PeakVirtualSize:       processInfo.PeakVirtualSize,
+		ReadOperationCount:    processInfo.ReadOperationCount,
+		WriteOperationCount:   processInfo.WriteOperationCount,
+		OtherOperationCount:   processInfo.OtherOperationCount,
+		ReadTransferCount:     processInfo.ReadTransferCount,
+		WriteTransferCount:    processInfo.WriteTransferCount,
+		OtherTransferCount:    processInfo.OtherTransferCount,
+		PageFaultCount:        processInfo.PageFaultCount,
+		CurrentProcessAddress: processInfo.CurrentProcessAddress,
+	}
+
+	next := processInfo.NextProcess
+	if i+1 < len(processes) {
+		row.NextID = pgtype.Int8{Int64: ids[i+1], Valid: true}
+		if next == nil {
+			next = &AdjacentProcess{
+				EProcessAddress: processes[i+1].CurrentProcessAddress,
+				ProcessName:     processes[i+1].ProcessName,
+				ProcessID:       processes[i+1].ProcessID,
+			}
+		}
+	}
+	if next != nil {
+		row.NextProcessEprocessAddress = pgtype.Text{String: next.EProcessAddress, Valid: true}
+		row.NextProcessName = pgtype.Text{String: next.ProcessName, Valid: true}
+		row.NextProcessID = pgtype.Int8{Int64: next.ProcessID, Valid: true}
+	}
+
+	previous := processInfo.PreviousProcess
+	if i > 0 {
+		row.PreviousID = pgtype.Int8{Int64: ids[i-1], Valid: true}
+		if previous == nil {
+			previous = &AdjacentProcess{
+				EProcessAddress: processes[i-1].CurrentProcessAddress,
+				ProcessName:     processes[i-1].ProcessName,
+				ProcessID:       processes[i-1].ProcessID,
+			}
+		}
+	}
+	if previous != nil {
+		row.PreviousProcessEprocessAddress = pgtype.Text{String: previous.EProcessAddress, Valid: true}
+		row.PreviousProcessName = pgtype.Text{String: previous.ProcessName, Valid: true}
+		row.PreviousProcessID = pgtype.Int8{Int64: previous.ProcessID, Valid: true}
+	}
+
+	return row
+}
+
+// recordFailedIteration stores a failed iteration snapshot for authenticated
+// captures. It returns nil when nothing was recorded.
+func (h *WebhookHandler) recordFailedIteration(ctx context.Context, agent db.Agent, userID *int64, message string) *db.ProcessSnapshot {
+	if userID == nil {
+		return nil
+	}
+
+	snapshot, err := h.queries.CreateProcessSnapshot(ctx, db.CreateProcessSnapshotParams{
+		UserID:       pgtype.Int8{Int64: *userID, Valid: true},
+		WebhookUrl:   pgtype.Text{String: agent.BaseUrl, Valid: true},
+		SnapshotType: "iteration",
+		ProcessCount: 0,
+		Success:      false,
+		ErrorMessage: pgtype.Text{String: message, Valid: true},
+		AgentID:      pgtype.Int8{Int64: agent.ID, Valid: true},
+	})
+	if err != nil {
+		log.Warnf("failed to record failed snapshot: %v", err)
+		return nil
+	}
+
+	h.publishSnapshot(ctx, snapshot)
+
+	h.notify.Notify(notify.Event{
+		Type:     notify.EventCaptureFailed,
+		UserID:   *userID,
+		Severity: "medium",
+		Title:    fmt.Sprintf("Capture of agent %s failed", agent.Name),
+		Message:  message,
+		Data: map[string]any{
+			"agentId":    agent.ID,
+			"agentName":  agent.Name,
+			"snapshotId": snapshot.ID,
+			"error":      message,
+		},
 	})
+
+	return &snapshot
 }
 
 func (h *WebhookHandler) ProcessByPid(c *fiber.Ctx) error {
 	var req struct {
-		WebhookURL string `json:"webhook_url"`
+		AgentID    *int64 `json:"agent_id"`
+		WebhookURL string `json:"webhook_url"` // Deprecated: must match a registered agent
 		Pid        int32  `json:"pid"`
 		SnapshotID *int64 `json:"snapshot_id,omitempty"` // Optional: add to existing snapshot
 	}
@@ -328,12 +611,17 @@ func (h *WebhookHandler) ProcessByPid(c *fiber.Ctx) error {
 		})
 	}
 
-	if req.WebhookURL == "" || req.Pid == 0 {
+	if (req.AgentID == nil && req.WebhookURL == "") || req.Pid == 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "webhook_url and pid are required",
+			"error": "agent_id and pid are required",
 		})
 	}
 
+	agent, err := h.resolveAgent(c.Context(), req.AgentID, req.WebhookURL)
+	if err != nil {
+		return err
+	}
+
 	// Get user ID from JWT context (if authenticated)
 	var userID *int64
 	if userIDVal := c.Locals("userID"); userIDVal != nil {
@@ -344,12 +632,11 @@ func (h *WebhookHandler) ProcessByPid(c *fiber.Ctx) error {
 
 	// Make request to webhook
 	webhookReq := ProcessByPidRequest{Pid: req.Pid}
-	respBody, err := h.makeHTTPRequest(req.WebhookURL+"/webhook/process-by-pid", "POST", webhookReq)
+	respBody, err := h.agents.Post(c.Context(), agent, "/webhook/process-by-pid", webhookReq)
 	log.Debug(string(respBody))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("Failed to call webhook: %v", err),
-		})
+		h.markAgentUnreachable(c.Context(), agent, userID, err)
+		return agentCallError(err)
 	}
 
 	// Parse response
@@ -360,10 +647,13 @@ func (h *WebhookHandler) ProcessByPid(c *fiber.Ctx) error {
 		})
 	}
 
+	h.markAgentSeen(c.Context(), agent.ID)
+
 	// If not authenticated, return process without persisting
 	if userID == nil {
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
 			"message":     "Process queried successfully (not persisted - no authentication)",
+			"agentId":     agent.ID,
 			"processInfo": webhookResp.ProcessInfo,
 			"success":     webhookResp.Success,
 		})
@@ -374,12 +664,13 @@ func (h *WebhookHandler) ProcessByPid(c *fiber.Ctx) error {
 
 	// Determine which snapshot to use
 	var snapshotID int64
+	var snapshot db.ProcessSnapshot
 	if req.SnapshotID != nil {
 		// Add to existing snapshot
 		snapshotID = *req.SnapshotID
 
 		// Verify snapshot exists and belongs to user
-		snapshot, err := h.queries.GetProcessSnapshot(c.Context(), snapshotID)
+		snapshot, err = h.queries.GetProcessSnapshot(c.Context(), snapshotID)
 		if err != nil {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Snapshot not found",
@@ -387,7 +678,7 @@ func (h *WebhookHandler) ProcessByPid(c *fiber.Ctx) error {
 		}
 
 		// Check if user owns this snapshot
-		if snapshot.UserID.Valid && snapshot.UserID.Int64 != *userID {
+		if !canAccess(c, snapshot.UserID) {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 				"error": "You don't have permission to add to this snapshot",
 			})
@@ -403,13 +694,14 @@ func (h *WebhookHandler) ProcessByPid(c *fiber.Ctx) error {
 		}
 	} else {
 		// Create new snapshot for this query
-		snapshot, err := h.queries.CreateProcessSnapshot(c.Context(), db.CreateProcessSnapshotParams{
+		snapshot, err = h.queries.CreateProcessSnapshot(c.Context(), db.CreateProcessSnapshotParams{
 			UserID:       userIDParam,
-			WebhookUrl:   req.WebhookURL,
+			WebhookUrl:   pgtype.Text{String: agent.BaseUrl, Valid: true},
 			SnapshotType: "query",
 			ProcessCount: 1,
 			Success:      true,
 			ErrorMessage: pgtype.Text{Valid: false},
+			AgentID:      pgtype.Int8{Int64: agent.ID, Valid: true},
 		})
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -417,10 +709,11 @@ func (h *WebhookHandler) ProcessByPid(c *fiber.Ctx) error {
 			})
 		}
 		snapshotID = snapshot.ID
+		h.publishSnapshot(c.Context(), snapshot)
 	}
 
 	// Persist process info
-	createdProcess, err := h.persistProcessInfo(c.Context(), snapshotID, nil, userID, webhookResp.ProcessInfo)
+	createdProcess, err := h.persistProcessInfo(c.Context(), snapshotID, userID, webhookResp.ProcessInfo)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to persist process info",
@@ -431,11 +724,12 @@ func (h *WebhookHandler) ProcessByPid(c *fiber.Ctx) error {
 	_, err = h.queries.CreateProcessQuery(c.Context(), db.CreateProcessQueryParams{
 		SnapshotID:    snapshotID,
 		UserID:        userIDParam,
-		WebhookUrl:    req.WebhookURL,
+		WebhookUrl:    agent.BaseUrl,
 		RequestedPid:  req.Pid,
 		ProcessInfoID: pgtype.Int8{Int64: createdProcess.ID, Valid: true},
 		Success:       true,
 		ErrorMessage:  pgtype.Text{Valid: false},
+		AgentID:       pgtype.Int8{Int64: agent.ID, Valid: true},
 	})
 
 	if err != nil {
@@ -443,9 +737,13 @@ func (h *WebhookHandler) ProcessByPid(c *fiber.Ctx) error {
 		fmt.Printf("Failed to create query history: %v\n", err)
 	}
 
+	h.publishProcesses(c.Context(), snapshot, 1, pgtype.Int8{Int64: createdProcess.ID, Valid: true})
+	h.evaluateAlertRules(c.Context(), snapshot, pgtype.Int8{Int64: createdProcess.ID, Valid: true})
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message":       "Process queried and persisted successfully",
 		"snapshotId":    snapshotID,
+		"agentId":       agent.ID,
 		"processInfoId": createdProcess.ID,
 		"processInfo":   webhookResp.ProcessInfo,
 		"success":       webhookResp.Success,
@@ -0,0 +1,170 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go-api/internal/db"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Capture job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Capturer runs a single iteration capture of an agent on behalf of a user
+// and returns the ID of the snapshot it recorded (successful or failed)
+type Capturer interface {
+	CaptureAgent(ctx context.Context, agentID int64, userID int64) (int64, error)
+}
+
+// Listener is told about every job a worker starts or finishes and every
+// stale job the pool fails
+type Listener interface {
+	JobChanged(ctx context.Context, job db.CaptureJob)
+}
+
+// Pool runs queued capture jobs with a fixed number of workers. Jobs live in
+// the capture_jobs table, so they survive restarts and several API instances
+// can share the queue.
+type Pool struct {
+	queries *db.Queries
+	workers int
+	timeout time.Duration
+	poll    time.Duration
+	wake    chan struct{}
+}
+
+func New(dbpool *pgxpool.Pool, workers int) *Pool {
+	return &Pool{
+		queries: db.New(dbpool),
+		workers: workers,
+		timeout: 2 * time.Minute,
+		poll:    5 * time.Second,
+		wake:    make(chan struct{}, workers),
+	}
+}
+
+// Enqueue records a queued capture job and wakes an idle worker
+func (p *Pool) Enqueue(ctx context.Context, agentID int64, userID int64) (db.CaptureJob, error) {
+	job, err := p.queries.CreateCaptureJob(ctx, db.CreateCaptureJobParams{
+		UserID:  userID,
+		AgentID: agentID,
+	})
+	if err != nil {
+		return db.CaptureJob{}, err
+	}
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+		// Every worker already has a pending wake-up
+	}
+
+	return job, nil
+}
+
+// Start launches the workers, which run until ctx is cancelled
+func (p *Pool) Start(ctx context.Context, capturer Capturer, listener Listener) {
+	for i := 0; i < p.workers; i++ {
+		go p.work(ctx, capturer, listener)
+	}
+	go p.reap(ctx, listener)
+}
+
+func (p *Pool) work(ctx context.Context, capturer Capturer, listener Listener) {
+	ticker := time.NewTicker(p.poll)
+	defer ticker.Stop()
+
+	for {
+		// Drain the queue before going back to sleep
+		for p.runNext(ctx, capturer, listener) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNext claims the oldest queued job and runs it. It returns false when
+// there was nothing to run.
+func (p *Pool) runNext(ctx context.Context, capturer Capturer, listener Listener) bool {
+	job, err := p.queries.ClaimNextCaptureJob(ctx)
+	if err != nil {
+		if err != pgx.ErrNoRows && ctx.Err() == nil {
+			log.Errorf("jobs: failed to claim capture job: %v", err)
+		}
+		return false
+	}
+	listener.JobChanged(ctx, job)
+
+	captureCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	snapshotID, captureErr := capturer.CaptureAgent(captureCtx, job.AgentID, job.UserID)
+	cancel()
+
+	params := db.FinishCaptureJobParams{
+		ID:     job.ID,
+		Status: StatusSucceeded,
+	}
+	if snapshotID != 0 {
+		params.SnapshotID = pgtype.Int8{Int64: snapshotID, Valid: true}
+	}
+	if captureErr != nil {
+		params.Status = StatusFailed
+		params.ErrorMessage = pgtype.Text{String: captureErr.Error(), Valid: true}
+		log.Warnf("jobs: capture job %d failed: %v", job.ID, captureErr)
+	}
+
+	finished, err := p.queries.FinishCaptureJob(ctx, params)
+	if err == pgx.ErrNoRows {
+		// The reaper failed the job while the capture was still running;
+		// keep that outcome and its event
+		log.Warnf("jobs: capture job %d finished after it was marked stale", job.ID)
+		return true
+	}
+	if err != nil {
+		log.Errorf("jobs: failed to record result of capture job %d: %v", job.ID, err)
+		return true
+	}
+	listener.JobChanged(ctx, finished)
+
+	return true
+}
+
+// reap fails jobs left running by an instance that stopped mid-capture
+func (p *Pool) reap(ctx context.Context, listener Listener) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		failed, err := p.queries.FailStaleCaptureJobs(ctx, int32((2 * p.timeout).Seconds()))
+		if err != nil {
+			log.Errorf("jobs: failed to fail stale capture jobs: %v", err)
+			continue
+		}
+		if len(failed) > 0 {
+			log.Warnf("jobs: marked %d stale capture job(s) as failed", len(failed))
+		}
+		for _, job := range failed {
+			listener.JobChanged(ctx, job)
+		}
+	}
+}
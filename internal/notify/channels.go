@@ -0,0 +1,309 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-api/internal/outbound"
+)
+
+// Headers of webhook deliveries. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)).
+const (
+	HeaderEvent     = "X-Notification-Event"
+	HeaderTimestamp = "X-Notification-Timestamp"
+	HeaderSignature = "X-Notification-Signature"
+)
+
+// Longest a single email or syslog delivery may take
+const sendTimeout = 30 * time.Second
+
+// httpChannel posts events as JSON, retrying transient failures with
+// exponential backoff
+type httpChannel struct {
+	notifier *Notifier
+	url      string
+	secret   string
+	body     func(Event) ([]byte, error)
+}
+
+func (c *httpChannel) Send(ctx context.Context, event Event) error {
+	body, err := c.body(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.notifier.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.notifier.backoff << (attempt - 1)):
+			}
+		}
+
+		retry, err := c.post(ctx, event, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return lastErr
+}
+
+// post makes one delivery attempt and reports whether a failure is worth
+// retrying
+func (c *httpChannel) post(ctx context.Context, event Event, body []byte) (bool, error) {
+	if err := c.notifier.policy.CheckURL(c.url); err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event.Type)
+	if c.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderTimestamp, timestamp)
+		req.Header.Set(HeaderSignature, "sha256="+Sign([]byte(c.secret), timestamp, body))
+	}
+
+	resp, err := c.notifier.client.Do(req)
+	if err != nil {
+		var policyErr *outbound.PolicyError
+		return !errors.As(err, &policyErr), fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	return retry, fmt.Errorf("destination returned status %d", resp.StatusCode)
+}
+
+// Sign returns the hex HMAC-SHA256 signature of a webhook delivery
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookBody(event Event) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+// slackBody is the incoming-webhook payload understood by Slack and
+// compatible services (Mattermost, Rocket.Chat)
+func slackBody(event Event) ([]byte, error) {
+	return json.Marshal(map[string]string{
+		"text": fmt.Sprintf("*[%s] %s*\n%s", strings.ToUpper(event.Severity), event.Title, event.Message),
+	})
+}
+
+// emailChannel sends a plain text email through the configured SMTP server,
+// upgrading to TLS when the server offers STARTTLS
+type emailChannel struct {
+	options SMTPOptions
+	to      []string
+}
+
+func (c *emailChannel) Send(ctx context.Context, event Event) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	address := net.JoinHostPort(c.options.Host, strconv.Itoa(c.options.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, c.options.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: c.options.Host}); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	if c.options.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.options.Username, c.options.Password, c.options.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(c.options.From); err != nil {
+		return fmt.Errorf("SMTP server rejected sender: %w", err)
+	}
+	for _, recipient := range c.to {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("SMTP server rejected recipient %s: %w", recipient, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := writer.Write(c.message(event)); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected email: %w", err)
+	}
+
+	return client.Quit()
+}
+
+func (c *emailChannel) message(event Event) []byte {
+	subject := fmt.Sprintf("[%s] %s", strings.ToUpper(event.Severity), oneLine(event.Title))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", c.options.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(c.to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", event.OccurredAt.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+
+	b.WriteString(event.Message + "\r\n\r\n")
+	fmt.Fprintf(&b, "Event: %s\r\n", event.Type)
+	fmt.Fprintf(&b, "Severity: %s\r\n", event.Severity)
+	fmt.Fprintf(&b, "Occurred at: %s\r\n", event.OccurredAt.Format(time.RFC3339))
+	for _, key := range slices.Sorted(maps.Keys(event.Data)) {
+		fmt.Fprintf(&b, "%s: %v\r\n", key, event.Data[key])
+	}
+
+	return []byte(b.String())
+}
+
+// validateAddress accepts a bare email address. Display names and anything
+// that could inject headers are rejected.
+func validateAddress(address string) error {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Address != address {
+		return fmt.Errorf("invalid email address %q", address)
+	}
+	return nil
+}
+
+// Syslog facility of every message: local0
+const syslogFacility = 16
+
+var syslogSeverities = map[string]int{
+	"low":      5, // notice
+	"medium":   4, // warning
+	"high":     3, // error
+	"critical": 2, // critical
+}
+
+// syslogChannel sends RFC 5424 messages over UDP, or over TCP with octet
+// counting framing (RFC 6587)
+type syslogChannel struct {
+	dialer  *net.Dialer
+	network string
+	address string
+}
+
+func (c *syslogChannel) Send(ctx context.Context, event Event) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	network := c.network
+	if network == "" {
+		network = "udp"
+	}
+
+	conn, err := c.dialer.DialContext(ctx, network, c.address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog server: %w", err)
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	message := syslogMessage(event)
+	if network == "tcp" {
+		message = strconv.Itoa(len(message)) + " " + message
+	}
+	if _, err := io.WriteString(conn, message); err != nil {
+		return fmt.Errorf("failed to write syslog message: %w", err)
+	}
+	return nil
+}
+
+func syslogMessage(event Event) string {
+	severity, ok := syslogSeverities[event.Severity]
+	if !ok {
+		severity = syslogSeverities["medium"]
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s go-api %d %s - %s: %s",
+		syslogFacility*8+severity,
+		event.OccurredAt.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		hostname,
+		os.Getpid(),
+		event.Type,
+		oneLine(event.Title),
+		oneLine(event.Message),
+	)
+}
+
+// checkSyslogAddress validates a host:port syslog destination
+func (n *Notifier) checkSyslogAddress(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("address must be host:port")
+	}
+	if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+		return fmt.Errorf("address has an invalid port")
+	}
+	return n.policy.CheckHost(host)
+}
+
+// oneLine flattens text for headers and syslog, which must not contain
+// line breaks
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,322 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-api/internal/outbound"
+)
+
+var testEvent = Event{
+	Type:       "alert.raised",
+	UserID:     7,
+	Severity:   "high",
+	Title:      "Suspicious\nprocess",
+	Message:    "evil.exe (PID 666) is not\tin the list",
+	Data:       map[string]any{"alertId": 12},
+	OccurredAt: time.Date(2026, 10, 17, 12, 30, 0, 0, time.UTC),
+}
+
+func newTestNotifier(t *testing.T, options outbound.Options) *Notifier {
+	t.Helper()
+
+	policy, err := outbound.NewPolicy(options)
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	n := New(nil, Options{Policy: policy, Retries: 2})
+	n.backoff = time.Millisecond
+	return n
+}
+
+// delivery is one request received by a stand-in webhook server
+type delivery struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookServer answers each request with the next status of statuses,
+// repeating the last one, and records what it received
+func webhookServer(t *testing.T, statuses ...int) (*httptest.Server, func() []delivery) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var received []delivery
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		received = append(received, delivery{header: r.Header.Clone(), body: body})
+		status := statuses[min(len(received), len(statuses))-1]
+		mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []delivery {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]delivery(nil), received...)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	n := newTestNotifier(t, outbound.Options{})
+	server, received := webhookServer(t, http.StatusNoContent)
+
+	channel, err := n.channel(KindWebhook, Config{URL: server.URL, Secret: "s3cret"})
+	if err != nil {
+		t.Fatalf("channel: %v", err)
+	}
+	if err := channel.Send(context.Background(), testEvent); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	deliveries := received()
+	if len(deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(deliveries))
+	}
+	got := deliveries[0]
+
+	if got.header.Get(HeaderEvent) != testEvent.Type {
+		t.Errorf("%s = %q, want %q", HeaderEvent, got.header.Get(HeaderEvent), testEvent.Type)
+	}
+	timestamp := got.header.Get(HeaderTimestamp)
+	if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+		t.Errorf("%s = %q, want Unix seconds", HeaderTimestamp, timestamp)
+	}
+
+	// Receivers verify with HMAC-SHA256(secret, timestamp + "." + body)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(timestamp + "." + string(got.body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got.header.Get(HeaderSignature) != want {
+		t.Errorf("%s = %q, want %q", HeaderSignature, got.header.Get(HeaderSignature), want)
+	}
+
+	var event Event
+	if err := json.Unmarshal(got.body, &event); err != nil {
+		t.Fatalf("body is not an event: %v", err)
+	}
+	if event.Type != testEvent.Type || event.UserID != testEvent.UserID || !event.OccurredAt.Equal(testEvent.OccurredAt) {
+		t.Errorf("event = %+v", event)
+	}
+}
+
+func TestWebhookWithoutSecret(t *testing.T) {
+	n := newTestNotifier(t, outbound.Options{})
+	server, received := webhookServer(t, http.StatusOK)
+
+	channel, _ := n.channel(KindWebhook, Config{URL: server.URL})
+	if err := channel.Send(context.Background(), testEvent); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	got := received()[0]
+	if got.header.Get(HeaderSignature) != "" || got.header.Get(HeaderTimestamp) != "" {
+		t.Errorf("unsigned channel sent signature headers: %v", got.header)
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"success", []int{http.StatusOK}, 1, false},
+		{"server error then success", []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}, 3, false},
+		{"rate limited then success", []int{http.StatusTooManyRequests, http.StatusOK}, 2, false},
+		{"timeout then success", []int{http.StatusRequestTimeout, http.StatusAccepted}, 2, false},
+		{"server error every attempt", []int{http.StatusInternalServerError}, 3, true},
+		{"client error is not retried", []int{http.StatusBadRequest}, 1, true},
+		{"not found is not retried", []int{http.StatusNotFound, http.StatusOK}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newTestNotifier(t, outbound.Options{})
+			server, received := webhookServer(t, tt.statuses...)
+
+			channel, _ := n.channel(KindWebhook, Config{URL: server.URL, Secret: "s3cret"})
+			err := channel.Send(context.Background(), testEvent)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send error = %v, want error %v", err, tt.wantErr)
+			}
+
+			deliveries := received()
+			if len(deliveries) != tt.wantAttempts {
+				t.Fatalf("got %d attempts, want %d", len(deliveries), tt.wantAttempts)
+			}
+			// Every attempt carries the same body
+			for _, d := range deliveries[1:] {
+				if string(d.body) != string(deliveries[0].body) {
+					t.Errorf("retry body differs: %s", d.body)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookBlockedByPolicy(t *testing.T) {
+	n := newTestNotifier(t, outbound.Options{BlockPrivate: true})
+	server, received := webhookServer(t, http.StatusOK)
+
+	channel, _ := n.channel(KindWebhook, Config{URL: server.URL})
+	err := channel.Send(context.Background(), testEvent)
+
+	var policyErr *outbound.PolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("Send error = %v, want a policy error", err)
+	}
+	if len(received()) != 0 {
+		t.Error("blocked destination was contacted")
+	}
+}
+
+func TestSlackPayload(t *testing.T) {
+	n := newTestNotifier(t, outbound.Options{})
+	server, received := webhookServer(t, http.StatusOK)
+
+	channel, _ := n.channel(KindSlack, Config{URL: server.URL})
+	if err := channel.Send(context.Background(), testEvent); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	got := received()[0]
+	if got.header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q", got.header.Get("Content-Type"))
+	}
+	if got.header.Get(HeaderSignature) != "" {
+		t.Error("slack delivery carries a signature")
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal(got.body, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	want := "*[HIGH] Suspicious\nprocess*\nevil.exe (PID 666) is not\tin the list"
+	if len(payload) != 1 || payload["text"] != want {
+		t.Errorf("payload = %q, want only text %q", payload, want)
+	}
+}
+
+// checkSyslogMessage checks an RFC 5424 message of testEvent
+func checkSyslogMessage(t *testing.T, message string) {
+	t.Helper()
+
+	// local0 (16) * 8 + error (3)
+	if !strings.HasPrefix(message, "<131>1 2026-10-17T12:30:00.000000Z ") {
+		t.Errorf("message %q has the wrong header", message)
+	}
+	fields := strings.SplitN(message, " ", 8)
+	if len(fields) != 8 || fields[3] != "go-api" || fields[5] != testEvent.Type || fields[6] != "-" {
+		t.Fatalf("message %q does not have the expected fields", message)
+	}
+	if fields[7] != "Suspicious process: evil.exe (PID 666) is not in the list" {
+		t.Errorf("msg = %q", fields[7])
+	}
+}
+
+func TestSyslogUDP(t *testing.T) {
+	n := newTestNotifier(t, outbound.Options{})
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	defer conn.Close()
+
+	channel, _ := n.channel(KindSyslog, Config{Address: conn.LocalAddr().String()})
+	if err := channel.Send(context.Background(), testEvent); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	size, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	checkSyslogMessage(t, string(buf[:size]))
+}
+
+func TestSyslogTCPFraming(t *testing.T) {
+	n := newTestNotifier(t, outbound.Options{})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer listener.Close()
+
+	// Two events, each on its own connection, with a multi-byte title so
+	// the frame length must count octets rather than characters
+	events := []Event{testEvent, testEvent}
+	events[1].Title = "Ação suspeita"
+
+	received := make(chan string, len(events))
+	go func() {
+		for range events {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			data, _ := io.ReadAll(conn)
+			conn.Close()
+			received <- string(data)
+		}
+	}()
+
+	channel, _ := n.channel(KindSyslog, Config{Network: "tcp", Address: listener.Addr().String()})
+	for _, event := range events {
+		if err := channel.Send(context.Background(), event); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	for i := range events {
+		var data string
+		select {
+		case data = <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatal("syslog server received nothing")
+		}
+
+		reader := bufio.NewReader(strings.NewReader(data))
+		length, err := reader.ReadString(' ')
+		if err != nil {
+			t.Fatalf("frame %q has no length", data)
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+		if err != nil {
+			t.Fatalf("frame length %q is not a number", length)
+		}
+		message, _ := io.ReadAll(reader)
+		if size != len(message) {
+			t.Fatalf("frame says %d octets, message has %d", size, len(message))
+		}
+
+		if i == 0 {
+			checkSyslogMessage(t, string(message))
+		} else if !strings.Contains(string(message), " - Ação suspeita: ") {
+			t.Errorf("message %q lacks the title", message)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+// Package notify delivers events (failed captures, raised alerts, agents
+// going offline) to the notification channels users subscribe to them:
+// signed HTTP webhooks, Slack-compatible incoming webhooks, email over SMTP
+// and syslog.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"go-api/internal/db"
+	"go-api/internal/outbound"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Event types channels can subscribe to
+const (
+	EventCaptureFailed = "capture.failed"
+	EventAlertRaised   = "alert.raised"
+	EventAgentOffline  = "agent.offline"
+)
+
+// EventTest is sent by channel tests; no channel subscribes to it
+const EventTest = "test"
+
+// EventTypes lists the event types channels can subscribe to
+var EventTypes = []string{EventCaptureFailed, EventAlertRaised, EventAgentOffline}
+
+// Severities, from least to most severe
+var Severities = []string{"low", "medium", "high", "critical"}
+
+// Channel kinds
+const (
+	KindWebhook = "webhook"
+	KindSlack   = "slack"
+	KindEmail   = "email"
+	KindSyslog  = "syslog"
+)
+
+// Events waiting for a worker. Notify drops events beyond this rather than
+// block a capture.
+const queueSize = 256
+
+// Event is something a user is notified about
+type Event struct {
+	Type       string         `json:"type"`
+	UserID     int64          `json:"userId"`
+	Severity   string         `json:"severity"`
+	Title      string         `json:"title"`
+	Message    string         `json:"message"`
+	Data       map[string]any `json:"data,omitempty"`
+	OccurredAt time.Time      `json:"occurredAt"`
+}
+
+// Channel delivers events to one destination
+type Channel interface {
+	Send(ctx context.Context, event Event) error
+}
+
+// Config is the destination of a channel, stored as JSON. The fields used
+// depend on the channel kind.
+type Config struct {
+	URL     string   `json:"url,omitempty"`     // webhook and slack
+	Secret  string   `json:"secret,omitempty"`  // webhook: HMAC-SHA256 signing secret
+	To      []string `json:"to,omitempty"`      // email: recipients
+	Network string   `json:"network,omitempty"` // syslog: udp (default) or tcp
+	Address string   `json:"address,omitempty"` // syslog: host:port
+}
+
+// SMTPOptions configures the server email channels send through. Email
+// channels are unavailable when Host is empty.
+type SMTPOptions struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+type Options struct {
+	Policy  *outbound.Policy // destinations of webhook, slack and syslog channels
+	SMTP    SMTPOptions
+	Workers int
+	Retries int // webhook and slack attempts after the first
+}
+
+// Notifier queues events and delivers them to the subscribed channels of
+// their user. Delivery is best-effort: the queue lives in memory and the
+// outcome of the last attempt is recorded on the channel.
+type Notifier struct {
+	queries *db.Queries
+	policy  *outbound.Policy
+	client  *http.Client
+	dialer  *net.Dialer
+	smtp    SMTPOptions
+	workers int
+	retries int
+	backoff time.Duration
+	queue   chan Event
+}
+
+func New(dbpool *pgxpool.Pool, options Options) *Notifier {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   options.Policy.Control,
+	}
+
+	// No proxy: the policy must see the real destination address
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Notifier{
+		queries: db.New(dbpool),
+		policy:  options.Policy,
+		client: &http.Client{
+			Timeout:   15 * time.Second,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 5 {
+					return errors.New("stopped after 5 redirects")
+				}
+				return options.Policy.CheckURL(req.URL.String())
+			},
+		},
+		dialer:  dialer,
+		smtp:    options.SMTP,
+		workers: options.Workers,
+		retries: options.Retries,
+		backoff: time.Second,
+		queue:   make(chan Event, queueSize),
+	}
+}
+
+// Start launches the delivery workers, which run until ctx is cancelled
+func (n *Notifier) Start(ctx context.Context) {
+	for i := 0; i < n.workers; i++ {
+		go n.work(ctx)
+	}
+}
+
+// Notify queues an event for delivery. It never blocks.
+func (n *Notifier) Notify(event Event) {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	select {
+	case n.queue <- event:
+	default:
+		log.Warnf("notify: queue full, dropping %s event for user %d", event.Type, event.UserID)
+	}
+}
+
+func (n *Notifier) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-n.queue:
+			n.deliver(ctx, event)
+		}
+	}
+}
+
+// deliver sends event to every enabled channel of its user subscribed to
+// its type and at or below its severity
+func (n *Notifier) deliver(ctx context.Context, event Event) {
+	channels, err := n.queries.GetSubscribedNotificationChannels(ctx, db.GetSubscribedNotificationChannelsParams{
+		UserID: event.UserID,
+		Event:  event.Type,
+	})
+	if err != nil {
+		log.Errorf("notify: failed to fetch channels of user %d: %v", event.UserID, err)
+		return
+	}
+
+	for _, channel := range channels {
+		if SeverityRank(event.Severity) < SeverityRank(channel.MinSeverity) {
+			continue
+		}
+		n.Send(ctx, channel, event)
+	}
+}
+
+// Send delivers event to one channel and records the outcome on it
+func (n *Notifier) Send(ctx context.Context, row db.NotificationChannel, event Event) error {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	err := n.send(ctx, row, event)
+
+	var lastError pgtype.Text
+	if err != nil {
+		lastError = pgtype.Text{String: err.Error(), Valid: true}
+		log.Warnf("notify: failed to deliver %s event to channel %d: %v", event.Type, row.ID, err)
+	}
+	if recordErr := n.queries.RecordNotificationAttempt(ctx, db.RecordNotificationAttemptParams{
+		ID:        row.ID,
+		LastError: lastError,
+	}); recordErr != nil {
+		log.Errorf("notify: failed to record delivery to channel %d: %v", row.ID, recordErr)
+	}
+
+	return err
+}
+
+func (n *Notifier) send(ctx context.Context, row db.NotificationChannel, event Event) error {
+	var config Config
+	if err := json.Unmarshal(row.Config, &config); err != nil {
+		return fmt.Errorf("invalid channel config: %w", err)
+	}
+
+	channel, err := n.channel(row.Kind, config)
+	if err != nil {
+		return err
+	}
+	return channel.Send(ctx, event)
+}
+
+// channel builds the sender for a channel of the given kind
+func (n *Notifier) channel(kind string, config Config) (Channel, error) {
+	switch kind {
+	case KindWebhook:
+		return &httpChannel{notifier: n, url: config.URL, secret: config.Secret, body: webhookBody}, nil
+	case KindSlack:
+		return &httpChannel{notifier: n, url: config.URL, body: slackBody}, nil
+	case KindEmail:
+		if n.smtp.Host == "" {
+			return nil, fmt.Errorf("email channels are not configured on this server")
+		}
+		return &emailChannel{options: n.smtp, to: config.To}, nil
+	case KindSyslog:
+		return &syslogChannel{dialer: n.dialer, network: config.Network, address: config.Address}, nil
+	}
+	return nil, fmt.Errorf("unknown channel kind %q", kind)
+}
+
+// Validate checks that config is complete for kind and that its
+// destination is allowed
+func (n *Notifier) Validate(kind string, config Config) error {
+	switch kind {
+	case KindWebhook, KindSlack:
+		if config.URL == "" {
+			return fmt.Errorf("url is required for %s channels", kind)
+		}
+		if err := n.policy.CheckURL(config.URL); err != nil {
+			return err
+		}
+	case KindEmail:
+		if n.smtp.Host == "" {
+			return fmt.Errorf("email channels are not configured on this server")
+		}
+		if len(config.To) == 0 {
+			return fmt.Errorf("to is required for email channels")
+		}
+		for _, address := range config.To {
+			if err := validateAddress(address); err != nil {
+				return err
+			}
+		}
+	case KindSyslog:
+		if config.Network != "" && config.Network != "udp" && config.Network != "tcp" {
+			return fmt.Errorf("network must be udp or tcp")
+		}
+		if err := n.checkSyslogAddress(config.Address); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("kind must be one of webhook, slack, email or syslog")
+	}
+	return nil
+}
+
+// SeverityRank orders severities; unknown severities rank lowest
+func SeverityRank(severity string) int {
+	return slices.Index(Severities, severity)
+}
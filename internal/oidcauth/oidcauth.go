@@ -0,0 +1,183 @@
+package oidcauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Options configures the OIDC client
+type Options struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string // "openid" is always requested
+	GroupsClaim  string   // ID token claim holding the user's groups
+}
+
+// Flow is the per-login state kept by the client between the redirect to
+// the provider and the callback
+type Flow struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"` // PKCE code verifier
+}
+
+// Identity is the verified user information taken from the ID token
+type Identity struct {
+	Issuer            string
+	Subject           string
+	PreferredUsername string
+	Email             string
+	Groups            []string
+}
+
+// Provider runs the authorization code flow against one issuer. Discovery
+// happens on first use, so the API starts even if the provider is down.
+type Provider struct {
+	options Options
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+	verifier *oidc.IDTokenVerifier
+	oauth    *oauth2.Config
+}
+
+func New(options Options) *Provider {
+	if options.GroupsClaim == "" {
+		options.GroupsClaim = "groups"
+	}
+	return &Provider{options: options}
+}
+
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.provider != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, p.options.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+
+	scopes := []string{oidc.ScopeOpenID}
+	for _, scope := range p.options.Scopes {
+		if scope != oidc.ScopeOpenID {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	p.provider = provider
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.options.ClientID})
+	p.oauth = &oauth2.Config{
+		ClientID:     p.options.ClientID,
+		ClientSecret: p.options.ClientSecret,
+		RedirectURL:  p.options.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       scopes,
+	}
+	return p.oauth, p.verifier, nil
+}
+
+// Begin starts a login and returns the provider URL to redirect the user to,
+// plus the flow state the callback needs
+func (p *Provider) Begin(ctx context.Context) (string, Flow, error) {
+	config, _, err := p.discover(ctx)
+	if err != nil {
+		return "", Flow{}, err
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", Flow{}, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", Flow{}, err
+	}
+
+	flow := Flow{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}
+	url := config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(flow.Verifier))
+	return url, flow, nil
+}
+
+// Finish exchanges the authorization code, verifies the ID token against
+// the flow and returns the user's identity
+func (p *Provider) Finish(ctx context.Context, code, state string, flow Flow) (Identity, error) {
+	if flow.State == "" || state != flow.State {
+		return Identity{}, errors.New("state mismatch")
+	}
+
+	config, verifier, err := p.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return Identity{}, fmt.Errorf("code exchange failed: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, errors.New("token response has no id_token")
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if idToken.Nonce != flow.Nonce {
+		return Identity{}, errors.New("nonce mismatch")
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, fmt.Errorf("invalid ID token claims: %w", err)
+	}
+
+	identity := Identity{
+		Issuer:  idToken.Issuer,
+		Subject: idToken.Subject,
+		Groups:  stringList(claims[p.options.GroupsClaim]),
+	}
+	identity.PreferredUsername, _ = claims["preferred_username"].(string)
+	identity.Email, _ = claims["email"].(string)
+
+	return identity, nil
+}
+
+// stringList accepts a claim holding either a list of strings or one string
+func stringList(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate OIDC state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
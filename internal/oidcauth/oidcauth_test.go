@@ -0,0 +1,142 @@
+package oidcauth
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"go-api/internal/oidcauth/oidctest"
+)
+
+func newTestProvider(t *testing.T) (*oidctest.Provider, *Provider) {
+	t.Helper()
+
+	stub, err := oidctest.New()
+	if err != nil {
+		t.Fatalf("failed to start stub provider: %v", err)
+	}
+	t.Cleanup(stub.Close)
+
+	provider := New(Options{
+		IssuerURL:    stub.Issuer,
+		ClientID:     stub.ClientID,
+		ClientSecret: stub.ClientSecret,
+		RedirectURL:  "http://localhost:3000/api/v1/auth/oidc/callback",
+		Scopes:       []string{"openid", "profile", "email"},
+	})
+	return stub, provider
+}
+
+func TestFinish(t *testing.T) {
+	stub, provider := newTestProvider(t)
+	stub.SetLogin(oidctest.Login{
+		Subject:           "user-1",
+		PreferredUsername: "alice",
+		Email:             "alice@example.com",
+		Groups:            []string{"sec-analysts", "everyone"},
+	})
+
+	ctx := context.Background()
+	authURL, flow, err := provider.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	for _, param := range []string{"state=" + flow.State, "nonce=" + flow.Nonce, "code_challenge_method=S256"} {
+		if !strings.Contains(authURL, param) {
+			t.Errorf("authorization URL %q lacks %q", authURL, param)
+		}
+	}
+
+	code, state, err := stub.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	identity, err := provider.Finish(ctx, code, state, flow)
+	if err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if identity.Issuer != stub.Issuer || identity.Subject != "user-1" {
+		t.Errorf("identity = %s/%s, want %s/user-1", identity.Issuer, identity.Subject, stub.Issuer)
+	}
+	if identity.PreferredUsername != "alice" || identity.Email != "alice@example.com" {
+		t.Errorf("profile = %q %q, want alice alice@example.com", identity.PreferredUsername, identity.Email)
+	}
+	if !slices.Equal(identity.Groups, []string{"sec-analysts", "everyone"}) {
+		t.Errorf("groups = %v", identity.Groups)
+	}
+}
+
+func TestFinishRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		login   oidctest.Login
+		tamper  func(state *string, flow *Flow)
+		wantErr string
+	}{
+		{
+			name:    "state mismatch",
+			tamper:  func(state *string, flow *Flow) { *state = "forged" },
+			wantErr: "state mismatch",
+		},
+		{
+			name:    "missing flow",
+			tamper:  func(state *string, flow *Flow) { *flow = Flow{} },
+			wantErr: "state mismatch",
+		},
+		{
+			name:    "nonce mismatch",
+			login:   oidctest.Login{Nonce: "replayed"},
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:    "wrong PKCE verifier",
+			tamper:  func(state *string, flow *Flow) { flow.Verifier = strings.Repeat("x", 43) },
+			wantErr: "code exchange failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub, provider := newTestProvider(t)
+			login := tt.login
+			login.Subject = "user-1"
+			stub.SetLogin(login)
+
+			ctx := context.Background()
+			authURL, flow, err := provider.Begin(ctx)
+			if err != nil {
+				t.Fatalf("Begin: %v", err)
+			}
+			code, state, err := stub.Authorize(authURL)
+			if err != nil {
+				t.Fatalf("Authorize: %v", err)
+			}
+			if tt.tamper != nil {
+				tt.tamper(&state, &flow)
+			}
+
+			_, err = provider.Finish(ctx, code, state, flow)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Finish error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStringList(t *testing.T) {
+	tests := []struct {
+		value any
+		want  []string
+	}{
+		{"admins", []string{"admins"}},
+		{[]any{"a", 1, "b"}, []string{"a", "b"}},
+		{nil, nil},
+	}
+	for _, tt := range tests {
+		if got := stringList(tt.value); !slices.Equal(got, tt.want) {
+			t.Errorf("stringList(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
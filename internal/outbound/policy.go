@@ -0,0 +1,210 @@
+package outbound
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// Ranges that are never reachable: link-local (which includes the cloud
+// metadata endpoints at 169.254.169.254), other metadata addresses,
+// unspecified, multicast and reserved space
+var defaultBlocked = []string{
+	"0.0.0.0/8",
+	"169.254.0.0/16",
+	"100.100.100.200/32", // Alibaba Cloud metadata
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"fe80::/10",
+	"ff00::/8",
+	"fd00:ec2::254/128", // AWS IPv6 metadata
+}
+
+// Ranges added to the blocklist when private destinations are disabled
+var privateRanges = []string{
+	"127.0.0.0/8",
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"100.64.0.0/10",
+	"::1/128",
+	"fc00::/7",
+}
+
+// PolicyError reports a destination rejected by the outbound policy
+type PolicyError struct {
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return "outbound request blocked: " + e.Reason
+}
+
+// Options configures a Policy. Empty allowlists allow everything that is
+// not blocked.
+type Options struct {
+	AllowedSchemes []string
+	AllowedHosts   []string // exact hostnames or "*.example.com" suffixes
+	AllowedCIDRs   []string
+	BlockedCIDRs   []string // added to the built-in blocklist
+	BlockPrivate   bool     // also block loopback and private ranges
+}
+
+// Policy decides which destinations outbound webhook calls may reach. URLs
+// are checked before a request is made and every address is checked again
+// when the connection is dialed, so DNS answers cannot rebind a permitted
+// hostname to a blocked address.
+type Policy struct {
+	schemes map[string]bool
+	hosts   []string
+	allowed []netip.Prefix
+	blocked []netip.Prefix
+}
+
+func NewPolicy(options Options) (*Policy, error) {
+	policy := &Policy{schemes: make(map[string]bool)}
+
+	schemes := options.AllowedSchemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+	for _, scheme := range schemes {
+		policy.schemes[strings.ToLower(strings.TrimSpace(scheme))] = true
+	}
+
+	for _, host := range options.AllowedHosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			policy.hosts = append(policy.hosts, host)
+		}
+	}
+
+	var err error
+	if policy.allowed, err = parsePrefixes(options.AllowedCIDRs); err != nil {
+		return nil, err
+	}
+
+	blocked := append([]string{}, defaultBlocked...)
+	if options.BlockPrivate {
+		blocked = append(blocked, privateRanges...)
+	}
+	if policy.blocked, err = parsePrefixes(append(blocked, options.BlockedCIDRs...)); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			// Accept bare addresses as single-host ranges
+			addr, addrErr := netip.ParseAddr(value)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid CIDR %q: %w", value, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// CheckURL validates the scheme and host of a URL. Hostnames are resolved
+// and checked at dial time; IP literals are checked here as well.
+func (p *Policy) CheckURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return &PolicyError{Reason: "invalid URL"}
+	}
+
+	if !p.schemes[strings.ToLower(parsed.Scheme)] {
+		return &PolicyError{Reason: fmt.Sprintf("scheme %q is not allowed", parsed.Scheme)}
+	}
+
+	if parsed.Hostname() == "" {
+		return &PolicyError{Reason: "URL has no host"}
+	}
+
+	return p.CheckHost(parsed.Hostname())
+}
+
+// CheckHost validates a destination host against the allowlist. Like
+// CheckURL, it checks IP literals here and leaves hostnames to dial time.
+func (p *Policy) CheckHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" {
+		return &PolicyError{Reason: "destination has no host"}
+	}
+
+	if len(p.hosts) > 0 && !p.hostAllowed(host) {
+		return &PolicyError{Reason: fmt.Sprintf("host %q is not in the allowlist", host)}
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.CheckAddr(addr)
+	}
+
+	return nil
+}
+
+func (p *Policy) hostAllowed(host string) bool {
+	for _, pattern := range p.hosts {
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAddr validates a resolved destination address
+func (p *Policy) CheckAddr(addr netip.Addr) error {
+	// Zoned addresses (fe80::1%eth0) never match a prefix, drop the zone
+	addr = addr.WithZone("").Unmap()
+
+	for _, prefix := range p.blocked {
+		if prefix.Contains(addr) {
+			return &PolicyError{Reason: fmt.Sprintf("address %s is in blocked range %s", addr, prefix)}
+		}
+	}
+
+	if len(p.allowed) == 0 {
+		return nil
+	}
+	for _, prefix := range p.allowed {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	return &PolicyError{Reason: fmt.Sprintf("address %s is not in an allowed range", addr)}
+}
+
+// Control is a net.Dialer Control hook that rejects connections to
+// addresses the policy does not permit. It runs after DNS resolution, on
+// the exact address being connected to.
+func (p *Policy) Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return &PolicyError{Reason: fmt.Sprintf("invalid dial address %q", address)}
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return &PolicyError{Reason: fmt.Sprintf("invalid dial address %q", address)}
+	}
+
+	return p.CheckAddr(addr)
+}
@@ -0,0 +1,272 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-api/internal/db"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/robfig/cron/v3"
+)
+
+// MinInterval is the shortest interval a schedule may use
+const MinInterval = 30 * time.Second
+
+// maxMissedScan bounds how many missed occurrences are counted for a
+// schedule that has been overdue for a long time
+const maxMissedScan = 10000
+
+// queueSize bounds how many claimed runs may wait for a free worker
+const queueSize = 100
+
+// Capturer runs a single iteration capture of an agent on behalf of a user
+// and returns the ID of the snapshot it recorded (successful or failed)
+type Capturer interface {
+	CaptureAgent(ctx context.Context, agentID int64, userID int64) (int64, error)
+}
+
+// Scheduler periodically looks for due capture schedules and runs them
+type Scheduler struct {
+	queries     *db.Queries
+	capturer    Capturer
+	tick        time.Duration
+	timeout     time.Duration
+	concurrency int
+}
+
+func New(dbpool *pgxpool.Pool, capturer Capturer, tick time.Duration) *Scheduler {
+	return &Scheduler{
+		queries:     db.New(dbpool),
+		capturer:    capturer,
+		tick:        tick,
+		timeout:     2 * time.Minute,
+		concurrency: 4,
+	}
+}
+
+// ParseSpec turns a schedule definition into a cron.Schedule. Exactly one of
+// cronExpression (standard 5-field syntax or descriptors such as @hourly) and
+// intervalSeconds must be set. Cron expressions are evaluated in UTC unless
+// they start with CRON_TZ=<zone>.
+func ParseSpec(cronExpression *string, intervalSeconds *int32) (cron.Schedule, error) {
+	if (cronExpression == nil) == (intervalSeconds == nil) {
+		return nil, fmt.Errorf("exactly one of cron_expression or interval_seconds is required")
+	}
+
+	if intervalSeconds != nil {
+		interval := time.Duration(*intervalSeconds) * time.Second
+		if interval < MinInterval {
+			return nil, fmt.Errorf("interval_seconds must be at least %d", int(MinInterval.Seconds()))
+		}
+		return cron.Every(interval), nil
+	}
+
+	schedule, err := cron.ParseStandard(*cronExpression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron_expression: %w", err)
+	}
+	// Without CRON_TZ the schedule takes the zone of the time passed to
+	// Next, which would make the result depend on the caller
+	if spec, ok := schedule.(*cron.SpecSchedule); ok && spec.Location == time.Local {
+		spec.Location = time.UTC
+	}
+	return schedule, nil
+}
+
+// SpecFromRow parses the schedule definition stored in a capture_schedules row
+func SpecFromRow(row db.CaptureSchedule) (cron.Schedule, error) {
+	var cronExpression *string
+	var intervalSeconds *int32
+	if row.CronExpression.Valid {
+		cronExpression = &row.CronExpression.String
+	}
+	if row.IntervalSeconds.Valid {
+		intervalSeconds = &row.IntervalSeconds.Int32
+	}
+	return ParseSpec(cronExpression, intervalSeconds)
+}
+
+// Start runs the scheduler loop until ctx is cancelled. The loop only claims
+// due schedules; captures run on a fixed pool of workers so a slow capture
+// never delays claiming the others.
+func (s *Scheduler) Start(ctx context.Context) {
+	queue := make(chan db.CaptureSchedule, queueSize)
+	for i := 0; i < s.concurrency; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case schedule := <-queue:
+					s.capture(ctx, schedule)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.tick)
+		defer ticker.Stop()
+
+		for {
+			s.claimDue(ctx, queue)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// claimDue claims as many due schedules as the queue has room for and
+// queues them. Schedules left unclaimed stay due for the next tick.
+func (s *Scheduler) claimDue(ctx context.Context, queue chan<- db.CaptureSchedule) {
+	free := cap(queue) - len(queue)
+	if free == 0 {
+		log.Warnf("scheduler: %d claimed runs are still waiting for a worker", len(queue))
+		return
+	}
+
+	now := time.Now().UTC()
+
+	schedules, err := s.queries.GetDueSchedules(ctx, db.GetDueSchedulesParams{
+		NextRunAt: pgtype.Timestamp{Time: now, Valid: true},
+		Limit:     int32(free),
+	})
+	if err != nil {
+		log.Errorf("scheduler: failed to fetch due schedules: %v", err)
+		return
+	}
+
+	// The loop is the only sender, so the queue has room for every
+	// schedule fetched
+	for _, schedule := range schedules {
+		if s.claim(ctx, schedule, now) {
+			queue <- schedule
+		}
+	}
+}
+
+// claim advances the next run of a due schedule and records the runs it
+// missed. It reports whether this instance claimed the run.
+func (s *Scheduler) claim(ctx context.Context, schedule db.CaptureSchedule, now time.Time) bool {
+	spec, err := SpecFromRow(schedule)
+	if err != nil {
+		log.Errorf("scheduler: schedule %d has an invalid spec: %v", schedule.ID, err)
+		return false
+	}
+
+	// Count occurrences that elapsed while nobody was running them (server
+	// down, previous capture still in flight) before claiming this run.
+	// Occurrences within one tick of now are folded into this run.
+	dueAt := schedule.NextRunAt.Time
+	grace := now.Add(-s.tick)
+	missed := 0
+	next := spec.Next(dueAt)
+	for scanned := 0; !next.After(now) && scanned < maxMissedScan; scanned++ {
+		if next.Before(grace) {
+			missed++
+		}
+		next = spec.Next(next)
+	}
+
+	// The scan gave up on a long outage: resume from the first occurrence
+	// after now, or the schedule would be claimed again on every tick.
+	// Interval schedules count the rest of the missed runs; for cron
+	// expressions the count is only a lower bound.
+	exact := true
+	if !next.After(now) {
+		if interval, ok := spec.(cron.ConstantDelaySchedule); ok {
+			if next.Before(grace) {
+				missed += int((grace.Sub(next)-1)/interval.Delay) + 1
+			}
+		} else {
+			exact = false
+		}
+		next = spec.Next(now)
+	}
+
+	claimed, err := s.queries.ClaimSchedule(ctx, db.ClaimScheduleParams{
+		NextRunAt:         pgtype.Timestamp{Time: next.UTC(), Valid: true},
+		LastRunAt:         pgtype.Timestamp{Time: now, Valid: true},
+		ID:                schedule.ID,
+		ExpectedNextRunAt: schedule.NextRunAt,
+	})
+	if err != nil {
+		log.Errorf("scheduler: failed to claim schedule %d: %v", schedule.ID, err)
+		return false
+	}
+	if claimed == 0 {
+		// Another instance picked it up, or it was paused/edited meanwhile
+		return false
+	}
+
+	if missed > 0 {
+		s.recordMissed(ctx, schedule, missed, exact, dueAt, now)
+	}
+	return true
+}
+
+// capture runs a claimed schedule and records the outcome
+func (s *Scheduler) capture(ctx context.Context, schedule db.CaptureSchedule) {
+	captureCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	snapshotID, captureErr := s.capturer.CaptureAgent(captureCtx, schedule.AgentID, schedule.UserID)
+
+	var lastSnapshotID pgtype.Int8
+	if snapshotID != 0 {
+		lastSnapshotID = pgtype.Int8{Int64: snapshotID, Valid: true}
+	}
+	var lastError pgtype.Text
+	if captureErr != nil {
+		lastError = pgtype.Text{String: captureErr.Error(), Valid: true}
+		log.Warnf("scheduler: capture for schedule %d failed: %v", schedule.ID, captureErr)
+	}
+
+	err := s.queries.RecordScheduleRun(ctx, db.RecordScheduleRunParams{
+		ID:             schedule.ID,
+		LastSnapshotID: lastSnapshotID,
+		LastError:      lastError,
+	})
+	if err != nil {
+		log.Errorf("scheduler: failed to record run of schedule %d: %v", schedule.ID, err)
+	}
+}
+
+// recordMissed stores a failed iteration snapshot describing runs that were
+// skipped, so gaps in the capture history are explicit
+func (s *Scheduler) recordMissed(ctx context.Context, schedule db.CaptureSchedule, missed int, exact bool, since, now time.Time) {
+	agent, err := s.queries.GetAgent(ctx, schedule.AgentID)
+	if err != nil {
+		log.Errorf("scheduler: failed to fetch agent %d: %v", schedule.AgentID, err)
+		return
+	}
+
+	count := strconv.Itoa(missed)
+	if !exact {
+		count = "at least " + count
+	}
+	message := fmt.Sprintf("schedule %d missed %s run(s) between %s and %s",
+		schedule.ID, count, since.Format(time.RFC3339), now.Format(time.RFC3339))
+
+	_, err = s.queries.CreateProcessSnapshot(ctx, db.CreateProcessSnapshotParams{
+		UserID:       pgtype.Int8{Int64: schedule.UserID, Valid: true},
+		WebhookUrl:   pgtype.Text{String: agent.BaseUrl, Valid: true},
+		SnapshotType: "iteration",
+		ProcessCount: 0,
+		Success:      false,
+		ErrorMessage: pgtype.Text{String: message, Valid: true},
+		AgentID:      pgtype.Int8{Int64: agent.ID, Valid: true},
+	})
+	if err != nil {
+		log.Errorf("scheduler: failed to record missed runs for schedule %d: %v", schedule.ID, err)
+	}
+}
@@ -0,0 +1,317 @@
+// Package search parses the process search language used by
+// GET /api/v1/processes/search and compiles it to SQL conditions on
+// process_info.
+//
+// A query is a list of terms separated by spaces; every term must match:
+//
+//	name:svchost* ppid:4 handles>1000 ws>500MB since:7d
+//
+// Terms are field:value or field<op>value with op one of = != > >= < <=.
+// A leading "-" negates a term, values with spaces go in double quotes and a
+// bare word matches process names containing it.
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"go-api/internal/db"
+)
+
+// Longest query accepted, in terms
+const maxTerms = 20
+
+// Furthest back a relative time such as since:7d may go
+const maxRelativeTime = 100 * 365 * 24 * time.Hour
+
+type kind int
+
+const (
+	kindGlob    kind = iota // case-insensitive glob (* and ?)
+	kindInt                 // integer
+	kindSize                // integer with optional B/KB/MB/GB/TB suffix
+	kindAddress             // exact match, case-insensitive
+	kindAgent               // snapshot captured by the agent
+	kindSince               // created_at at or after a time
+	kindUntil               // created_at before a time
+)
+
+type field struct {
+	column string
+	kind   kind
+}
+
+var fields = map[string]field{
+	"name":     {"process_name", kindGlob},
+	"pid":      {"process_id", kindInt},
+	"ppid":     {"parent_process_id", kindInt},
+	"threads":  {"thread_count", kindInt},
+	"handles":  {"handle_count", kindInt},
+	"priority": {"base_priority", kindInt},
+	"ws":       {"working_set_size", kindSize},
+	"peakws":   {"peak_working_set_size", kindSize},
+	"vm":       {"virtual_size", kindSize},
+	"peakvm":   {"peak_virtual_size", kindSize},
+	"faults":   {"page_fault_count", kindInt},
+	"snapshot": {"snapshot_id", kindInt},
+	"address":  {"current_process_address", kindAddress},
+	"agent":    {"snapshot_id", kindAgent},
+	"since":    {"created_at", kindSince},
+	"until":    {"created_at", kindUntil},
+}
+
+// Operators, longest first so ">=" is not read as ">"
+var operators = []string{">=", "<=", "!=", ":", "=", ">", "<"}
+
+var sizeUnits = map[string]int64{
+	"":   1,
+	"b":  1,
+	"kb": 1 << 10,
+	"mb": 1 << 20,
+	"gb": 1 << 30,
+	"tb": 1 << 40,
+}
+
+type term struct {
+	field  field
+	op     string
+	value  any
+	negate bool
+}
+
+// Query is a parsed search query
+type Query struct {
+	terms []term
+}
+
+// Error is a syntax or value error in a query
+type Error struct {
+	Term    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid search term %q: %s", e.Term, e.Message)
+}
+
+// Parse parses a query. Relative times such as since:7d are resolved
+// against now.
+func Parse(input string, now time.Time) (*Query, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, &Error{Term: input, Message: "query is empty"}
+	}
+	if len(tokens) > maxTerms {
+		return nil, &Error{Term: input, Message: fmt.Sprintf("queries are limited to %d terms", maxTerms)}
+	}
+
+	query := &Query{}
+	for _, token := range tokens {
+		t, err := parseTerm(token, now)
+		if err != nil {
+			return nil, err
+		}
+		query.terms = append(query.terms, t)
+	}
+	return query, nil
+}
+
+// tokenize splits input on spaces outside double quotes. Quotes are
+// removed.
+func tokenize(input string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuotes, started := false, false
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			started = true
+		case unicode.IsSpace(r) && !inQuotes:
+			if started {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if inQuotes {
+		return nil, &Error{Term: input, Message: "unterminated quote"}
+	}
+	if started {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+func parseTerm(token string, now time.Time) (term, error) {
+	text, negate := strings.CutPrefix(token, "-")
+
+	name, op, value := splitTerm(text)
+	if op == "" {
+		// Bare word: process name contains it
+		if text == "" {
+			return term{}, &Error{Term: token, Message: "empty term"}
+		}
+		return term{field: fields["name"], op: ":", value: "*" + text + "*", negate: negate}, nil
+	}
+
+	f, ok := fields[strings.ToLower(name)]
+	if !ok {
+		return term{}, &Error{Term: token, Message: fmt.Sprintf("unknown field %q", name)}
+	}
+	if value == "" {
+		return term{}, &Error{Term: token, Message: "missing value"}
+	}
+
+	t := term{field: f, op: op, negate: negate}
+	if op == "=" {
+		t.op = ":"
+	}
+
+	switch f.kind {
+	case kindGlob, kindAddress:
+		if t.op != ":" {
+			return term{}, &Error{Term: token, Message: "only : is supported for this field"}
+		}
+		t.value = value
+	case kindInt, kindAgent:
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return term{}, &Error{Term: token, Message: "value must be an integer"}
+		}
+		if f.kind == kindAgent && t.op != ":" {
+			return term{}, &Error{Term: token, Message: "only : is supported for this field"}
+		}
+		t.value = number
+	case kindSize:
+		size, err := parseSize(value)
+		if err != nil {
+			return term{}, &Error{Term: token, Message: err.Error()}
+		}
+		t.value = size
+	case kindSince, kindUntil:
+		if t.op != ":" {
+			return term{}, &Error{Term: token, Message: "only : is supported for this field"}
+		}
+		at, err := parseTime(value, now)
+		if err != nil {
+			return term{}, &Error{Term: token, Message: err.Error()}
+		}
+		t.value = at
+	}
+	return t, nil
+}
+
+// splitTerm splits "field<op>value". op is empty when the token has no
+// operator after a field name.
+func splitTerm(text string) (name, op, value string) {
+	end := strings.IndexFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if end <= 0 {
+		return "", "", ""
+	}
+	for _, candidate := range operators {
+		if rest, ok := strings.CutPrefix(text[end:], candidate); ok {
+			return text[:end], candidate, rest
+		}
+	}
+	return "", "", ""
+}
+
+func parseSize(value string) (int64, error) {
+	lower := strings.ToLower(value)
+	end := strings.IndexFunc(lower, func(r rune) bool {
+		return !unicode.IsDigit(r)
+	})
+	if end < 0 {
+		end = len(lower)
+	}
+
+	multiplier, ok := sizeUnits[lower[end:]]
+	if !ok || end == 0 {
+		return 0, fmt.Errorf("value must be a size such as 4096, 512KB or 2GB")
+	}
+	number, err := strconv.ParseInt(lower[:end], 10, 64)
+	if err != nil || number > (1<<62)/multiplier {
+		return 0, fmt.Errorf("size is out of range")
+	}
+	return number * multiplier, nil
+}
+
+// parseTime accepts relative times (30m, 12h, 7d, 2w), dates and RFC 3339
+// timestamps
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at.UTC(), nil
+	}
+	if at, err := time.Parse(time.DateOnly, value); err == nil {
+		return at, nil
+	}
+
+	units := map[byte]time.Duration{
+		'm': time.Minute,
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+	}
+	if len(value) > 1 {
+		if unit, ok := units[value[len(value)-1]]; ok {
+			number, err := strconv.Atoi(value[:len(value)-1])
+			if err == nil && number >= 0 && time.Duration(number) <= maxRelativeTime/unit {
+				return now.UTC().Add(-time.Duration(number) * unit), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("value must be a relative time such as 7d or 12h, a date or an RFC 3339 timestamp")
+}
+
+// Where returns the query as one SQL condition on process_info. arg binds a
+// value as a query parameter and returns its placeholder; values are never
+// written into the SQL.
+func (q *Query) Where(arg func(value any) string) string {
+	conditions := make([]string, len(q.terms))
+	for i, t := range q.terms {
+		condition := t.sql(arg)
+		if t.negate {
+			condition = "NOT (" + condition + ")"
+		}
+		conditions[i] = condition
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")"
+}
+
+func (t term) sql(arg func(any) string) string {
+	column := t.field.column
+
+	switch t.field.kind {
+	case kindGlob:
+		return column + " ILIKE " + arg(db.GlobToLike(t.value.(string)))
+	case kindAddress:
+		return "LOWER(" + column + ") = LOWER(" + arg(t.value) + ")"
+	case kindAgent:
+		return column + " IN (SELECT id FROM process_snapshots WHERE agent_id = " + arg(t.value) + ")"
+	case kindSince:
+		return column + " >= " + arg(t.value)
+	case kindUntil:
+		return column + " < " + arg(t.value)
+	}
+
+	op := t.op
+	if op == ":" {
+		op = "="
+	}
+	// The cast keeps large values from failing to bind to INTEGER columns
+	return column + " " + op + " " + arg(t.value) + "::BIGINT"
+}
@@ -0,0 +1,169 @@
+// Package stix holds the STIX 2.1 objects the API exports for incident
+// handoff (https://docs.oasis-open.org/cti/stix/v2.1/stix-v2.1.html) and
+// the helpers to build them.
+//
+// Object IDs are derived from what the object describes, so exporting the
+// same snapshot twice yields the same IDs and receivers can deduplicate.
+package stix
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const SpecVersion = "2.1"
+
+// MediaType is the content type of a STIX 2.1 bundle
+const MediaType = "application/stix+json;version=2.1"
+
+// Namespace of the deterministic IDs of cyber-observable objects, from the
+// specification
+var scoNamespace = uuid.MustParse("00abedb4-aa42-466c-9c01-fed23315a9b7")
+
+// Namespace of the IDs of the objects this API produces
+var apiNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/Rodrigaumm/api-go"))
+
+// Timestamp is a STIX timestamp: UTC with millisecond precision
+type Timestamp time.Time
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(t).UTC().Format("2006-01-02T15:04:05.000Z"))
+}
+
+// ID returns the ID of an object of objectType identified by key within
+// this API
+func ID(objectType, key string) string {
+	return objectType + "--" + uuid.NewSHA1(apiNamespace, []byte(objectType+"/"+key)).String()
+}
+
+// SCOID returns the ID of a cyber-observable object from its ID
+// contributing properties, as the specification prescribes
+func SCOID(objectType string, contributing map[string]any) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	// Maps encode with sorted keys, which is what the canonical form needs
+	// for the flat properties used here
+	encoder.Encode(contributing)
+	return objectType + "--" + uuid.NewSHA1(scoNamespace, bytes.TrimRight(buf.Bytes(), "\n")).String()
+}
+
+// Quote returns s as a string literal of the STIX patterning language
+func Quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+type Bundle struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Objects []any  `json:"objects"`
+}
+
+// NewBundle returns an empty bundle with a random ID, since a bundle is
+// only a transport
+func NewBundle() *Bundle {
+	return &Bundle{
+		Type:    "bundle",
+		ID:      "bundle--" + uuid.NewString(),
+		Objects: []any{},
+	}
+}
+
+func (b *Bundle) Add(objects ...any) {
+	b.Objects = append(b.Objects, objects...)
+}
+
+type ExternalReference struct {
+	SourceName  string `json:"source_name"`
+	Description string `json:"description,omitempty"`
+	ExternalID  string `json:"external_id,omitempty"`
+}
+
+// Common properties of domain and relationship objects
+type Common struct {
+	Type               string              `json:"type"`
+	SpecVersion        string              `json:"spec_version"`
+	ID                 string              `json:"id"`
+	CreatedByRef       string              `json:"created_by_ref,omitempty"`
+	Created            Timestamp           `json:"created"`
+	Modified           Timestamp           `json:"modified"`
+	Labels             []string            `json:"labels,omitempty"`
+	ExternalReferences []ExternalReference `json:"external_references,omitempty"`
+}
+
+// NewCommon returns the common properties of an object created, and last
+// modified, at created
+func NewCommon(objectType, id string, created time.Time) Common {
+	return Common{
+		Type:        objectType,
+		SpecVersion: SpecVersion,
+		ID:          id,
+		Created:     Timestamp(created),
+		Modified:    Timestamp(created),
+	}
+}
+
+type Identity struct {
+	Common
+	Name          string `json:"name"`
+	IdentityClass string `json:"identity_class"`
+}
+
+type ObservedData struct {
+	Common
+	FirstObserved  Timestamp `json:"first_observed"`
+	LastObserved   Timestamp `json:"last_observed"`
+	NumberObserved int       `json:"number_observed"`
+	ObjectRefs     []string  `json:"object_refs"`
+}
+
+type Indicator struct {
+	Common
+	Name           string    `json:"name"`
+	Description    string    `json:"description,omitempty"`
+	IndicatorTypes []string  `json:"indicator_types"`
+	Pattern        string    `json:"pattern"`
+	PatternType    string    `json:"pattern_type"`
+	ValidFrom      Timestamp `json:"valid_from"`
+}
+
+type Relationship struct {
+	Common
+	RelationshipType string `json:"relationship_type"`
+	SourceRef        string `json:"source_ref"`
+	TargetRef        string `json:"target_ref"`
+}
+
+// Process is a process cyber-observable object
+type Process struct {
+	Type        string     `json:"type"`
+	SpecVersion string     `json:"spec_version"`
+	ID          string     `json:"id"`
+	IsHidden    bool       `json:"is_hidden,omitempty"`
+	PID         int64      `json:"pid"`
+	CreatedTime *Timestamp `json:"created_time,omitempty"`
+	ImageRef    string     `json:"image_ref,omitempty"`
+	ParentRef   string     `json:"parent_ref,omitempty"`
+}
+
+// File is a file cyber-observable object, used for process images
+type File struct {
+	Type        string `json:"type"`
+	SpecVersion string `json:"spec_version"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+}
+
+// NewFile returns the file object of a file name
+func NewFile(name string) File {
+	return File{
+		Type:        "file",
+		SpecVersion: SpecVersion,
+		ID:          SCOID("file", map[string]any{"name": name}),
+		Name:        name,
+	}
+}
@@ -0,0 +1,272 @@
+// Package stream pushes live events (snapshots created, processes
+// persisted, capture jobs changing state, alerts raised) to the dashboards
+// of their users. Events go through PostgreSQL LISTEN/NOTIFY so every API
+// instance sees the events of the others.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go-api/internal/db"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Event types
+const (
+	EventSnapshotCreated    = "snapshot.created"
+	EventProcessesPersisted = "processes.persisted"
+	EventJobUpdated         = "job.updated"
+	EventAlertRaised        = "alert.raised"
+)
+
+// EventTypes lists the event types subscribers can filter on
+var EventTypes = []string{EventSnapshotCreated, EventProcessesPersisted, EventJobUpdated, EventAlertRaised}
+
+// Notification channel shared with the PublishStreamEvent query
+const channel = "api_stream"
+
+// pg_notify rejects payloads of 8000 bytes or more
+const maxPayload = 7999
+
+// Events buffered per subscriber. A subscriber that falls this far behind is
+// dropped; its client reconnects and reloads what it missed.
+const bufferSize = 64
+
+// Event is one change, delivered to subscribers of its user
+type Event struct {
+	Type       string          `json:"type"`
+	UserID     int64           `json:"userId"`
+	AgentID    *int64          `json:"agentId,omitempty"`
+	Data       json.RawMessage `json:"data"`
+	OccurredAt time.Time       `json:"occurredAt"`
+
+	// Truncated is set when Data was compacted to fit a notification; the
+	// full row is available from the REST API
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Compacter is implemented by event data that can shrink, for example by
+// cutting a long error message, when the event does not fit a notification
+type Compacter interface {
+	Compact() any
+}
+
+// Filter narrows the events of a subscription
+type Filter struct {
+	Types   []string    // empty for every type
+	AgentID pgtype.Int8 // unset for every agent
+}
+
+// Hub publishes events and fans out the events of every instance to the
+// local subscribers
+type Hub struct {
+	dbpool  *pgxpool.Pool
+	queries *db.Queries
+
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+func New(dbpool *pgxpool.Pool) *Hub {
+	return &Hub{
+		dbpool:      dbpool,
+		queries:     db.New(dbpool),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Start listens for events until ctx is cancelled, reconnecting when the
+// listening connection is lost. Events published while disconnected are
+// not delivered.
+func (h *Hub) Start(ctx context.Context) {
+	go func() {
+		backoff := time.Second
+		for {
+			err := h.listen(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warnf("stream: listener stopped, reconnecting in %s: %v", backoff, err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, 30*time.Second)
+		}
+	}()
+}
+
+func (h *Hub) listen(ctx context.Context) error {
+	conn, err := h.dbpool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// A listening connection must not go back to the pool
+	pgConn := conn.Hijack()
+	defer pgConn.Close(context.Background())
+
+	if _, err := pgConn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := pgConn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			log.Warnf("stream: ignoring malformed event: %v", err)
+			continue
+		}
+		h.dispatch(event)
+	}
+}
+
+// Publish sends an event about the data of userID to the subscribers of
+// every instance. Events too large for a notification are sent with their
+// data compacted, or dropped when the data cannot be compacted. Like
+// notifications, events are best-effort: failures are logged and never fail
+// the change they describe.
+func (h *Hub) Publish(ctx context.Context, eventType string, userID int64, agentID pgtype.Int8, data any) {
+	event := Event{
+		Type:       eventType,
+		UserID:     userID,
+		OccurredAt: time.Now().UTC(),
+	}
+	if agentID.Valid {
+		event.AgentID = &agentID.Int64
+	}
+
+	message, err := encode(event, data)
+	if err == nil && len(message) > maxPayload {
+		compacter, ok := data.(Compacter)
+		if !ok {
+			log.Warnf("stream: dropping %s event for user %d, %d bytes is too large", eventType, userID, len(message))
+			return
+		}
+		event.Truncated = true
+		message, err = encode(event, compacter.Compact())
+		if err == nil && len(message) > maxPayload {
+			log.Warnf("stream: dropping %s event for user %d, %d bytes is too large even compacted", eventType, userID, len(message))
+			return
+		}
+	}
+	if err != nil {
+		log.Warnf("stream: failed to marshal %s event: %v", eventType, err)
+		return
+	}
+
+	if err := h.queries.PublishStreamEvent(ctx, string(message)); err != nil {
+		log.Warnf("stream: failed to publish %s event for user %d: %v", eventType, userID, err)
+	}
+}
+
+func encode(event Event, data any) ([]byte, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	event.Data = payload
+	return json.Marshal(event)
+}
+
+// Subscribe registers a subscriber to the events of userID matching filter.
+// It must be closed when the client goes away.
+func (h *Hub) Subscribe(userID int64, filter Filter) *Subscription {
+	subscription := &Subscription{
+		hub:    h,
+		userID: userID,
+		filter: filter,
+		events: make(chan Event, bufferSize),
+	}
+
+	h.mu.Lock()
+	h.subscribers[subscription] = struct{}{}
+	h.mu.Unlock()
+
+	return subscription
+}
+
+func (h *Hub) dispatch(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for subscription := range h.subscribers {
+		if !subscription.matches(event) {
+			continue
+		}
+
+		select {
+		case subscription.events <- event:
+		default:
+			log.Warnf("stream: dropping subscriber of user %d, %d events behind", subscription.userID, bufferSize)
+			h.remove(subscription)
+		}
+	}
+}
+
+// remove unregisters a subscription and closes its channel. The caller
+// holds h.mu.
+func (h *Hub) remove(subscription *Subscription) {
+	if _, ok := h.subscribers[subscription]; !ok {
+		return
+	}
+	delete(h.subscribers, subscription)
+	close(subscription.events)
+}
+
+// Subscription receives the events of one client
+type Subscription struct {
+	hub    *Hub
+	userID int64
+	filter Filter
+	events chan Event
+}
+
+// Events returns the events of the subscription. It is closed when the
+// subscriber falls too far behind or is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close unregisters the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+func (s *Subscription) matches(event Event) bool {
+	if event.UserID != s.userID {
+		return false
+	}
+	if len(s.filter.Types) > 0 && !slices.Contains(s.filter.Types, event.Type) {
+		return false
+	}
+	if s.filter.AgentID.Valid && (event.AgentID == nil || *event.AgentID != s.filter.AgentID.Int64) {
+		return false
+	}
+	return true
+}
+
+// ValidateTypes checks that every type is a known event type
+func ValidateTypes(types []string) error {
+	for _, eventType := range types {
+		if !slices.Contains(EventTypes, eventType) {
+			return fmt.Errorf("unknown event type %q", eventType)
+		}
+	}
+	return nil
+}
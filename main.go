@@ -3,9 +3,17 @@ package main
 import (
 	"context"
 	"os"
+	"time"
 
 	"go-api/internal/config"
 	"go-api/internal/handlers"
+	"go-api/internal/jobs"
+	"go-api/internal/jwtkeys"
+	"go-api/internal/notify"
+	"go-api/internal/oidcauth"
+	"go-api/internal/outbound"
+	"go-api/internal/scheduler"
+	"go-api/internal/stream"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
@@ -17,6 +25,28 @@ import (
 func main() {
 	// Load configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+
+	// Asymmetric keys replace the shared secret when configured
+	var jwtKeys *jwtkeys.KeySet
+	if len(cfg.JWTKeyFiles) > 0 {
+		keys, err := jwtkeys.LoadPEMFiles(cfg.JWTKeyFiles)
+		if err != nil {
+			log.Fatal("Invalid JWT keys: ", err)
+		}
+		jwtKeys = keys
+	} else if cfg.IsDevelopment() && os.Getenv("JWT_SECRET") == "" {
+		log.Warn("JWT_SECRET is not set, using the insecure development secret")
+	}
+
+	handlers.ConfigureTokens(handlers.TokenConfig{
+		Secret:          cfg.JWTSecret,
+		Keys:            jwtKeys,
+		AccessTokenTTL:  cfg.AccessTokenTTL,
+		RefreshTokenTTL: cfg.RefreshTokenTTL,
+	})
 
 	// Connect to database
 	ctx := context.Background()
@@ -26,6 +56,72 @@ func main() {
 	}
 	defer dbpool.Close()
 
+	// Destinations agents and notification channels may be reached at
+	policy, err := outbound.NewPolicy(outbound.Options{
+		AllowedSchemes: cfg.OutboundAllowedSchemes,
+		AllowedHosts:   cfg.OutboundAllowedHosts,
+		AllowedCIDRs:   cfg.OutboundAllowedCIDRs,
+		BlockedCIDRs:   cfg.OutboundBlockedCIDRs,
+		BlockPrivate:   cfg.OutboundBlockPrivate,
+	})
+	if err != nil {
+		log.Fatal("Invalid outbound policy:", err)
+	}
+
+	// Failed captures, raised alerts and offline agents are delivered to
+	// the notification channels of their users
+	notifier := notify.New(dbpool, notify.Options{
+		Policy: policy,
+		SMTP: notify.SMTPOptions{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		},
+		Workers: cfg.NotifyWorkers,
+		Retries: cfg.NotifyRetries,
+	})
+	notifier.Start(ctx)
+
+	// Live events for dashboards, shared by every instance through
+	// LISTEN/NOTIFY
+	streamHub := stream.New(dbpool)
+	streamHub.Start(ctx)
+
+	// Capture job workers and the periodic capture scheduler share the
+	// webhook handler used by the HTTP routes
+	jobPool := jobs.New(dbpool, cfg.JobWorkers)
+	webhookHandler := handlers.NewWebhookHandler(dbpool, jobPool, policy, notifier, streamHub, cfg.CPUTimeUnit)
+	jobPool.Start(ctx, webhookHandler, webhookHandler)
+
+	captureScheduler := scheduler.New(dbpool, webhookHandler, cfg.SchedulerTick)
+	captureScheduler.Start(ctx)
+
+	// Expired refresh tokens and revocations are no longer needed
+	handlers.StartTokenCleanup(ctx, dbpool, time.Hour)
+
+	// OIDC single sign-on, only when an issuer is configured
+	var oidcHandler *handlers.OIDCHandler
+	if cfg.OIDCEnabled() {
+		provider := oidcauth.New(oidcauth.Options{
+			IssuerURL:    cfg.OIDCIssuerURL,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+			GroupsClaim:  cfg.OIDCGroupsClaim,
+		})
+		oidcHandler, err = handlers.NewOIDCHandler(dbpool, provider, handlers.OIDCOptions{
+			RoleMapping:  cfg.OIDCRoleMapping,
+			DefaultRole:  cfg.OIDCDefaultRole,
+			SecureCookie: !cfg.IsDevelopment(),
+		})
+		if err != nil {
+			log.Fatal("Invalid OIDC configuration: ", err)
+		}
+	}
+
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
@@ -41,7 +137,7 @@ func main() {
 	app.Use(logger.New())
 	app.Use(cors.New())
 
-	setupRoutes(app, dbpool)
+	setupRoutes(app, dbpool, webhookHandler, policy, notifier, streamHub, oidcHandler)
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -52,7 +148,7 @@ func main() {
 	log.Fatal(app.Listen(":" + port))
 }
 
-func setupRoutes(app *fiber.App, dbpool *pgxpool.Pool) {
+func setupRoutes(app *fiber.App, dbpool *pgxpool.Pool, webhookHandler *handlers.WebhookHandler, policy *outbound.Policy, notifier *notify.Notifier, streamHub *stream.Hub, oidcHandler *handlers.OIDCHandler) {
 	// Health check
 	app.Get("/health", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -61,52 +157,149 @@ func setupRoutes(app *fiber.App, dbpool *pgxpool.Pool) {
 		})
 	})
 
+	// Public keys for verifying access tokens
+	app.Get("/.well-known/jwks.json", handlers.GetJWKS)
+
 	// API v1
 	api := app.Group("/api/v1")
 
-	// Auth routes (no JWT required)
+	// Auth routes (login and refresh need no JWT)
 	authHandler := handlers.NewAuthHandler(dbpool)
 	auth := api.Group("/auth")
 	auth.Post("/login", authHandler.Login)
+	auth.Post("/refresh", authHandler.Refresh)
+	auth.Post("/logout", handlers.JWTMiddleware(dbpool), handlers.RejectAPIKeys(), authHandler.Logout)
+	if oidcHandler != nil {
+		auth.Get("/oidc/login", oidcHandler.Login)
+		auth.Get("/oidc/callback", oidcHandler.Callback)
+	}
+
+	// API key routes (JWT required, keys cannot manage keys)
+	apiKeyHandler := handlers.NewAPIKeyHandler(dbpool)
+	apiKeys := api.Group("/api-keys")
+	apiKeys.Use(handlers.JWTMiddleware(dbpool), handlers.RejectAPIKeys())
+	apiKeys.Get("/", apiKeyHandler.GetAPIKeys)
+	apiKeys.Post("/", apiKeyHandler.CreateAPIKey)
+	apiKeys.Delete("/:id", apiKeyHandler.DeleteAPIKey)
 
-	// User routes (no JWT required for now)
+	// User routes (JWT required, management is admin-only)
+	// POST /users without a token only works while no user exists, to
+	// bootstrap the first admin
 	userHandler := handlers.NewUserHandler(dbpool)
 	users := api.Group("/users")
-	users.Get("/", userHandler.GetUsers)
+	users.Post("/", handlers.OptionalJWTMiddleware(dbpool), handlers.RejectAPIKeys(), userHandler.CreateUser)
+	users.Use(handlers.JWTMiddleware(dbpool), handlers.RejectAPIKeys())
+	users.Get("/", handlers.RequirePermission(handlers.PermManageUsers), userHandler.GetUsers)
 	users.Get("/:id", userHandler.GetUser)
-	users.Post("/", userHandler.CreateUser)
 	users.Put("/:id", userHandler.UpdateUser)
-	users.Delete("/:id", userHandler.DeleteUser)
+	users.Put("/:id/role", handlers.RequirePermission(handlers.PermManageUsers), userHandler.UpdateUserRole)
+	users.Delete("/:id", handlers.RequirePermission(handlers.PermManageUsers), userHandler.DeleteUser)
+
+	// Agent inventory routes (JWT required)
+	agentHandler := handlers.NewAgentHandler(dbpool, policy)
+	agents := api.Group("/agents")
+	agents.Use(handlers.JWTMiddleware(dbpool), handlers.RequirePermission(handlers.PermReadData))
+	agents.Get("/", agentHandler.GetAgents)
+	agents.Get("/:id", agentHandler.GetAgent)
+	agents.Get("/:id/snapshots", agentHandler.GetAgentSnapshots)
+	agents.Post("/", handlers.RequirePermission(handlers.PermManageAgents), agentHandler.CreateAgent)
+	agents.Put("/:id", handlers.RequirePermission(handlers.PermManageAgents), agentHandler.UpdateAgent)
+	agents.Put("/:id/credentials", handlers.RequirePermission(handlers.PermManageAgents), agentHandler.UpdateAgentCredentials)
+	agents.Delete("/:id", handlers.RequirePermission(handlers.PermManageAgents), agentHandler.DeleteAgent)
+
+	// Capture schedule routes (JWT required)
+	scheduleHandler := handlers.NewScheduleHandler(dbpool)
+	schedules := api.Group("/schedules")
+	schedules.Use(handlers.JWTMiddleware(dbpool), handlers.RequirePermission(handlers.PermReadData))
+	schedules.Get("/", scheduleHandler.GetSchedules)
+	schedules.Get("/:id", scheduleHandler.GetSchedule)
+	schedules.Post("/", handlers.RequirePermission(handlers.PermCapture), scheduleHandler.CreateSchedule)
+	schedules.Put("/:id", handlers.RequirePermission(handlers.PermCapture), scheduleHandler.UpdateSchedule)
+	schedules.Post("/:id/pause", handlers.RequirePermission(handlers.PermCapture), scheduleHandler.PauseSchedule)
+	schedules.Post("/:id/resume", handlers.RequirePermission(handlers.PermCapture), scheduleHandler.ResumeSchedule)
+	schedules.Delete("/:id", handlers.RequirePermission(handlers.PermCapture), scheduleHandler.DeleteSchedule)
+
+	// Capture job routes (JWT required)
+	jobHandler := handlers.NewJobHandler(dbpool)
+	jobRoutes := api.Group("/jobs")
+	jobRoutes.Use(handlers.JWTMiddleware(dbpool), handlers.RequirePermission(handlers.PermReadData))
+	jobRoutes.Get("/:id", jobHandler.GetJob)
+
+	// Alert routes (JWT required)
+	alertHandler := handlers.NewAlertHandler(dbpool)
+	alerts := api.Group("/alerts")
+	alerts.Use(handlers.JWTMiddleware(dbpool), handlers.RequirePermission(handlers.PermReadData))
+
+	// Rule routes (before /:id)
+	alerts.Get("/rules", alertHandler.GetRules)
+	alerts.Get("/rules/:id", alertHandler.GetRule)
+	alerts.Post("/rules", handlers.RequirePermission(handlers.PermManageAlerts), alertHandler.CreateRule)
+	alerts.Put("/rules/:id", handlers.RequirePermission(handlers.PermManageAlerts), alertHandler.UpdateRule)
+	alerts.Delete("/rules/:id", handlers.RequirePermission(handlers.PermManageAlerts), alertHandler.DeleteRule)
+
+	alerts.Get("/", alertHandler.GetAlerts)
+	alerts.Get("/:id", alertHandler.GetAlert)
+	alerts.Post("/:id/acknowledge", handlers.RequirePermission(handlers.PermManageAlerts), alertHandler.AcknowledgeAlert)
+	alerts.Post("/:id/reopen", handlers.RequirePermission(handlers.PermManageAlerts), alertHandler.ReopenAlert)
+
+	// Event stream (JWT required, also accepted in ?access_token=)
+	streamHandler := handlers.NewStreamHandler(dbpool, streamHub)
+	api.Get("/stream", handlers.StreamTokenFromQuery, handlers.JWTMiddleware(dbpool), handlers.RequirePermission(handlers.PermReadData), streamHandler.Stream)
+
+	// Notification channel routes (JWT required)
+	notificationHandler := handlers.NewNotificationHandler(dbpool, notifier)
+	notifications := api.Group("/notifications")
+	notifications.Use(handlers.JWTMiddleware(dbpool), handlers.RequirePermission(handlers.PermReadData))
+	notifications.Get("/channels", notificationHandler.GetChannels)
+	notifications.Get("/channels/:id", notificationHandler.GetChannel)
+	notifications.Post("/channels", handlers.RequirePermission(handlers.PermManageAlerts), notificationHandler.CreateChannel)
+	notifications.Put("/channels/:id", handlers.RequirePermission(handlers.PermManageAlerts), notificationHandler.UpdateChannel)
+	notifications.Delete("/channels/:id", handlers.RequirePermission(handlers.PermManageAlerts), notificationHandler.DeleteChannel)
+	notifications.Post("/channels/:id/test", handlers.RequirePermission(handlers.PermManageAlerts), notificationHandler.TestChannel)
 
 	// Process routes (JWT required)
 	processHandler := handlers.NewProcessHandler(dbpool)
 	processes := api.Group("/processes")
-	processes.Use(handlers.JWTMiddleware())
+	processes.Use(handlers.JWTMiddleware(dbpool), handlers.RequirePermission(handlers.PermReadData))
 
 	// Snapshot routes
 	processes.Get("/snapshots", processHandler.GetSnapshots)
 	processes.Get("/snapshots/type/:type", processHandler.GetSnapshotsByType)
+	processes.Get("/snapshots/export", processHandler.ExportSnapshots)
 	processes.Get("/snapshots/:id", processHandler.GetSnapshot)
 	processes.Get("/snapshots/:id/processes", processHandler.GetSnapshotProcesses)
 	processes.Get("/snapshots/:id/queries", processHandler.GetSnapshotQueries)
-	processes.Delete("/snapshots/:id", processHandler.DeleteSnapshot)
+	processes.Get("/snapshots/:id/diff/:otherId", processHandler.GetSnapshotDiff)
+	processes.Get("/snapshots/:id/integrity", processHandler.GetSnapshotIntegrity)
+	processes.Get("/snapshots/:id/tree", processHandler.GetSnapshotTree)
+	processes.Get("/snapshots/:id/rates", processHandler.GetSnapshotRates)
+	processes.Get("/snapshots/:id/export", processHandler.ExportSnapshot)
+	processes.Get("/snapshots/:id/stix", processHandler.GetSnapshotSTIX)
+	processes.Delete("/snapshots/:id", handlers.RequirePermission(handlers.PermDeleteData), processHandler.DeleteSnapshot)
 
 	// Query history and statistics
 	processes.Get("/queries/history", processHandler.GetQueryHistory)
 	processes.Get("/statistics", processHandler.GetStatistics)
 
+	// Process search and timeline (before /:id)
+	processes.Get("/search", processHandler.SearchProcesses)
+	processes.Get("/timeline", processHandler.GetProcessTimeline)
+
 	// Process info routes
 	processes.Get("/", processHandler.GetProcessInfos)
 	processes.Get("/pid/:pid", processHandler.GetProcessInfosByProcessID)
 	processes.Get("/:id", processHandler.GetProcessInfo)
-	processes.Delete("/:id", processHandler.DeleteProcessInfo)
+	processes.Delete("/:id", handlers.RequirePermission(handlers.PermDeleteData), processHandler.DeleteProcessInfo)
 
 	// Webhook routes (optional JWT - works with or without authentication)
-	// If authenticated: persists to user's snapshot
+	// If authenticated: persists to user's snapshot (viewers are rejected)
 	// If not authenticated: returns data without persisting
-	webhookHandler := handlers.NewWebhookHandler(dbpool)
 	webhook := api.Group("/webhook")
-	webhook.Use(handlers.OptionalJWTMiddleware()) // Optional authentication
+	webhook.Use(handlers.OptionalJWTMiddleware(dbpool)) // Optional authentication
+	webhook.Use(handlers.RequirePermissionWhenAuthenticated(handlers.PermCapture))
 	webhook.Post("/iterate-processes", webhookHandler.IterateProcesses)
 	webhook.Post("/process-by-pid", webhookHandler.ProcessByPid)
+
+	// Import of offline captures (JWT required)
+	api.Post("/snapshots/import", handlers.JWTMiddleware(dbpool), handlers.RequirePermission(handlers.PermCapture), webhookHandler.ImportSnapshot)
 }
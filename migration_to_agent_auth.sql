@@ -0,0 +1,11 @@
+-- Migration to add agent request signing and mTLS credentials
+-- Requires migration_to_agents.sql
+
+BEGIN;
+
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS signing_secret TEXT;
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS client_cert_pem TEXT;
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS client_key_pem TEXT;
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS ca_cert_pem TEXT;
+
+COMMIT;
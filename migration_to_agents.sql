@@ -0,0 +1,85 @@
+-- Migration to add the registered agent inventory
+-- Run this migration if you have existing snapshots that reference webhook_url directly
+--
+-- Notes:
+-- - Every distinct webhook_url becomes an agent whose base_url is the URL
+--   normalized the way the API normalizes base_url on registration (scheme
+--   and host lowercased, default ports and trailing slashes removed, path
+--   case kept), so legacy webhook_url callers resolve to the migrated agent
+--   and registering the same URL again does not create a duplicate.
+-- - Migrated agents are named after their normalized URL; rename them with
+--   PUT /api/v1/agents/:id.
+-- - URLs that are not http(s) are left without an agent.
+
+BEGIN;
+
+-- Step 0: Same normalization as normalizeAgentURL
+-- (internal/handlers/agent_handler.go); NULL when the URL is not http(s)
+CREATE OR REPLACE FUNCTION pg_temp.normalize_agent_url(raw TEXT) RETURNS TEXT AS $$
+    SELECT CASE
+        WHEN m IS NULL OR LOWER(m[1]) NOT IN ('http', 'https') OR m[2] IN ('', '[]') THEN NULL
+        ELSE LOWER(m[1]) || '://' || LOWER(m[2])
+            || CASE
+                WHEN COALESCE(m[3], '') = ''
+                    OR (LOWER(m[1]) = 'http' AND m[3] = '80')
+                    OR (LOWER(m[1]) = 'https' AND m[3] = '443') THEN ''
+                ELSE ':' || m[3]
+            END
+            || RTRIM(m[4], '/')
+    END
+    FROM (
+        SELECT regexp_match(
+            BTRIM(raw, E' \t\r\n'),
+            '^([A-Za-z][A-Za-z0-9+.-]*)://(?:[^@/?#]*@)?(\[[^]]*\]|[^:/?#]*)(?::([0-9]*))?([^?#]*)'
+        ) AS m
+    ) parsed
+$$ LANGUAGE SQL IMMUTABLE;
+
+-- Step 1: Create agents table
+CREATE TABLE IF NOT EXISTS agents (
+    id BIGSERIAL PRIMARY KEY,
+    owner_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    base_url TEXT NOT NULL UNIQUE,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    os_build VARCHAR(255),
+    last_seen_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Step 2: Add agent_id to snapshots and queries
+ALTER TABLE process_snapshots ADD COLUMN IF NOT EXISTS agent_id BIGINT REFERENCES agents(id) ON DELETE SET NULL;
+ALTER TABLE process_queries ADD COLUMN IF NOT EXISTS agent_id BIGINT REFERENCES agents(id) ON DELETE SET NULL;
+
+-- Step 3: Register one agent per distinct (normalized) webhook_url already in use
+INSERT INTO agents (owner_id, name, base_url, last_seen_at)
+SELECT
+    (ARRAY_AGG(user_id ORDER BY created_at) FILTER (WHERE user_id IS NOT NULL))[1],
+    pg_temp.normalize_agent_url(webhook_url),
+    pg_temp.normalize_agent_url(webhook_url),
+    MAX(created_at) FILTER (WHERE success)
+FROM process_snapshots
+WHERE pg_temp.normalize_agent_url(webhook_url) IS NOT NULL
+GROUP BY pg_temp.normalize_agent_url(webhook_url)
+ON CONFLICT (base_url) DO NOTHING;
+
+-- Step 4: Link existing snapshots and queries to their agents
+UPDATE process_snapshots ps
+SET agent_id = a.id
+FROM agents a
+WHERE ps.agent_id IS NULL
+AND a.base_url = pg_temp.normalize_agent_url(ps.webhook_url);
+
+UPDATE process_queries pq
+SET agent_id = a.id
+FROM agents a
+WHERE pq.agent_id IS NULL
+AND a.base_url = pg_temp.normalize_agent_url(pq.webhook_url);
+
+-- Step 5: Indexes
+CREATE INDEX IF NOT EXISTS idx_process_snapshots_agent_id ON process_snapshots(agent_id);
+CREATE INDEX IF NOT EXISTS idx_process_queries_agent_id ON process_queries(agent_id);
+CREATE INDEX IF NOT EXISTS idx_agents_owner_id ON agents(owner_id);
+
+COMMIT;
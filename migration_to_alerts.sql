@@ -0,0 +1,46 @@
+-- Migration to add alert rules and alerts
+-- Requires migration_to_snapshots.sql
+
+BEGIN;
+
+-- Detection rules, run against every new snapshot of their owner. process
+-- and parent rules are written in the process search language; integrity
+-- rules fire on EPROCESS list inconsistencies of iteration snapshots.
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('process', 'parent', 'integrity')),
+    query TEXT,                -- process and parent rules: processes the rule applies to
+    parent_query TEXT,         -- parent rules: search query the parent must match
+    issue_type VARCHAR(32),    -- integrity rules: issue to alert on, NULL for any
+    severity VARCHAR(20) NOT NULL DEFAULT 'medium' CHECK (severity IN ('low', 'medium', 'high', 'critical')),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Rule matches. Rule name and severity are copied so alerts outlive edits
+-- and deletion of their rule.
+CREATE TABLE IF NOT EXISTS alerts (
+    id BIGSERIAL PRIMARY KEY,
+    rule_id BIGINT REFERENCES alert_rules(id) ON DELETE SET NULL,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    snapshot_id BIGINT NOT NULL REFERENCES process_snapshots(id) ON DELETE CASCADE,
+    process_info_id BIGINT REFERENCES process_info(id) ON DELETE SET NULL,
+    rule_name VARCHAR(255) NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    message TEXT NOT NULL,
+    process_id BIGINT,
+    process_name VARCHAR(255),
+    acknowledged_at TIMESTAMP,
+    acknowledged_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_rules_user_id ON alert_rules(user_id);
+CREATE INDEX IF NOT EXISTS idx_alerts_snapshot_id ON alerts(snapshot_id);
+CREATE INDEX IF NOT EXISTS idx_alerts_user_page ON alerts(user_id, created_at DESC, id DESC);
+
+COMMIT;
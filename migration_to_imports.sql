@@ -0,0 +1,16 @@
+-- Migration to import offline process captures
+-- Requires migration_to_agents.sql
+
+BEGIN;
+
+-- Imported captures were not taken through an agent URL
+ALTER TABLE process_snapshots ALTER COLUMN webhook_url DROP NOT NULL;
+
+-- Provenance of a snapshot: 'agent' for captures made through the API,
+-- 'import' for offline captures uploaded later. created_at of an imported
+-- snapshot is the original capture time.
+ALTER TABLE process_snapshots ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'agent';
+ALTER TABLE process_snapshots ADD COLUMN IF NOT EXISTS source_host TEXT;
+ALTER TABLE process_snapshots ADD COLUMN IF NOT EXISTS imported_at TIMESTAMP;
+
+COMMIT;
@@ -0,0 +1,22 @@
+-- Migration to add asynchronous capture jobs
+-- Requires migration_to_agents.sql
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS capture_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    agent_id BIGINT NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'succeeded', 'failed')),
+    snapshot_id BIGINT REFERENCES process_snapshots(id) ON DELETE SET NULL,
+    error_message TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_capture_jobs_user_id ON capture_jobs(user_id);
+CREATE INDEX IF NOT EXISTS idx_capture_jobs_queued ON capture_jobs(id) WHERE status = 'queued';
+
+COMMIT;
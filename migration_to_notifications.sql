@@ -0,0 +1,28 @@
+-- Migration to add notification channels and agent offline tracking
+-- Requires migration_to_agents.sql
+
+BEGIN;
+
+ALTER TABLE agents ADD COLUMN IF NOT EXISTS offline_since TIMESTAMP;
+
+-- Outbound notification channels. A channel delivers the events of its
+-- owner whose type is in events; config holds the destination, whose shape
+-- depends on kind.
+CREATE TABLE IF NOT EXISTS notification_channels (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('webhook', 'slack', 'email', 'syslog')),
+    config JSONB NOT NULL DEFAULT '{}',
+    events TEXT[] NOT NULL DEFAULT '{}',
+    min_severity VARCHAR(20) NOT NULL DEFAULT 'low' CHECK (min_severity IN ('low', 'medium', 'high', 'critical')),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_attempt_at TIMESTAMP,
+    last_error TEXT, -- NULL when the last delivery succeeded
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_channels_user_id ON notification_channels(user_id);
+
+COMMIT;
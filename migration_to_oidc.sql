@@ -0,0 +1,12 @@
+-- Migration to add OIDC single sign-on
+-- Requires migration_to_roles.sql
+
+BEGIN;
+
+-- Users provisioned through OIDC are linked to the IdP by issuer and subject
+ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_issuer TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_subject TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_oidc_subject ON users(oidc_issuer, oidc_subject);
+
+COMMIT;
@@ -0,0 +1,10 @@
+-- Migration to add indexes for keyset pagination of list endpoints
+
+BEGIN;
+
+-- Default order of the lists: newest first, id breaks ties
+CREATE INDEX IF NOT EXISTS idx_process_snapshots_user_page ON process_snapshots(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_process_info_user_page ON process_info(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_process_queries_user_page ON process_queries(user_id, created_at DESC, id DESC);
+
+COMMIT;
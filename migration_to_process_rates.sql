@@ -0,0 +1,27 @@
+-- Migration to add derived per-second process rates
+-- Requires migration_to_agents.sql
+
+BEGIN;
+
+-- Per-second rates of a process between two consecutive iteration snapshots
+-- of the same agent, derived from the cumulative counters of process_info
+CREATE TABLE IF NOT EXISTS process_rates (
+    process_info_id BIGINT PRIMARY KEY REFERENCES process_info(id) ON DELETE CASCADE,
+    previous_process_info_id BIGINT REFERENCES process_info(id) ON DELETE SET NULL,
+    snapshot_id BIGINT NOT NULL REFERENCES process_snapshots(id) ON DELETE CASCADE,
+    previous_snapshot_id BIGINT REFERENCES process_snapshots(id) ON DELETE SET NULL,
+    interval_seconds DOUBLE PRECISION NOT NULL,
+    cpu_percent DOUBLE PRECISION,
+    read_bytes_per_sec DOUBLE PRECISION,
+    write_bytes_per_sec DOUBLE PRECISION,
+    other_bytes_per_sec DOUBLE PRECISION,
+    read_ops_per_sec DOUBLE PRECISION,
+    write_ops_per_sec DOUBLE PRECISION,
+    page_faults_per_sec DOUBLE PRECISION,
+    handle_growth_per_sec DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_process_rates_snapshot_id ON process_rates(snapshot_id);
+
+COMMIT;
@@ -0,0 +1,16 @@
+-- Migration to add user roles (admin, analyst, viewer)
+-- Existing users become analysts so current workflows keep working, and the
+-- oldest user is promoted to admin so someone can manage accounts.
+
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'viewer'
+    CHECK (role IN ('admin', 'analyst', 'viewer'));
+
+UPDATE users SET role = 'analyst' WHERE role = 'viewer';
+
+UPDATE users SET role = 'admin'
+WHERE id = (SELECT id FROM users ORDER BY created_at ASC, id ASC LIMIT 1)
+  AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'admin');
+
+COMMIT;
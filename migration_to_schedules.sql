@@ -0,0 +1,26 @@
+-- Migration to add scheduled periodic captures per agent
+-- Requires migration_to_agents.sql
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS capture_schedules (
+    id BIGSERIAL PRIMARY KEY,
+    agent_id BIGINT NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    cron_expression TEXT,
+    interval_seconds INTEGER,
+    paused BOOLEAN NOT NULL DEFAULT false,
+    next_run_at TIMESTAMP NOT NULL,
+    last_run_at TIMESTAMP,
+    last_snapshot_id BIGINT REFERENCES process_snapshots(id) ON DELETE SET NULL,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    CONSTRAINT capture_schedule_spec CHECK ((cron_expression IS NULL) <> (interval_seconds IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_capture_schedules_user_id ON capture_schedules(user_id);
+CREATE INDEX IF NOT EXISTS idx_capture_schedules_due ON capture_schedules(next_run_at) WHERE NOT paused;
+
+COMMIT;
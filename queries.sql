@@ -4,18 +4,36 @@ SELECT * FROM users WHERE id = $1 LIMIT 1;
 -- name: GetUserByName :one
 SELECT * FROM users WHERE name = $1 LIMIT 1;
 
+-- name: GetUserByOIDCSubject :one
+SELECT * FROM users WHERE oidc_issuer = $1 AND oidc_subject = $2 LIMIT 1;
+
 -- name: GetUsers :many
 SELECT * FROM users ORDER BY created_at DESC;
 
 -- name: CreateUser :one
-INSERT INTO users (name, password) VALUES ($1, $2) RETURNING *;
+INSERT INTO users (name, password, role) VALUES ($1, $2, $3) RETURNING *;
+
+-- name: CreateOIDCUser :one
+INSERT INTO users (name, password, role, oidc_issuer, oidc_subject) VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: CreateFirstUser :one
+INSERT INTO users (name, password, role)
+SELECT $1, $2, 'admin'
+WHERE NOT EXISTS (SELECT 1 FROM users)
+RETURNING *;
 
 -- name: UpdateUser :one
 UPDATE users SET name = $1, password = $2, updated_at = NOW() WHERE id = $3 RETURNING *;
 
+-- name: UpdateUserRole :one
+UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2 RETURNING *;
+
 -- name: DeleteUser :exec
 DELETE FROM users WHERE id = $1;
 
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users WHERE role = 'admin';
+
 -- ============================================
 -- Process Snapshots Queries
 -- ============================================
@@ -27,20 +45,26 @@ INSERT INTO process_snapshots (
     snapshot_type,
     process_count,
     success,
-    error_message
-) VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+    error_message,
+    agent_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
 
 -- name: GetProcessSnapshot :one
 SELECT * FROM process_snapshots WHERE id = $1 LIMIT 1;
 
 -- name: GetProcessSnapshotsByUser :many
 SELECT * FROM process_snapshots 
-WHERE user_id = $1 OR user_id IS NULL
+WHERE user_id = sqlc.arg(user_id) OR (user_id IS NULL AND sqlc.arg(include_unowned)::BOOLEAN)
 ORDER BY created_at DESC;
 
 -- name: GetProcessSnapshotsByType :many
 SELECT * FROM process_snapshots 
-WHERE (user_id = $1 OR user_id IS NULL) AND snapshot_type = $2
+WHERE (user_id = sqlc.arg(user_id) OR (user_id IS NULL AND sqlc.arg(include_unowned)::BOOLEAN)) AND snapshot_type = sqlc.arg(snapshot_type)
+ORDER BY created_at DESC;
+
+-- name: GetProcessSnapshotsByAgent :many
+SELECT * FROM process_snapshots 
+WHERE (user_id = sqlc.arg(user_id) OR (user_id IS NULL AND sqlc.arg(include_unowned)::BOOLEAN)) AND agent_id = sqlc.arg(agent_id)
 ORDER BY created_at DESC;
 
 -- name: UpdateProcessSnapshotCount :exec
@@ -94,22 +118,57 @@ INSERT INTO process_info (
     $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31
 ) RETURNING *;
 
--- name: UpdateNextProcess :one
-UPDATE process_info
-SET next_id = $1, next_process_id = $2, next_process_name = $3, next_process_eprocess_address = $4
-WHERE id = $5 RETURNING *;
+-- name: ReserveProcessInfoIDs :many
+SELECT nextval(pg_get_serial_sequence('process_info', 'id'))::BIGINT AS id
+FROM generate_series(1, sqlc.arg(count)::INTEGER);
 
--- name: UpdatePreviousProcess :one
-UPDATE process_info
-SET previous_id = $1, previous_process_id = $2, previous_process_name = $3, previous_process_eprocess_address = $4
-WHERE id = $5 RETURNING *;
+-- name: CreateProcessInfos :copyfrom
+INSERT INTO process_info (
+    id,
+    snapshot_id,
+    user_id,
+    process_id,
+    parent_process_id,
+    process_name,
+    thread_count,
+    handle_count,
+    base_priority,
+    create_time,
+    user_time,
+    kernel_time,
+    working_set_size,
+    peak_working_set_size,
+    virtual_size,
+    peak_virtual_size,
+    read_operation_count,
+    write_operation_count,
+    other_operation_count,
+    read_transfer_count,
+    write_transfer_count,
+    other_transfer_count,
+    page_fault_count,
+    current_process_address,
+    next_process_eprocess_address,
+    next_process_name,
+    next_process_id,
+    next_id,
+    previous_process_eprocess_address,
+    previous_process_name,
+    previous_process_id,
+    previous_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+    $11, $12, $13, $14, $15, $16, $17, $18, $19, $20,
+    $21, $22, $23, $24, $25, $26, $27, $28, $29, $30,
+    $31, $32
+);
 
 -- name: GetProcessInfo :one
 SELECT * FROM process_info WHERE id = $1 LIMIT 1;
 
 -- name: GetProcessInfosByUser :many
 SELECT * FROM process_info 
-WHERE user_id = $1 OR user_id IS NULL
+WHERE user_id = sqlc.arg(user_id) OR (user_id IS NULL AND sqlc.arg(include_unowned)::BOOLEAN)
 ORDER BY created_at DESC;
 
 -- name: GetProcessInfosBySnapshot :many
@@ -119,7 +178,7 @@ ORDER BY process_id ASC;
 
 -- name: GetProcessInfosByProcessID :many
 SELECT * FROM process_info 
-WHERE (user_id = $1 OR user_id IS NULL) AND process_id = $2
+WHERE (user_id = sqlc.arg(user_id) OR (user_id IS NULL AND sqlc.arg(include_unowned)::BOOLEAN)) AND process_id = sqlc.arg(process_id)
 ORDER BY created_at DESC;
 
 -- name: GetProcessInfoBySnapshotAndPID :one
@@ -142,15 +201,16 @@ INSERT INTO process_queries (
     requested_pid,
     process_info_id,
     success,
-    error_message
-) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+    error_message,
+    agent_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
 
 -- name: GetProcessQuery :one
 SELECT * FROM process_queries WHERE id = $1 LIMIT 1;
 
 -- name: GetProcessQueriesByUser :many
 SELECT * FROM process_queries 
-WHERE user_id = $1 OR user_id IS NULL
+WHERE user_id = sqlc.arg(user_id) OR (user_id IS NULL AND sqlc.arg(include_unowned)::BOOLEAN)
 ORDER BY created_at DESC;
 
 -- name: GetProcessQueriesBySnapshot :many
@@ -160,7 +220,7 @@ ORDER BY created_at DESC;
 
 -- name: GetProcessQueriesByPID :many
 SELECT * FROM process_queries 
-WHERE (user_id = $1 OR user_id IS NULL) AND requested_pid = $2
+WHERE (user_id = sqlc.arg(user_id) OR (user_id IS NULL AND sqlc.arg(include_unowned)::BOOLEAN)) AND requested_pid = sqlc.arg(requested_pid)
 ORDER BY created_at DESC;
 
 -- ============================================
@@ -181,10 +241,10 @@ SELECT
     requested_pid,
     COUNT(*) as query_count
 FROM process_queries
-WHERE user_id = $1 OR user_id IS NULL
+WHERE user_id = sqlc.arg(user_id) OR (user_id IS NULL AND sqlc.arg(include_unowned)::BOOLEAN)
 GROUP BY requested_pid
 ORDER BY query_count DESC
-LIMIT $2;
+LIMIT sqlc.arg('limit');
 
 -- name: GetSnapshotStatistics :one
 SELECT 
@@ -193,4 +253,4 @@ SELECT
     COALESCE(AVG(process_count), 0) as avg_processes_per_snapshot
 FROM process_info pi
 LEFT JOIN process_snapshots ps ON ps.id = pi.snapshot_id
-WHERE pi.user_id = $1 OR pi.user_id IS NULL;
+WHERE pi.user_id = sqlc.arg(user_id) OR (pi.user_id IS NULL AND sqlc.arg(include_unowned)::BOOLEAN);
@@ -0,0 +1,38 @@
+-- name: CreateAgent :one
+INSERT INTO agents (
+    owner_id,
+    name,
+    base_url,
+    tags,
+    os_build
+) VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: GetAgent :one
+SELECT * FROM agents WHERE id = $1 LIMIT 1;
+
+-- name: GetAgentByBaseURL :one
+SELECT * FROM agents WHERE base_url = $1 LIMIT 1;
+
+-- name: GetAgents :many
+SELECT * FROM agents ORDER BY name ASC;
+
+-- name: UpdateAgent :one
+UPDATE agents
+SET name = $2, base_url = $3, tags = $4, os_build = $5, updated_at = NOW()
+WHERE id = $1 RETURNING *;
+
+-- name: UpdateAgentCredentials :one
+UPDATE agents
+SET signing_secret = $2, client_cert_pem = $3, client_key_pem = $4, ca_cert_pem = $5, updated_at = NOW()
+WHERE id = $1 RETURNING *;
+
+-- name: TouchAgentLastSeen :exec
+UPDATE agents SET last_seen_at = NOW(), offline_since = NULL WHERE id = $1;
+
+-- name: MarkAgentOffline :execrows
+-- Records the start of an outage. It affects no rows while the agent is
+-- already offline, so each outage is reported once.
+UPDATE agents SET offline_since = NOW() WHERE id = $1 AND offline_since IS NULL;
+
+-- name: DeleteAgent :exec
+DELETE FROM agents WHERE id = $1;
@@ -0,0 +1,71 @@
+-- name: CreateAlertRule :one
+INSERT INTO alert_rules (
+    user_id,
+    name,
+    description,
+    kind,
+    query,
+    parent_query,
+    issue_type,
+    severity,
+    enabled
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING *;
+
+-- name: GetAlertRule :one
+SELECT * FROM alert_rules WHERE id = $1 LIMIT 1;
+
+-- name: GetAlertRulesByUser :many
+SELECT * FROM alert_rules WHERE user_id = $1 ORDER BY created_at DESC;
+
+-- name: GetEnabledAlertRulesByUser :many
+SELECT * FROM alert_rules WHERE user_id = $1 AND enabled ORDER BY id;
+
+-- name: UpdateAlertRule :one
+UPDATE alert_rules SET
+    name = $2,
+    description = $3,
+    kind = $4,
+    query = $5,
+    parent_query = $6,
+    issue_type = $7,
+    severity = $8,
+    enabled = $9,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteAlertRule :exec
+DELETE FROM alert_rules WHERE id = $1;
+
+-- name: CreateAlert :one
+INSERT INTO alerts (
+    rule_id,
+    user_id,
+    snapshot_id,
+    process_info_id,
+    rule_name,
+    severity,
+    message,
+    process_id,
+    process_name
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING *;
+
+-- name: GetAlert :one
+SELECT * FROM alerts WHERE id = $1 LIMIT 1;
+
+-- name: GetAlertsBySnapshot :many
+SELECT * FROM alerts WHERE snapshot_id = $1 ORDER BY id;
+
+-- name: AcknowledgeAlert :one
+UPDATE alerts SET acknowledged_at = NOW(), acknowledged_by = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: ReopenAlert :one
+UPDATE alerts SET acknowledged_at = NULL, acknowledged_by = NULL
+WHERE id = $1
+RETURNING *;
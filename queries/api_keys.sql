@@ -0,0 +1,49 @@
+-- name: CreateApiKey :one
+INSERT INTO api_keys (
+    user_id,
+    name,
+    prefix,
+    key_hash,
+    scopes,
+    expires_at
+) VALUES (
+    sqlc.arg(user_id),
+    sqlc.arg(name),
+    sqlc.arg(prefix),
+    sqlc.arg(key_hash),
+    sqlc.arg(scopes),
+    NOW() + sqlc.narg(expires_in_days)::INTEGER * INTERVAL '1 day'
+) RETURNING *;
+
+-- name: GetApiKey :one
+SELECT * FROM api_keys WHERE id = $1 LIMIT 1;
+
+-- name: GetApiKeysByUser :many
+SELECT * FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC;
+
+-- name: GetActiveApiKeyByPrefix :one
+SELECT
+    k.id,
+    k.user_id,
+    k.key_hash,
+    k.scopes,
+    u.name AS user_name,
+    u.role AS user_role
+FROM api_keys k
+JOIN users u ON u.id = k.user_id
+WHERE k.prefix = $1
+  AND (k.expires_at IS NULL OR k.expires_at > NOW())
+LIMIT 1;
+
+-- name: GetActiveApiKey :one
+SELECT * FROM api_keys
+WHERE id = $1
+  AND (expires_at IS NULL OR expires_at > NOW())
+LIMIT 1;
+
+-- name: TouchApiKeyLastUsed :exec
+UPDATE api_keys SET last_used_at = NOW()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+
+-- name: DeleteApiKey :exec
+DELETE FROM api_keys WHERE id = $1;
@@ -0,0 +1,24 @@
+-- name: CreateImportedSnapshot :one
+-- Creates the iteration snapshot of an offline capture. created_at is the
+-- time the capture was taken, imported_at the time it was uploaded.
+INSERT INTO process_snapshots (
+    user_id,
+    snapshot_type,
+    process_count,
+    success,
+    agent_id,
+    source,
+    source_host,
+    created_at,
+    imported_at
+) VALUES (
+    sqlc.arg(user_id),
+    'iteration',
+    sqlc.arg(process_count),
+    true,
+    sqlc.arg(agent_id),
+    'import',
+    sqlc.arg(source_host),
+    sqlc.arg(captured_at),
+    NOW()
+) RETURNING *;
@@ -0,0 +1,20 @@
+-- name: GetQueriedProcessesNearSnapshot :many
+SELECT
+    pq.id AS query_id,
+    pq.snapshot_id,
+    pq.requested_pid,
+    pq.created_at AS queried_at,
+    pi.id AS process_info_id,
+    pi.process_id,
+    pi.process_name,
+    pi.create_time,
+    pi.current_process_address,
+    pi.next_process_eprocess_address,
+    pi.previous_process_eprocess_address
+FROM process_queries pq
+JOIN process_info pi ON pi.id = pq.process_info_id
+WHERE pq.agent_id = sqlc.arg(agent_id)
+  AND (pq.user_id = sqlc.arg(user_id) OR (pq.user_id IS NULL AND sqlc.arg(include_unowned)::BOOLEAN))
+  AND pq.success = TRUE
+  AND pq.created_at BETWEEN sqlc.arg(from_time) AND sqlc.arg(to_time)
+ORDER BY pq.created_at ASC;
@@ -0,0 +1,30 @@
+-- name: CreateCaptureJob :one
+INSERT INTO capture_jobs (
+    user_id,
+    agent_id
+) VALUES ($1, $2) RETURNING *;
+
+-- name: GetCaptureJob :one
+SELECT * FROM capture_jobs WHERE id = $1 LIMIT 1;
+
+-- name: ClaimNextCaptureJob :one
+UPDATE capture_jobs
+SET status = 'running', started_at = NOW(), updated_at = NOW()
+WHERE id = (
+    SELECT id FROM capture_jobs
+    WHERE status = 'queued'
+    ORDER BY id
+    FOR UPDATE SKIP LOCKED
+    LIMIT 1
+) RETURNING *;
+
+-- name: FinishCaptureJob :one
+UPDATE capture_jobs
+SET status = $2, snapshot_id = $3, error_message = $4, finished_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND status = 'running' RETURNING *;
+
+-- name: FailStaleCaptureJobs :many
+UPDATE capture_jobs
+SET status = 'failed', error_message = 'job did not finish in time', finished_at = NOW(), updated_at = NOW()
+WHERE status = 'running' AND started_at < NOW() - sqlc.arg(max_runtime_seconds)::INTEGER * INTERVAL '1 second'
+RETURNING *;
@@ -0,0 +1,42 @@
+-- name: CreateNotificationChannel :one
+INSERT INTO notification_channels (
+    user_id,
+    name,
+    kind,
+    config,
+    events,
+    min_severity,
+    enabled
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetNotificationChannel :one
+SELECT * FROM notification_channels WHERE id = $1 LIMIT 1;
+
+-- name: GetNotificationChannelsByUser :many
+SELECT * FROM notification_channels WHERE user_id = $1 ORDER BY created_at DESC;
+
+-- name: GetSubscribedNotificationChannels :many
+SELECT * FROM notification_channels
+WHERE user_id = sqlc.arg(user_id) AND enabled AND sqlc.arg(event)::TEXT = ANY(events)
+ORDER BY id;
+
+-- name: UpdateNotificationChannel :one
+UPDATE notification_channels SET
+    name = $2,
+    kind = $3,
+    config = $4,
+    events = $5,
+    min_severity = $6,
+    enabled = $7,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: RecordNotificationAttempt :exec
+UPDATE notification_channels SET last_attempt_at = NOW(), last_error = $2
+WHERE id = $1;
+
+-- name: DeleteNotificationChannel :exec
+DELETE FROM notification_channels WHERE id = $1;
@@ -0,0 +1,75 @@
+-- name: CreateProcessRates :execrows
+-- Derives per-second rates for the processes of an iteration snapshot that
+-- were also in the previous iteration snapshot of the same agent and user.
+-- cpu_time_unit_seconds is the length of one user/kernel time unit.
+-- Imported offline captures are never used as the previous snapshot.
+WITH cur AS (
+    SELECT id, agent_id, user_id, created_at FROM process_snapshots WHERE id = sqlc.arg(snapshot_id)
+), prev AS (
+    SELECT ps.id, EXTRACT(EPOCH FROM (cur.created_at - ps.created_at))::DOUBLE PRECISION AS seconds
+    FROM process_snapshots ps
+    JOIN cur ON ps.agent_id = cur.agent_id AND ps.user_id IS NOT DISTINCT FROM cur.user_id
+    WHERE ps.snapshot_type = 'iteration' AND ps.source = 'agent' AND ps.success AND ps.created_at < cur.created_at
+    ORDER BY ps.created_at DESC, ps.id DESC
+    LIMIT 1
+)
+INSERT INTO process_rates (
+    process_info_id,
+    previous_process_info_id,
+    snapshot_id,
+    previous_snapshot_id,
+    interval_seconds,
+    cpu_percent,
+    read_bytes_per_sec,
+    write_bytes_per_sec,
+    other_bytes_per_sec,
+    read_ops_per_sec,
+    write_ops_per_sec,
+    page_faults_per_sec,
+    handle_growth_per_sec
+)
+SELECT
+    c.id,
+    p.id,
+    c.snapshot_id,
+    prev.id,
+    prev.seconds,
+    CASE WHEN c.user_time::BIGINT + c.kernel_time >= p.user_time::BIGINT + p.kernel_time
+        THEN ((c.user_time::BIGINT + c.kernel_time) - (p.user_time::BIGINT + p.kernel_time)) * sqlc.arg(cpu_time_unit_seconds)::DOUBLE PRECISION * 100 / prev.seconds END,
+    CASE WHEN c.read_transfer_count >= p.read_transfer_count THEN (c.read_transfer_count - p.read_transfer_count) / prev.seconds END,
+    CASE WHEN c.write_transfer_count >= p.write_transfer_count THEN (c.write_transfer_count - p.write_transfer_count) / prev.seconds END,
+    CASE WHEN c.other_transfer_count >= p.other_transfer_count THEN (c.other_transfer_count - p.other_transfer_count) / prev.seconds END,
+    CASE WHEN c.read_operation_count >= p.read_operation_count THEN (c.read_operation_count - p.read_operation_count) / prev.seconds END,
+    CASE WHEN c.write_operation_count >= p.write_operation_count THEN (c.write_operation_count - p.write_operation_count) / prev.seconds END,
+    CASE WHEN c.page_fault_count >= p.page_fault_count THEN (c.page_fault_count - p.page_fault_count) / prev.seconds END,
+    (c.handle_count - p.handle_count) / prev.seconds
+FROM prev
+JOIN process_info c ON c.snapshot_id = sqlc.arg(snapshot_id)
+JOIN process_info p ON p.snapshot_id = prev.id
+    AND p.process_id = c.process_id
+    AND p.create_time = c.create_time
+    AND LOWER(p.current_process_address) = LOWER(c.current_process_address)
+WHERE prev.seconds > 0;
+
+-- name: GetProcessRatesBySnapshot :many
+SELECT
+    r.process_info_id,
+    r.previous_process_info_id,
+    r.snapshot_id,
+    r.previous_snapshot_id,
+    r.interval_seconds,
+    r.cpu_percent,
+    r.read_bytes_per_sec,
+    r.write_bytes_per_sec,
+    r.other_bytes_per_sec,
+    r.read_ops_per_sec,
+    r.write_ops_per_sec,
+    r.page_faults_per_sec,
+    r.handle_growth_per_sec,
+    r.created_at,
+    pi.process_id,
+    pi.process_name
+FROM process_rates r
+JOIN process_info pi ON pi.id = r.process_info_id
+WHERE r.snapshot_id = $1
+ORDER BY r.cpu_percent DESC NULLS LAST, pi.process_id ASC;
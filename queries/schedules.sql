@@ -0,0 +1,46 @@
+-- name: CreateSchedule :one
+INSERT INTO capture_schedules (
+    agent_id,
+    user_id,
+    cron_expression,
+    interval_seconds,
+    paused,
+    next_run_at
+) VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: GetSchedule :one
+SELECT * FROM capture_schedules WHERE id = $1 LIMIT 1;
+
+-- name: GetSchedulesByUser :many
+SELECT * FROM capture_schedules
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetDueSchedules :many
+SELECT * FROM capture_schedules
+WHERE NOT paused AND next_run_at <= $1
+ORDER BY next_run_at ASC
+LIMIT $2;
+
+-- name: UpdateSchedule :one
+UPDATE capture_schedules
+SET cron_expression = $2, interval_seconds = $3, next_run_at = $4, updated_at = NOW()
+WHERE id = $1 RETURNING *;
+
+-- name: SetSchedulePaused :one
+UPDATE capture_schedules
+SET paused = $2, next_run_at = $3, updated_at = NOW()
+WHERE id = $1 RETURNING *;
+
+-- name: ClaimSchedule :execrows
+UPDATE capture_schedules
+SET next_run_at = sqlc.arg(next_run_at), last_run_at = sqlc.arg(last_run_at), updated_at = NOW()
+WHERE id = sqlc.arg(id) AND next_run_at = sqlc.arg(expected_next_run_at) AND NOT paused;
+
+-- name: RecordScheduleRun :exec
+UPDATE capture_schedules
+SET last_snapshot_id = $2, last_error = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteSchedule :exec
+DELETE FROM capture_schedules WHERE id = $1;
@@ -0,0 +1,4 @@
+-- name: PublishStreamEvent :exec
+-- Hands an event to every API instance listening on the api_stream
+-- channel. Payloads must stay under 8000 bytes.
+SELECT pg_notify('api_stream', sqlc.arg(payload)::TEXT);
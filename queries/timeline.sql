@@ -0,0 +1,48 @@
+-- name: GetProcessTimeline :many
+-- Counter history of a PID across snapshots, oldest first. Rows of
+-- different process instances that reused the PID are told apart by the
+-- caller using create_time, the EPROCESS address and the agent.
+SELECT
+    pi.id,
+    pi.snapshot_id,
+    ps.agent_id,
+    ps.created_at AS captured_at,
+    pi.process_id,
+    pi.parent_process_id,
+    pi.process_name,
+    pi.create_time,
+    pi.current_process_address,
+    pi.thread_count,
+    pi.handle_count,
+    pi.user_time,
+    pi.kernel_time,
+    pi.working_set_size,
+    pi.peak_working_set_size,
+    pi.virtual_size,
+    pi.peak_virtual_size,
+    pi.read_operation_count,
+    pi.write_operation_count,
+    pi.other_operation_count,
+    pi.read_transfer_count,
+    pi.write_transfer_count,
+    pi.other_transfer_count,
+    pi.page_fault_count,
+    r.interval_seconds,
+    r.cpu_percent,
+    r.read_bytes_per_sec,
+    r.write_bytes_per_sec,
+    r.other_bytes_per_sec,
+    r.read_ops_per_sec,
+    r.write_ops_per_sec,
+    r.page_faults_per_sec,
+    r.handle_growth_per_sec
+FROM process_info pi
+JOIN process_snapshots ps ON ps.id = pi.snapshot_id
+LEFT JOIN process_rates r ON r.process_info_id = pi.id
+WHERE pi.process_id = sqlc.arg(process_id)
+  AND (pi.user_id = sqlc.arg(user_id) OR (pi.user_id IS NULL AND sqlc.arg(include_unowned)::BOOLEAN))
+  AND (sqlc.narg(create_time)::TEXT IS NULL OR pi.create_time = sqlc.narg(create_time))
+  AND (sqlc.narg(eprocess_address)::TEXT IS NULL OR LOWER(pi.current_process_address) = LOWER(sqlc.narg(eprocess_address)))
+  AND (sqlc.narg(agent_id)::BIGINT IS NULL OR ps.agent_id = sqlc.narg(agent_id))
+ORDER BY ps.created_at ASC, pi.id ASC
+LIMIT sqlc.arg(max_points)::INTEGER;
@@ -4,7 +4,33 @@ CREATE TABLE users (
     name VARCHAR(255) NOT NULL,
     password TEXT NOT NULL,
     created_at TIMESTAMP DEFAULT NOW(),
-    updated_at TIMESTAMP DEFAULT NOW()
+    updated_at TIMESTAMP DEFAULT NOW(),
+    role VARCHAR(20) NOT NULL DEFAULT 'viewer' CHECK (role IN ('admin', 'analyst', 'viewer')),
+    oidc_issuer TEXT, -- set for users provisioned through OIDC single sign-on
+    oidc_subject TEXT,
+    UNIQUE (oidc_issuer, oidc_subject)
+);
+
+-- Registered agents (hosts running the kernel process agent)
+-- Captures reference an agent instead of a free-form webhook URL
+CREATE TABLE agents (
+    id BIGSERIAL PRIMARY KEY,
+    owner_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    base_url TEXT NOT NULL UNIQUE, -- normalized (lowercase scheme/host, no trailing slash)
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    os_build VARCHAR(255),
+    last_seen_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    -- Agent authentication (never returned by the API)
+    signing_secret TEXT, -- HMAC-SHA256 shared secret; requests are signed and responses verified when set
+    client_cert_pem TEXT, -- mTLS client certificate presented to the agent
+    client_key_pem TEXT,
+    ca_cert_pem TEXT, -- CA used to verify the agent's server certificate
+
+    offline_since TIMESTAMP -- first failed connection since the agent last answered
 );
 
 -- Table to represent a "snapshot" or "session" of process capture
@@ -12,13 +38,19 @@ CREATE TABLE users (
 CREATE TABLE process_snapshots (
     id BIGSERIAL PRIMARY KEY,
     user_id BIGINT REFERENCES users(id) ON DELETE CASCADE, -- NULL if no JWT token
-    webhook_url TEXT NOT NULL,
+    webhook_url TEXT, -- NULL for imported captures
     snapshot_type VARCHAR(50) NOT NULL, -- 'iteration' or 'query'
     process_count INTEGER NOT NULL DEFAULT 0,
     success BOOLEAN NOT NULL DEFAULT true,
     error_message TEXT,
-    created_at TIMESTAMP DEFAULT NOW(),
-    updated_at TIMESTAMP DEFAULT NOW()
+    created_at TIMESTAMP DEFAULT NOW(), -- original capture time for imports
+    updated_at TIMESTAMP DEFAULT NOW(),
+    agent_id BIGINT REFERENCES agents(id) ON DELETE SET NULL,
+    -- Provenance: 'agent' for captures made through the API, 'import' for
+    -- offline captures uploaded later
+    source VARCHAR(20) NOT NULL DEFAULT 'agent',
+    source_host TEXT, -- host an imported capture was taken on
+    imported_at TIMESTAMP
 );
 
 -- Schema for process information based on webhook_handler.go ProcessInfo struct
@@ -89,20 +121,191 @@ CREATE TABLE process_queries (
     process_info_id BIGINT REFERENCES process_info(id) ON DELETE SET NULL,
     success BOOLEAN NOT NULL DEFAULT true,
     error_message TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    agent_id BIGINT REFERENCES agents(id) ON DELETE SET NULL
+);
+
+-- Periodic captures of an agent, driven by the in-process scheduler
+-- Exactly one of cron_expression / interval_seconds is set
+CREATE TABLE capture_schedules (
+    id BIGSERIAL PRIMARY KEY,
+    agent_id BIGINT NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    cron_expression TEXT,
+    interval_seconds INTEGER,
+    paused BOOLEAN NOT NULL DEFAULT false,
+    next_run_at TIMESTAMP NOT NULL,
+    last_run_at TIMESTAMP,
+    last_snapshot_id BIGINT REFERENCES process_snapshots(id) ON DELETE SET NULL,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW(),
+
+    CONSTRAINT capture_schedule_spec CHECK ((cron_expression IS NULL) <> (interval_seconds IS NULL))
+);
+
+-- Asynchronous iteration captures, run by the job worker pool
+CREATE TABLE capture_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    agent_id BIGINT NOT NULL REFERENCES agents(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'succeeded', 'failed')),
+    snapshot_id BIGINT REFERENCES process_snapshots(id) ON DELETE SET NULL,
+    error_message TEXT,
+    created_at TIMESTAMP DEFAULT NOW(),
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Refresh tokens, stored as SHA-256 hashes. Each use rotates the token;
+-- tokens descending from one login share a family_id so that reusing a
+-- rotated token revokes the whole chain.
+CREATE TABLE refresh_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Access tokens revoked before they expire (logout), by JWT ID
+CREATE TABLE revoked_tokens (
+    jti TEXT PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Personal API keys for automation. Only the SHA-256 hash is stored; the
+-- prefix identifies the key in lookups and listings.
+CREATE TABLE api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(16) NOT NULL UNIQUE,
+    key_hash TEXT NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Per-second rates of a process between two consecutive iteration snapshots
+-- of the same agent, derived from the cumulative counters of process_info
+CREATE TABLE process_rates (
+    process_info_id BIGINT PRIMARY KEY REFERENCES process_info(id) ON DELETE CASCADE,
+    previous_process_info_id BIGINT REFERENCES process_info(id) ON DELETE SET NULL,
+    snapshot_id BIGINT NOT NULL REFERENCES process_snapshots(id) ON DELETE CASCADE,
+    previous_snapshot_id BIGINT REFERENCES process_snapshots(id) ON DELETE SET NULL,
+    interval_seconds DOUBLE PRECISION NOT NULL,
+    -- NULL when the counter went backwards between the captures
+    cpu_percent DOUBLE PRECISION, -- user + kernel time, as a share of one CPU
+    read_bytes_per_sec DOUBLE PRECISION,
+    write_bytes_per_sec DOUBLE PRECISION,
+    other_bytes_per_sec DOUBLE PRECISION,
+    read_ops_per_sec DOUBLE PRECISION,
+    write_ops_per_sec DOUBLE PRECISION,
+    page_faults_per_sec DOUBLE PRECISION,
+    handle_growth_per_sec DOUBLE PRECISION NOT NULL, -- negative when handles were closed
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Detection rules, run against every new snapshot of their owner. process
+-- and parent rules are written in the process search language; integrity
+-- rules fire on EPROCESS list inconsistencies of iteration snapshots.
+CREATE TABLE alert_rules (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('process', 'parent', 'integrity')),
+    query TEXT,                -- process and parent rules: processes the rule applies to
+    parent_query TEXT,         -- parent rules: search query the parent must match
+    issue_type VARCHAR(32),    -- integrity rules: issue to alert on, NULL for any
+    severity VARCHAR(20) NOT NULL DEFAULT 'medium' CHECK (severity IN ('low', 'medium', 'high', 'critical')),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Rule matches. Rule name and severity are copied so alerts outlive edits
+-- and deletion of their rule.
+CREATE TABLE alerts (
+    id BIGSERIAL PRIMARY KEY,
+    rule_id BIGINT REFERENCES alert_rules(id) ON DELETE SET NULL,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    snapshot_id BIGINT NOT NULL REFERENCES process_snapshots(id) ON DELETE CASCADE,
+    process_info_id BIGINT REFERENCES process_info(id) ON DELETE SET NULL,
+    rule_name VARCHAR(255) NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    message TEXT NOT NULL,
+    process_id BIGINT,
+    process_name VARCHAR(255),
+    acknowledged_at TIMESTAMP,
+    acknowledged_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
     created_at TIMESTAMP DEFAULT NOW()
 );
 
+-- Outbound notification channels. A channel delivers the events of its
+-- owner whose type is in events; config holds the destination, whose shape
+-- depends on kind.
+CREATE TABLE notification_channels (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('webhook', 'slack', 'email', 'syslog')),
+    config JSONB NOT NULL DEFAULT '{}',
+    events TEXT[] NOT NULL DEFAULT '{}',
+    min_severity VARCHAR(20) NOT NULL DEFAULT 'low' CHECK (min_severity IN ('low', 'medium', 'high', 'critical')),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_attempt_at TIMESTAMP,
+    last_error TEXT, -- NULL when the last delivery succeeded
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
 -- Indexes for better performance
 CREATE INDEX idx_process_snapshots_user_id ON process_snapshots(user_id);
 CREATE INDEX idx_process_snapshots_created_at ON process_snapshots(created_at DESC);
 CREATE INDEX idx_process_snapshots_type ON process_snapshots(snapshot_type);
+CREATE INDEX idx_process_snapshots_agent_id ON process_snapshots(agent_id);
+CREATE INDEX idx_process_snapshots_user_page ON process_snapshots(user_id, created_at DESC, id DESC);
 
 CREATE INDEX idx_process_info_snapshot_id ON process_info(snapshot_id);
 CREATE INDEX idx_process_info_user_id ON process_info(user_id);
 CREATE INDEX idx_process_info_process_id ON process_info(process_id);
 CREATE INDEX idx_process_info_created_at ON process_info(created_at DESC);
+CREATE INDEX idx_process_info_user_page ON process_info(user_id, created_at DESC, id DESC);
 
 CREATE INDEX idx_process_queries_snapshot_id ON process_queries(snapshot_id);
 CREATE INDEX idx_process_queries_user_id ON process_queries(user_id);
 CREATE INDEX idx_process_queries_created_at ON process_queries(created_at DESC);
 CREATE INDEX idx_process_queries_requested_pid ON process_queries(requested_pid);
+CREATE INDEX idx_process_queries_agent_id ON process_queries(agent_id);
+CREATE INDEX idx_process_queries_user_page ON process_queries(user_id, created_at DESC, id DESC);
+
+CREATE INDEX idx_agents_owner_id ON agents(owner_id);
+
+CREATE INDEX idx_capture_schedules_user_id ON capture_schedules(user_id);
+CREATE INDEX idx_capture_schedules_due ON capture_schedules(next_run_at) WHERE NOT paused;
+
+CREATE INDEX idx_capture_jobs_user_id ON capture_jobs(user_id);
+CREATE INDEX idx_capture_jobs_queued ON capture_jobs(id) WHERE status = 'queued';
+
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+
+CREATE INDEX idx_process_rates_snapshot_id ON process_rates(snapshot_id);
+
+CREATE INDEX idx_alert_rules_user_id ON alert_rules(user_id);
+CREATE INDEX idx_alerts_snapshot_id ON alerts(snapshot_id);
+CREATE INDEX idx_alerts_user_page ON alerts(user_id, created_at DESC, id DESC);
+
+CREATE INDEX idx_notification_channels_user_id ON notification_channels(user_id);